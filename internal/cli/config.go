@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect project configuration",
+	Long: `Inspect the configuration anvil uses for the current project.
+
+Project configuration is merged from these layers (highest precedence last):
+  1. The linked project entry in the global config
+  2. anvil.yaml in the default branch worktree
+  3. anvil.yaml in the project root`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the merged project configuration",
+	Long: `Prints the merged project configuration used by work, scaffold,
+remove and sync.
+
+With --resolved, prints every configured key together with the layer
+and file it was taken from.
+
+Examples:
+  anvil config show              # Print merged config as YAML
+  anvil config show --resolved   # Show where each value came from`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pc, err := OpenProjectFromCWD()
+		if err != nil {
+			return err
+		}
+
+		if mustGetBool(cmd, "resolved") {
+			return printResolvedConfig(os.Stdout, pc.Resolved)
+		}
+
+		return printConfigYAML(os.Stdout, pc.Config)
+	},
+}
+
+func printConfigYAML(w io.Writer, cfg *config.Config) error {
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func printResolvedConfig(w io.Writer, resolved *config.ResolvedConfig) error {
+	keys := resolved.Keys()
+	if len(keys) == 0 {
+		_, err := fmt.Fprintln(w, "No configuration values set.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE"); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		layer, _ := resolved.Source(key)
+		source := string(layer.Source)
+		if layer.Path != "" {
+			source = fmt.Sprintf("%s (%s)", layer.Source, layer.Path)
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", key, formatConfigValue(resolved.Value(key)), source); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// formatConfigValue renders a resolved value on a single line.
+func formatConfigValue(value any) string {
+	switch v := value.(type) {
+	case []config.StepConfig:
+		return pluralize(len(v), "step")
+	case []config.CleanupStep:
+		return pluralize(len(v), "step")
+	case *bool:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprintf("%t", *v)
+	case *config.PreFlight:
+		if v == nil {
+			return ""
+		}
+		return formatFlowYAML(v.Condition)
+	case config.ToolConfig, config.VarConfig:
+		return formatFlowYAML(v)
+	case config.ProfileConfig:
+		if v.Description != "" {
+			return v.Description
+		}
+		return formatFlowYAML(config.ProfileConfig{Only: v.Only, Skip: v.Skip, Tags: v.Tags})
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func formatFlowYAML(value any) string {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	setFlowStyle(node)
+	content, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimRight(string(content), "\n")
+}
+
+func setFlowStyle(node *yaml.Node) {
+	node.Style |= yaml.FlowStyle
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().Bool("resolved", false, "Show the source layer of each value")
+}
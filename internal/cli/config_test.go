@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+func TestPrintResolvedConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	content := `scaffold:
+  steps:
+    - name: bash.run
+      command: echo hi
+`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, config.ProjectConfigFile), []byte(content), 0644))
+
+	resolved, err := config.ResolveProjectConfig(projectDir, "", &config.ProjectInfo{Preset: "laravel"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, printResolvedConfig(&buf, resolved))
+
+	output := buf.String()
+	assert.Contains(t, output, "KEY")
+	assert.Contains(t, output, "preset")
+	assert.Contains(t, output, "laravel")
+	assert.Contains(t, output, "global")
+	assert.Contains(t, output, "scaffold.steps")
+	assert.Contains(t, output, "1 step")
+	assert.Contains(t, output, filepath.Join(projectDir, config.ProjectConfigFile))
+}
+
+func TestPrintResolvedConfig_Empty(t *testing.T) {
+	resolved, err := config.ResolveProjectConfig("", "", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, printResolvedConfig(&buf, resolved))
+
+	assert.Contains(t, buf.String(), "No configuration values set.")
+}
+
+func TestPrintConfigYAML(t *testing.T) {
+	cfg := &config.Config{
+		Preset: "laravel",
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{{Name: "bash.run", Command: "echo hi"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, printConfigYAML(&buf, cfg))
+
+	output := buf.String()
+	assert.Contains(t, output, "preset: laravel")
+	assert.Contains(t, output, "command: echo hi")
+	assert.NotContains(t, output, "editor_cmd")
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,11 +14,16 @@ import (
 	"github.com/naoray/anvil/internal/scaffold/steps"
 )
 
+// errNotInProject is returned when the current directory is neither in a
+// linked project nor in one of its worktrees.
+var errNotInProject = errors.New("not in a linked anvil project (run 'anvil link' first)")
+
 type ProjectContext struct {
 	CWD           string
 	GitDir        string
 	ProjectPath   string
 	Config        *config.Config
+	Resolved      *config.ResolvedConfig
 	DefaultBranch string
 
 	ProjectName  string
@@ -56,7 +62,7 @@ func OpenProjectFromCWD() (*ProjectContext, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("not in a linked anvil project (run 'anvil link' first)")
+	return nil, errNotInProject
 }
 
 // openProject creates a ProjectContext for a linked project
@@ -78,23 +84,50 @@ func openProject(cwd, projectName string, projectInfo *config.ProjectInfo, globa
 	// Best-effort expansion; empty worktreeBase is handled downstream
 	worktreeBase, _ := globalCfg.GetWorktreeBaseExpanded()
 
-	cfg := &config.Config{
-		SiteName:      projectInfo.SiteName,
-		Preset:        projectInfo.Preset,
-		DefaultBranch: defaultBranch,
-		EditorCmd:     projectInfo.EditorCmd,
-	}
-
-	return &ProjectContext{
+	pc := &ProjectContext{
 		CWD:           cwd,
 		GitDir:        gitDir,
 		ProjectPath:   projectInfo.Path,
-		Config:        cfg,
 		DefaultBranch: defaultBranch,
 		ProjectName:   projectName,
 		WorktreeBase:  worktreeBase,
 		GlobalConfig:  globalCfg,
-	}, nil
+	}
+
+	globalInfo := *projectInfo
+	globalInfo.DefaultBranch = defaultBranch
+
+	resolved, err := config.ResolveProjectConfig(projectInfo.Path, pc.DefaultBranchWorktreePath(), &globalInfo)
+	if err != nil {
+		return nil, fmt.Errorf("resolving project config: %w", err)
+	}
+	pc.Resolved = resolved
+	pc.Config = resolved.Config
+	if pc.Config.DefaultBranch != "" {
+		pc.DefaultBranch = pc.Config.DefaultBranch
+	}
+
+	pc.presetManager, err = presets.LoadManager(presets.Dirs(projectInfo.Path)...)
+	if err != nil {
+		return nil, fmt.Errorf("loading presets: %w", err)
+	}
+
+	return pc, nil
+}
+
+// DefaultBranchWorktreePath returns the path of the worktree that has the
+// default branch checked out. Falls back to the conventional worktree path
+// when git cannot list worktrees or the branch is not checked out.
+func (pc *ProjectContext) DefaultBranchWorktreePath() string {
+	// Best-effort: an unlisted default branch falls back to the conventional path
+	if worktrees, err := git.ListWorktrees(pc.GitDir); err == nil {
+		for _, wt := range worktrees {
+			if wt.Branch == pc.DefaultBranch {
+				return wt.Path
+			}
+		}
+	}
+	return pc.GetWorktreePath(pc.DefaultBranch)
 }
 
 // openProjectFromWorktree creates a ProjectContext when inside a worktree of a linked project
@@ -204,7 +237,17 @@ func (pc *ProjectContext) initManagers() {
 	stepRegistry := steps.NewRegistry()
 	stepRegistry.RegisterDefaults()
 
-	pc.presetManager = presets.NewManager()
+	// Contexts not opened by openProject only know the built-in presets
+	if pc.presetManager == nil {
+		pc.presetManager = presets.NewManager()
+	}
 	pc.scaffoldManager = scaffold.NewScaffoldManagerWithRegistry(stepRegistry)
-	presets.RegisterAllWithScaffold(pc.scaffoldManager)
+	if pc.GlobalConfig != nil {
+		pc.scaffoldManager.SetParallelDependencies(pc.GlobalConfig.Scaffold.ParallelDependencies)
+	}
+	if pc.Resolved != nil {
+		pc.scaffoldManager.SetConfigLocator(pc.Resolved)
+	}
+	pc.scaffoldManager.SetProject(pc.ProjectName, pc.DefaultBranchWorktreePath())
+	pc.presetManager.RegisterWithScaffold(pc.scaffoldManager)
 }
@@ -143,6 +143,65 @@ func TestOpenProjectFromCWD_LinkedProject(t *testing.T) {
 	}
 }
 
+func TestOpenProject_MergesProjectConfig(t *testing.T) {
+	repoDir := createLinkedProject(t)
+	tmpDir := filepath.Dir(repoDir)
+	worktreeBase := filepath.Join(tmpDir, "worktrees")
+
+	// Check out the default branch in a separate worktree, like anvil work main
+	detachHEAD(t, repoDir)
+	mainDir := filepath.Join(worktreeBase, "my-project", "main")
+	cmd := exec.Command("git", "worktree", "add", mainDir, "main")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("creating main worktree: %v\n%s", err, output)
+	}
+
+	mainConfig := `scaffold:
+  steps:
+    - name: bash.run
+      command: echo from-main
+sync:
+  upstream: develop
+`
+	if err := os.WriteFile(filepath.Join(mainDir, config.ProjectConfigFile), []byte(mainConfig), 0644); err != nil {
+		t.Fatalf("writing main config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, config.ProjectConfigFile), []byte("sync:\n  strategy: merge\n"), 0644); err != nil {
+		t.Fatalf("writing root config: %v", err)
+	}
+
+	globalCfg := &config.GlobalConfig{
+		WorktreeBase: worktreeBase,
+		Projects: map[string]*config.ProjectInfo{
+			"my-project": {Path: repoDir, DefaultBranch: "main", Preset: "php"},
+		},
+	}
+
+	pc, err := openProject(repoDir, "my-project", globalCfg.Projects["my-project"], globalCfg)
+	if err != nil {
+		t.Fatalf("openProject() error = %v", err)
+	}
+
+	if evalSymlinks(pc.DefaultBranchWorktreePath()) != evalSymlinks(mainDir) {
+		t.Errorf("DefaultBranchWorktreePath() = %v, want %v", pc.DefaultBranchWorktreePath(), mainDir)
+	}
+	if len(pc.Config.Scaffold.Steps) != 1 || pc.Config.Scaffold.Steps[0].Command != "echo from-main" {
+		t.Errorf("Config.Scaffold.Steps = %+v, want the default branch steps", pc.Config.Scaffold.Steps)
+	}
+	if pc.Config.Sync.Upstream != "develop" || pc.Config.Sync.Strategy != "merge" {
+		t.Errorf("Config.Sync = %+v, want upstream develop and strategy merge", pc.Config.Sync)
+	}
+	if pc.Config.Preset != "php" {
+		t.Errorf("Config.Preset = %v, want php", pc.Config.Preset)
+	}
+
+	layer, ok := pc.Resolved.Source("sync.strategy")
+	if !ok || layer.Source != config.SourceProject {
+		t.Errorf("sync.strategy source = %v, want %v", layer.Source, config.SourceProject)
+	}
+}
+
 func TestProjectContext_IsInWorktree(t *testing.T) {
 	t.Run("returns false for non-worktree directory", func(t *testing.T) {
 		tmpDir := t.TempDir()
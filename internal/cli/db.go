@@ -0,0 +1,661 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold/steps"
+	"github.com/naoray/anvil/internal/scaffold/words"
+	"github.com/naoray/anvil/internal/snapshot"
+	"github.com/naoray/anvil/internal/ui"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage worktree databases",
+	Long:  `Manage the database of the current worktree and the databases anvil created.`,
+}
+
+var dbSnapshotCmd = &cobra.Command{
+	Use:   "snapshot [NAME]",
+	Short: "Save a snapshot of the worktree database",
+	Long: `Saves a snapshot of the database of the current worktree, to restore it
+with 'anvil db restore' after a migration or seeder went wrong.
+
+MySQL and PostgreSQL snapshots are copies of the database on the same
+server, named snap_<name>_<database>. SQLite snapshots are copies of the
+database file. The snapshots of a worktree are listed in
+.anvil/snapshots/<worktree>/ of the project, along with the commit they
+were taken at.
+
+Arguments:
+  NAME  Name of the snapshot (lowercase letters, digits, - and _)
+        If omitted, the current date and time is used.
+
+Examples:
+  anvil db snapshot                  # Snapshot named after the current time
+  anvil db snapshot before-migration`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := time.Now().Format("20060102-150405")
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		store, db, worktreePath, err := openSnapshotStore()
+		if err != nil {
+			return err
+		}
+		// Best-effort: the commit is informational
+		commit, _ := git.ShortSHA(worktreePath)
+
+		if mustGetBool(cmd, "dry-run") {
+			ui.PrintInfo(fmt.Sprintf("[DRY RUN] Would save snapshot %s of %s", name, db.Name))
+			return nil
+		}
+
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+		if _, err := store.Take(ctx, db, name, commit); err != nil {
+			return err
+		}
+		ui.PrintDone(fmt.Sprintf("Saved snapshot %s of %s", name, db.Name))
+		return nil
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore NAME",
+	Short: "Restore the worktree database from a snapshot",
+	Long: `Replaces the database of the current worktree with a snapshot taken by
+'anvil db snapshot'. The snapshot is kept, so it can be restored again.
+
+Arguments:
+  NAME  Name of the snapshot (see 'anvil db list-snapshots')`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSnapshotNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, db, _, err := openSnapshotStore()
+		if err != nil {
+			return err
+		}
+		snap, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		if mustGetBool(cmd, "dry-run") {
+			ui.PrintInfo(fmt.Sprintf("[DRY RUN] Would restore %s from snapshot %s", db.Name, snap.Name))
+			return nil
+		}
+
+		if !mustGetBool(cmd, "force") {
+			if !ui.IsInteractive() {
+				return fmt.Errorf("restoring replaces the database %s (use --force to skip confirmation)", db.Name)
+			}
+			confirmed, err := ui.Confirm(fmt.Sprintf("Replace the database %s with snapshot %s?", db.Name, snap.Name))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				ui.PrintInfo("Cancelled")
+				return nil
+			}
+		}
+
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+		if _, err := store.Restore(ctx, db, snap.Name); err != nil {
+			return err
+		}
+		ui.PrintDone(fmt.Sprintf("Restored %s from snapshot %s", db.Name, snap.Name))
+		return nil
+	},
+}
+
+var dbListSnapshotsCmd = &cobra.Command{
+	Use:   "list-snapshots",
+	Short: "List the snapshots of the worktree database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pc, err := OpenProjectFromCWD()
+		if err != nil {
+			return fmt.Errorf("opening project: %w", err)
+		}
+		worktreePath, err := currentWorktreePath(pc)
+		if err != nil {
+			return err
+		}
+		return listSnapshots(os.Stdout, snapshot.NewStore(pc.ProjectName, pc.ProjectPath, worktreePath))
+	},
+}
+
+var dbDropSnapshotCmd = &cobra.Command{
+	Use:               "drop-snapshot NAME",
+	Short:             "Delete a snapshot of the worktree database",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSnapshotNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pc, err := OpenProjectFromCWD()
+		if err != nil {
+			return fmt.Errorf("opening project: %w", err)
+		}
+		worktreePath, err := currentWorktreePath(pc)
+		if err != nil {
+			return err
+		}
+		store := snapshot.NewStore(pc.ProjectName, pc.ProjectPath, worktreePath)
+
+		if mustGetBool(cmd, "dry-run") {
+			snap, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+			ui.PrintInfo(fmt.Sprintf("[DRY RUN] Would delete snapshot %s", snap.Name))
+			return nil
+		}
+
+		if _, err := store.Drop(args[0]); err != nil {
+			return err
+		}
+		ui.PrintDone(fmt.Sprintf("Deleted snapshot %s", args[0]))
+		return nil
+	},
+}
+
+var dbLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the databases anvil created",
+	Long: `Lists the databases db.create, db.clone and 'anvil db snapshot' created,
+with the project and worktree they belong to. db.destroy only drops the
+databases registered for its worktree, so databases of other projects that
+happen to share the suffix are left alone.
+
+Worktrees that no longer exist are marked (missing).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		records, err := config.ReadDatabaseRegistry()
+		if err != nil {
+			return err
+		}
+		return listDatabases(os.Stdout, records)
+	},
+}
+
+var dbGcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Drop the databases of worktrees that no longer exist",
+	Long: `Finds the databases left behind by worktrees deleted by hand, with rm -rf,
+or by 'anvil unlink --clean', and offers to drop them.
+
+The MySQL and PostgreSQL servers of the worktrees of all linked projects,
+and the servers in the database registry (see 'anvil db ls'), are checked.
+A registered database is orphaned when its worktree no longer exists.
+
+A database that is not registered but ends in a suffix such as
+_swift_runner that the .anvil.local of no worktree of a linked project uses
+is listed separately. It may belong to a project that is not linked or to
+a worktree on another machine sharing the server, so each one is only
+dropped after confirming it, never with --yes.
+
+Examples:
+  anvil db gc --dry-run  # List the orphaned databases
+  anvil db gc            # Select the orphaned databases to drop
+  anvil db gc --yes      # Drop all registered orphaned databases`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		globalCfg, err := config.LoadOrCreateGlobalConfig()
+		if err != nil {
+			return fmt.Errorf("loading global config: %w", err)
+		}
+		if len(globalCfg.Projects) == 0 {
+			ui.PrintDone("No linked projects found. Run 'anvil link' first.")
+			return nil
+		}
+
+		suffixes, servers, err := scanLiveWorktrees(globalCfg)
+		if err != nil {
+			return err
+		}
+		records, err := config.ReadDatabaseRegistry()
+		if err != nil {
+			return err
+		}
+		// Servers only known from the registry are connected to with the
+		// credentials of the ANVIL_DB_* variables or the global config
+		for _, record := range records {
+			if record.Engine != config.DBEngineMySQL && record.Engine != config.DBEnginePgSQL {
+				continue
+			}
+			opts, err := steps.ServerConnectionOptions(record.Engine, record.Host)
+			if err != nil {
+				ui.PrintWarning(fmt.Sprintf("Skipping %s: %v", record.Name, err))
+				continue
+			}
+			servers = addDatabaseServer(servers, databaseServer{engine: record.Engine, opts: opts})
+		}
+
+		var registered, unregistered []orphanDatabase
+		for _, server := range servers {
+			ui.PrintInfo(fmt.Sprintf("Checking %s databases at %s", server.engine, server.opts))
+			err := withDatabaseServer(server, func(client steps.DatabaseClient) error {
+				databases, err := client.ListDatabases("%")
+				if err != nil {
+					return err
+				}
+				for _, orphan := range findOrphanDatabases(server.engine, server.opts.Address(), databases, suffixes, records) {
+					orphan.server = server
+					if orphan.Registered {
+						registered = append(registered, orphan)
+					} else {
+						unregistered = append(unregistered, orphan)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				ui.PrintWarning(fmt.Sprintf("Skipping %s: %v", server.opts.Address(), err))
+			}
+		}
+
+		if len(registered) == 0 && len(unregistered) == 0 {
+			ui.PrintDone("No orphaned databases.")
+			return nil
+		}
+		if len(registered) > 0 {
+			ui.PrintInfo("Registered databases of removed worktrees:")
+			if err := listOrphanDatabases(os.Stdout, registered); err != nil {
+				return err
+			}
+		}
+		if len(unregistered) > 0 {
+			ui.PrintInfo("Unregistered databases with a suffix no worktree uses:")
+			if err := listOrphanDatabases(os.Stdout, unregistered); err != nil {
+				return err
+			}
+		}
+
+		if mustGetBool(cmd, "dry-run") {
+			ui.PrintInfo(fmt.Sprintf("[DRY RUN] Would drop %d registered database(s) and ask about %d unregistered database(s)", len(registered), len(unregistered)))
+			return nil
+		}
+
+		interactive := ui.IsInteractive()
+		var selected []orphanDatabase
+		switch {
+		case len(registered) == 0:
+		case mustGetBool(cmd, "yes"):
+			selected = registered
+		case !interactive:
+			return fmt.Errorf("dropping databases needs confirmation (use --yes to drop the registered ones)")
+		default:
+			labels := make([]string, len(registered))
+			for i, orphan := range registered {
+				labels[i] = fmt.Sprintf("%s (%s, %s)", orphan.Record.Name, orphan.Record.Engine, orphan.Reason)
+			}
+			indexes, err := ui.SelectDatabasesToDrop(labels)
+			if err != nil {
+				return fmt.Errorf("selecting databases: %w", err)
+			}
+			if len(indexes) > 0 {
+				confirmed, err := ui.Confirm(fmt.Sprintf("Drop %d database(s)?", len(indexes)))
+				if err != nil {
+					return err
+				}
+				if confirmed {
+					for _, i := range indexes {
+						selected = append(selected, registered[i])
+					}
+				}
+			}
+		}
+
+		// Unregistered databases may belong to someone else: each one is
+		// confirmed on its own
+		if len(unregistered) > 0 && !interactive {
+			ui.PrintInfo(fmt.Sprintf("Kept %d unregistered database(s); run 'anvil db gc' in a terminal to confirm each", len(unregistered)))
+		}
+		for _, orphan := range unregistered {
+			if !interactive {
+				break
+			}
+			confirmed, err := ui.Confirm(fmt.Sprintf("Drop %s at %s? It is not registered and may belong to another project", orphan.Record.Name, orphan.Record.Host))
+			if err != nil {
+				return err
+			}
+			if confirmed {
+				selected = append(selected, orphan)
+			}
+		}
+
+		if len(selected) == 0 {
+			ui.PrintInfo("No databases dropped.")
+			return nil
+		}
+		dropped := dropOrphanDatabases(servers, selected)
+		if err := config.UnregisterDatabases(dropped...); err != nil {
+			return err
+		}
+		if failed := len(selected) - len(dropped); failed > 0 {
+			return fmt.Errorf("could not drop %d database(s)", failed)
+		}
+		ui.PrintDone(fmt.Sprintf("Dropped %d database(s)", len(dropped)))
+		return nil
+	},
+}
+
+// withDatabaseServer runs fn with a client connected to server, and closes
+// the connection when fn returns.
+func withDatabaseServer(server databaseServer, fn func(client steps.DatabaseClient) error) error {
+	client, err := steps.DefaultDatabaseClientFactory(string(server.engine), server.opts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }() // best-effort cleanup
+
+	if err := client.Ping(); err != nil {
+		return fmt.Errorf("connecting to %s database at %s: %w", server.engine, server.opts, err)
+	}
+	return fn(client)
+}
+
+// dropOrphanDatabases drops the selected databases, one connection per
+// server, and returns the records of the dropped ones.
+func dropOrphanDatabases(servers []databaseServer, selected []orphanDatabase) []config.DatabaseRecord {
+	var dropped []config.DatabaseRecord
+	for _, server := range servers {
+		err := withDatabaseServer(server, func(client steps.DatabaseClient) error {
+			for _, orphan := range selected {
+				if orphan.server != server {
+					continue
+				}
+				if err := client.DropDatabase(orphan.Record.Name); err != nil {
+					ui.PrintErrorWithHint(fmt.Sprintf("Error dropping %s", orphan.Record.Name), err.Error())
+					continue
+				}
+				dropped = append(dropped, orphan.Record)
+				ui.PrintSuccess(fmt.Sprintf("Dropped %s", orphan.Record.Name))
+			}
+			return nil
+		})
+		if err != nil && slices.ContainsFunc(selected, func(o orphanDatabase) bool { return o.server == server }) {
+			ui.PrintErrorWithHint(fmt.Sprintf("Error dropping databases at %s", server.opts.Address()), err.Error())
+		}
+	}
+	return dropped
+}
+
+// databaseServer is a database server with the options to connect to it.
+type databaseServer struct {
+	engine config.DatabaseEngine
+	opts   steps.DatabaseOptions
+}
+
+// addDatabaseServer adds server to servers unless they hold its address.
+func addDatabaseServer(servers []databaseServer, server databaseServer) []databaseServer {
+	for _, s := range servers {
+		if s.engine == server.engine && config.NormalizeDatabaseHost(s.engine, s.opts.Address()) == config.NormalizeDatabaseHost(server.engine, server.opts.Address()) {
+			return servers
+		}
+	}
+	return append(servers, server)
+}
+
+// scanLiveWorktrees returns the database suffixes of the worktrees of all
+// linked projects and the servers of their MySQL and PostgreSQL databases.
+// It fails when the worktrees of a project cannot be listed, as their
+// databases would look orphaned.
+func scanLiveWorktrees(globalCfg *config.GlobalConfig) (map[string]bool, []databaseServer, error) {
+	suffixes := make(map[string]bool)
+	var servers []databaseServer
+
+	for _, name := range slices.Sorted(maps.Keys(globalCfg.Projects)) {
+		info := globalCfg.Projects[name]
+		pc, err := openProject(info.Path, name, info, globalCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening project %s: %w", name, err)
+		}
+		worktrees, err := git.ListWorktrees(pc.GitDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing worktrees of %s: %w", name, err)
+		}
+
+		for _, wt := range worktrees {
+			if _, err := os.Stat(wt.Path); os.IsNotExist(err) {
+				continue // deleted by hand, not yet pruned by git
+			}
+			state, err := config.ReadLocalState(wt.Path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("worktree %s: %w", wt.Path, err)
+			}
+			if state.DbSuffix != "" {
+				suffixes[state.DbSuffix] = true
+			}
+
+			engine, err := steps.DetectDatabaseEngine("", wt.Path)
+			if err != nil || engine == config.DBEngineSQLite {
+				continue
+			}
+			opts, err := steps.ResolveConnectionOptions(engine, nil, wt.Path)
+			if err != nil {
+				ui.PrintWarning(fmt.Sprintf("Skipping the database server of %s: %v", wt.Path, err))
+				continue
+			}
+			servers = addDatabaseServer(servers, databaseServer{engine: engine, opts: opts})
+		}
+	}
+	return suffixes, servers, nil
+}
+
+// orphanDatabase is a database of a worktree that no longer exists.
+type orphanDatabase struct {
+	Record     config.DatabaseRecord
+	Reason     string
+	Registered bool // Whether the registry knows the database, or only its suffix matched
+
+	server databaseServer
+}
+
+// findOrphanDatabases returns the databases of the server at host that
+// belong to no live worktree. A registered database is orphaned when its
+// worktree no longer exists; any other database when its name ends in a
+// suffix none of the live worktrees uses.
+func findOrphanDatabases(engine config.DatabaseEngine, host string, databases []string, suffixes map[string]bool, records []config.DatabaseRecord) []orphanDatabase {
+	var orphans []orphanDatabase
+	for _, name := range databases {
+		record := config.DatabaseRecord{Engine: engine, Host: host, Name: name}
+		i := slices.IndexFunc(records, func(r config.DatabaseRecord) bool {
+			return r.OnServer(engine, host) && r.Name == name
+		})
+		if i >= 0 {
+			if _, err := os.Stat(records[i].Worktree); os.IsNotExist(err) {
+				orphans = append(orphans, orphanDatabase{
+					Record:     records[i],
+					Reason:     fmt.Sprintf("worktree %s no longer exists", records[i].Worktree),
+					Registered: true,
+				})
+			}
+			continue
+		}
+
+		if suffix := words.ExtractSuffix(name); suffix != "" && !suffixes[suffix] {
+			orphans = append(orphans, orphanDatabase{
+				Record: record,
+				Reason: fmt.Sprintf("no worktree uses suffix %s", suffix),
+			})
+		}
+	}
+	return orphans
+}
+
+// listOrphanDatabases prints orphaned databases and why they are orphaned.
+func listOrphanDatabases(w io.Writer, orphans []orphanDatabase) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NAME\tENGINE\tHOST\tREASON"); err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", orphan.Record.Name, orphan.Record.Engine, orphan.Record.Host, orphan.Reason); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// listDatabases prints registered databases by project and worktree.
+func listDatabases(w io.Writer, records []config.DatabaseRecord) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, "No databases registered.")
+		return err
+	}
+
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b config.DatabaseRecord) int {
+		return cmp.Or(
+			cmp.Compare(a.Project, b.Project),
+			cmp.Compare(a.Worktree, b.Worktree),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NAME\tENGINE\tHOST\tPROJECT\tWORKTREE"); err != nil {
+		return err
+	}
+	for _, record := range records {
+		project := record.Project
+		if project == "" {
+			project = "-"
+		}
+		worktree := record.Worktree
+		if _, err := os.Stat(worktree); os.IsNotExist(err) {
+			worktree += " (missing)"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", record.Name, record.Engine, record.Host, project, worktree); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// openSnapshotStore returns the snapshot store and the database of the
+// current worktree, and the worktree path.
+func openSnapshotStore() (*snapshot.Store, snapshot.Database, string, error) {
+	pc, err := OpenProjectFromCWD()
+	if err != nil {
+		return nil, snapshot.Database{}, "", fmt.Errorf("opening project: %w", err)
+	}
+	worktreePath, err := currentWorktreePath(pc)
+	if err != nil {
+		return nil, snapshot.Database{}, "", err
+	}
+	// Like scaffold, feature worktrees are named after their folder and the
+	// default branch worktree after the project's site name
+	siteName := filepath.Base(worktreePath)
+	if evalPath(worktreePath) == evalPath(pc.DefaultBranchWorktreePath()) {
+		siteName = pc.Config.SiteName
+	}
+	db, err := worktreeDatabase(worktreePath, siteName)
+	if err != nil {
+		return nil, snapshot.Database{}, "", err
+	}
+	return snapshot.NewStore(pc.ProjectName, pc.ProjectPath, worktreePath), db, worktreePath, nil
+}
+
+// worktreeDatabase returns the database of the worktree at worktreePath:
+// DB_DATABASE of its .env, which the app uses, or else the database
+// db.create named after siteName.
+func worktreeDatabase(worktreePath, siteName string) (snapshot.Database, error) {
+	engine, err := steps.DetectDatabaseEngine("", worktreePath)
+	if err != nil {
+		return snapshot.Database{}, err
+	}
+	if engine == config.DBEngineSQLite {
+		return snapshot.Database{Engine: engine, Name: steps.WorktreeSqliteFile(worktreePath, nil)}, nil
+	}
+	name, err := steps.WorktreeDatabase(worktreePath, "")
+	if err != nil && siteName != "" {
+		name, err = steps.WorktreeDatabase(worktreePath, siteName)
+	}
+	if err != nil {
+		return snapshot.Database{}, err
+	}
+	return snapshot.Database{Engine: engine, Name: name}, nil
+}
+
+// listSnapshots prints the snapshots of a store.
+func listSnapshots(w io.Writer, store *snapshot.Store) error {
+	snapshots, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		_, err := fmt.Fprintln(w, "No snapshots.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NAME\tCOMMIT\tCREATED\tDATABASE"); err != nil {
+		return err
+	}
+	for _, snap := range snapshots {
+		commit := snap.Commit
+		if commit == "" {
+			commit = "-"
+		}
+		created := snap.Created.Local().Format("2006-01-02 15:04:05")
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", snap.Name, commit, created, snap.Database); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// completeSnapshotNames completes the names of the snapshots of the current
+// worktree.
+func completeSnapshotNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	pc, err := OpenProjectFromCWD()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	worktreePath, err := currentWorktreePath(pc)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	snapshots, err := snapshot.NewStore(pc.ProjectName, pc.ProjectPath, worktreePath).List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(snapshots))
+	for _, snap := range snapshots {
+		names = append(names, snap.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbSnapshotCmd)
+	dbCmd.AddCommand(dbRestoreCmd)
+	dbCmd.AddCommand(dbListSnapshotsCmd)
+	dbCmd.AddCommand(dbDropSnapshotCmd)
+	dbCmd.AddCommand(dbLsCmd)
+	dbCmd.AddCommand(dbGcCmd)
+
+	dbRestoreCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	dbGcCmd.Flags().BoolP("yes", "y", false, "Drop all orphaned databases without asking")
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/snapshot"
+)
+
+func TestWorktreeDatabase(t *testing.T) {
+	t.Run("server database", func(t *testing.T) {
+		worktree := filepath.Join(t.TempDir(), "feature-auth")
+		require.NoError(t, os.Mkdir(worktree, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(worktree, ".env"), []byte("DB_CONNECTION=mysql\n"), 0644))
+		require.NoError(t, config.WriteLocalState(worktree, config.LocalState{DbSuffix: "swift_runner"}))
+
+		db, err := worktreeDatabase(worktree, "feature-auth")
+		require.NoError(t, err)
+		assert.Equal(t, snapshot.Database{Engine: config.DBEngineMySQL, Name: "feature_auth_swift_runner"}, db)
+
+		require.NoError(t, os.WriteFile(filepath.Join(worktree, ".env"), []byte("DB_CONNECTION=mysql\nDB_DATABASE=shop_swift_runner\n"), 0644))
+		db, err = worktreeDatabase(worktree, "feature-auth")
+		require.NoError(t, err)
+		assert.Equal(t, "shop_swift_runner", db.Name, "DB_DATABASE is the database the app uses")
+	})
+
+	t.Run("unknown site name", func(t *testing.T) {
+		worktree := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(worktree, ".env"), []byte("DB_CONNECTION=mysql\n"), 0644))
+		require.NoError(t, config.WriteLocalState(worktree, config.LocalState{DbSuffix: "swift_runner"}))
+
+		_, err := worktreeDatabase(worktree, "")
+		assert.ErrorContains(t, err, "site name its db_suffix belongs to is unknown")
+	})
+
+	t.Run("sqlite file", func(t *testing.T) {
+		worktree := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(worktree, ".env"), []byte("DB_CONNECTION=sqlite\n"), 0644))
+
+		db, err := worktreeDatabase(worktree, "")
+		require.NoError(t, err)
+		assert.Equal(t, snapshot.Database{
+			Engine: config.DBEngineSQLite,
+			Name:   filepath.Join(worktree, "database", "database.sqlite"),
+		}, db)
+	})
+
+	t.Run("no database", func(t *testing.T) {
+		_, err := worktreeDatabase(t.TempDir(), "app")
+		assert.Error(t, err)
+	})
+}
+
+func TestListSnapshots(t *testing.T) {
+	project := t.TempDir()
+	worktree := filepath.Join(project, "feature-auth")
+	file := filepath.Join(worktree, "database.sqlite")
+	require.NoError(t, os.MkdirAll(worktree, 0755))
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0644))
+	store := snapshot.NewStore("app", project, worktree)
+
+	var out bytes.Buffer
+	require.NoError(t, listSnapshots(&out, store))
+	assert.Equal(t, "No snapshots.\n", out.String())
+
+	_, err := store.Take(context.Background(), snapshot.Database{Engine: config.DBEngineSQLite, Name: file}, "seeded", "abc1234")
+	require.NoError(t, err)
+
+	out.Reset()
+	require.NoError(t, listSnapshots(&out, store))
+	assert.Contains(t, out.String(), "NAME    COMMIT   CREATED")
+	assert.Contains(t, out.String(), "seeded  abc1234")
+	assert.Contains(t, out.String(), file)
+}
+
+func TestListDatabases(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, listDatabases(&out, nil))
+	assert.Equal(t, "No databases registered.\n", out.String())
+
+	worktree := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "removed")
+	out.Reset()
+	require.NoError(t, listDatabases(&out, []config.DatabaseRecord{
+		{Engine: config.DBEngineMySQL, Host: "127.0.0.1:3306", Name: "shop_swift_runner", Project: "shop", Worktree: missing},
+		{Engine: config.DBEnginePgSQL, Host: "127.0.0.1:5432", Name: "app_calm_river", Project: "app", Worktree: worktree},
+	}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "NAME")
+	assert.Contains(t, lines[1], "app_calm_river")
+	assert.Contains(t, lines[1], "pgsql")
+	assert.NotContains(t, lines[1], "(missing)")
+	assert.Contains(t, lines[2], "shop_swift_runner")
+	assert.Contains(t, lines[2], missing+" (missing)")
+}
+
+func TestFindOrphanDatabases(t *testing.T) {
+	live := t.TempDir()
+	removed := filepath.Join(t.TempDir(), "removed")
+	records := []config.DatabaseRecord{
+		{Engine: config.DBEngineMySQL, Host: "127.0.0.1:3306", Name: "app_swift_runner", Project: "app", Worktree: live},
+		{Engine: config.DBEngineMySQL, Host: "127.0.0.1:3306", Name: "shop_swift_runner", Project: "shop", Worktree: removed},
+		{Engine: config.DBEngineMySQL, Host: "127.0.0.1:3306", Name: "snap_seeded_shop_swift_runner", Project: "shop", Worktree: removed},
+		{Engine: config.DBEngineMySQL, Host: "db.local:3306", Name: "blog_calm_engine", Worktree: removed},
+	}
+	databases := []string{
+		"information_schema", "mysql", "app", "app_swift_runner",
+		"shop_swift_runner", "snap_seeded_shop_swift_runner",
+		"blog_calm_engine", "legacy_swift_runner", "blog_quick_parser",
+	}
+
+	orphans := findOrphanDatabases(config.DBEngineMySQL, "127.0.0.1:3306", databases, map[string]bool{"swift_runner": true}, records)
+
+	var names []string
+	for _, orphan := range orphans {
+		names = append(names, orphan.Record.Name)
+	}
+	assert.Equal(t, []string{"shop_swift_runner", "snap_seeded_shop_swift_runner", "blog_calm_engine", "blog_quick_parser"}, names,
+		"registered databases of removed worktrees and unregistered databases with an unused suffix")
+	assert.Equal(t, []bool{true, true, false, false}, []bool{orphans[0].Registered, orphans[1].Registered, orphans[2].Registered, orphans[3].Registered},
+		"suffix matches are told apart from registered orphans, as they may belong to another project")
+	assert.Equal(t, "shop", orphans[0].Record.Project)
+	assert.Contains(t, orphans[0].Reason, removed)
+	assert.Equal(t, "127.0.0.1:3306", orphans[2].Record.Host)
+	assert.Equal(t, "no worktree uses suffix calm_engine", orphans[2].Reason)
+
+	var out bytes.Buffer
+	require.NoError(t, listOrphanDatabases(&out, orphans))
+	assert.Contains(t, out.String(), "NAME")
+	assert.Contains(t, out.String(), "no worktree uses suffix quick_parser")
+}
+
+func TestScanLiveWorktrees(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, key := range []string{"ANVIL_DB_URL", "ANVIL_DB_HOST", "ANVIL_DB_PORT", "ANVIL_DB_USERNAME", "ANVIL_DB_PASSWORD"} {
+		t.Setenv(key, "")
+	}
+	pc, repoDir := makeTestProject(t, "app")
+	require.NoError(t, config.WriteLocalState(repoDir, config.LocalState{DbSuffix: "calm_engine"}))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".env"), []byte("DB_CONNECTION=pgsql\nDB_HOST=db.local\n"), 0644))
+
+	feature := addMergedWorktree(t, pc, "feature")
+	require.NoError(t, config.WriteLocalState(feature, config.LocalState{DbSuffix: "swift_runner"}))
+	require.NoError(t, os.WriteFile(filepath.Join(feature, ".env"), []byte("DB_CONNECTION=mysql\n"), 0644))
+	deleted := addMergedWorktree(t, pc, "deleted")
+	require.NoError(t, config.WriteLocalState(deleted, config.LocalState{DbSuffix: "quick_parser"}))
+	require.NoError(t, os.RemoveAll(deleted))
+
+	suffixes, servers, err := scanLiveWorktrees(pc.GlobalConfig)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"calm_engine": true, "swift_runner": true}, suffixes)
+	require.Len(t, servers, 2)
+	assert.Equal(t, config.DBEnginePgSQL, servers[0].engine)
+	assert.Equal(t, "db.local:5432", servers[0].opts.Address())
+	assert.Equal(t, config.DBEngineMySQL, servers[1].engine)
+	assert.Equal(t, "127.0.0.1:3306", servers[1].opts.Address())
+
+	pc.GlobalConfig.Projects["missing"] = &config.ProjectInfo{Path: filepath.Join(t.TempDir(), "missing")}
+	_, _, err = scanLiveWorktrees(pc.GlobalConfig)
+	assert.Error(t, err, "the worktrees of every linked project must be known")
+}
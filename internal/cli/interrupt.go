@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext returns a copy of parent that is cancelled on the first
+// Ctrl-C (SIGINT) or SIGTERM. The signal handler is removed as soon as it
+// fires, so a second Ctrl-C terminates anvil immediately. Call stop once the
+// interruptible operation has finished to restore the default behavior.
+func interruptContext(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -32,11 +33,17 @@ Examples:
   # Link with a specific preset
   anvil link --preset laravel
 
+  # Link with a preset from ~/.config/anvil/presets or .anvil/presets
+  anvil link --preset my-company-laravel
+
   # Link a specific path
   anvil link ~/Projects/my-app
 
   # Link with a custom name
-  anvil link --name my-custom-name`,
+  anvil link --name my-custom-name
+
+  # Scaffold new worktrees with the "minimal" profile by default
+  anvil link --profile minimal`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Determine the path to link
@@ -110,10 +117,18 @@ Examples:
 			defaultBranch = config.DefaultBranch
 		}
 
+		presetManager, err := presets.LoadManager(presets.Dirs(absPath)...)
+		if err != nil {
+			return fmt.Errorf("loading presets: %w", err)
+		}
+
 		// Detect or prompt for preset
 		preset := mustGetString(cmd, "preset")
-		if preset == "" {
-			presetManager := presets.NewManager()
+		if preset != "" {
+			if _, ok := presetManager.Get(preset); !ok {
+				return fmt.Errorf("unknown preset %q (available: %s)", preset, strings.Join(presetManager.Available(), ", "))
+			}
+		} else {
 			detected := presetManager.Detect(absPath)
 			if detected != "" {
 				preset = detected
@@ -140,6 +155,7 @@ Examples:
 			DefaultBranch: defaultBranch,
 			Preset:        preset,
 			SiteName:      siteName,
+			Profile:       mustGetString(cmd, "profile"),
 		}
 
 		// Add to global config
@@ -161,6 +177,9 @@ Examples:
 		if preset != "" {
 			ui.PrintInfo(fmt.Sprintf("Preset: %s", preset))
 		}
+		if projectInfo.Profile != "" {
+			ui.PrintInfo(fmt.Sprintf("Scaffold profile: %s", projectInfo.Profile))
+		}
 		ui.PrintInfo(fmt.Sprintf("Worktrees will be stored in: %s", projectWorktreeDir))
 		ui.PrintInfo("")
 		ui.PrintInfo("Create a new worktree with:")
@@ -173,9 +192,10 @@ Examples:
 func init() {
 	rootCmd.AddCommand(linkCmd)
 
-	linkCmd.Flags().String("preset", "", "Project preset (laravel, php)")
+	linkCmd.Flags().String("preset", "", "Project preset (built-in or from a preset file, see 'anvil preset list')")
 	linkCmd.Flags().String("name", "", "Custom name for the linked project (defaults to git remote repo name, then directory name)")
 	linkCmd.Flags().String("site-name", "", "Site name for scaffold steps (defaults to project name)")
+	linkCmd.Flags().String("profile", "", "Default scaffold profile for the project's worktrees")
 }
 
 // deriveProjectName determines the project name using the fallback chain:
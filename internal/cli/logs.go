@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show logs of past runs",
+	Long:  `Show the output recorded by past anvil runs.`,
+}
+
+var logsScaffoldCmd = &cobra.Command{
+	Use:   "scaffold [WORKTREE]",
+	Short: "Show the step output of past scaffold runs",
+	Long: `Shows the output of the steps of a past scaffold run.
+
+Every scaffold run writes the output of each step to
+.anvil/logs/<run-id>/ inside the worktree. The logs of the last 10 runs
+are kept.
+
+Arguments:
+  WORKTREE  Name of the worktree (folder name, branch name, or partial match)
+            If omitted, uses the current worktree.
+
+Examples:
+  anvil logs scaffold                          # Logs of the latest run
+  anvil logs scaffold auth --list              # List recorded runs
+  anvil logs scaffold auth --step migrate      # Output of a single step
+  anvil logs scaffold auth --run 20250101-120000`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pc, err := OpenProjectFromCWD()
+		if err != nil {
+			return fmt.Errorf("opening project: %w", err)
+		}
+
+		var worktreePath string
+		if len(args) > 0 {
+			worktreePath, err = findWorktreePath(pc.GitDir, args[0])
+		} else {
+			worktreePath, err = currentWorktreePath(pc)
+		}
+		if err != nil {
+			return err
+		}
+
+		if mustGetBool(cmd, "list") {
+			return listScaffoldRuns(os.Stdout, worktreePath)
+		}
+		return printScaffoldLogs(os.Stdout, worktreePath, mustGetString(cmd, "run"), mustGetString(cmd, "step"))
+	},
+}
+
+// currentWorktreePath returns the worktree that contains the current directory.
+func currentWorktreePath(pc *ProjectContext) (string, error) {
+	worktrees, err := git.ListWorktrees(pc.GitDir)
+	if err != nil {
+		return "", fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	cwd := evalPath(pc.CWD)
+	best := ""
+	for _, wt := range worktrees {
+		path := evalPath(wt.Path)
+		if (cwd == path || strings.HasPrefix(cwd, path+string(filepath.Separator))) && len(path) > len(best) {
+			best = wt.Path
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("not inside a worktree (pass the worktree name)")
+	}
+	return best, nil
+}
+
+func evalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
+
+// listScaffoldRuns prints the recorded scaffold runs of a worktree.
+func listScaffoldRuns(w io.Writer, worktreePath string) error {
+	runs, err := scaffold.ListRunLogs(worktreePath)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		_, err := fmt.Fprintln(w, "No scaffold runs recorded.")
+		return err
+	}
+
+	journal := latestJournal(worktreePath)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "RUN\tSTEPS\tSTATUS"); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		status := "-"
+		if journal != nil && journal.RunID == run.ID {
+			status = journal.Status
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%s\n", run.ID, len(run.Steps), status); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// printScaffoldLogs prints the step logs of a run (the latest if runID is
+// empty), or only the log of the step with the given key or id.
+func printScaffoldLogs(w io.Writer, worktreePath, runID, step string) error {
+	runs, err := scaffold.ListRunLogs(worktreePath)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no scaffold runs recorded for %s", filepath.Base(worktreePath))
+	}
+
+	run := runs[0]
+	if runID != "" {
+		found := false
+		for _, candidate := range runs {
+			if candidate.ID == runID {
+				run, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown run %q (see 'anvil logs scaffold --list')", runID)
+		}
+	}
+
+	files := make([]string, 0, len(run.Steps))
+	if step != "" {
+		path, ok := run.StepLog(step)
+		if !ok {
+			return fmt.Errorf("no log for step %q in run %s", step, run.ID)
+		}
+		files = append(files, path)
+	} else {
+		for _, name := range run.Steps {
+			files = append(files, filepath.Join(run.Dir, name))
+		}
+	}
+
+	for i, path := range files {
+		if len(files) > 1 {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "==> %s <==\n", filepath.Base(path)); err != nil {
+				return err
+			}
+		}
+		if err := copyFile(w, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestJournal returns the journal of the last scaffold run, or nil.
+func latestJournal(worktreePath string) *config.ScaffoldJournal {
+	state, err := config.ReadLocalState(worktreePath)
+	if err != nil {
+		return nil
+	}
+	return state.ScaffoldJournal
+}
+
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("reading log: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.AddCommand(logsScaffoldCmd)
+
+	logsScaffoldCmd.Flags().Bool("list", false, "List recorded runs")
+	logsScaffoldCmd.Flags().String("run", "", "Show the run with this id instead of the latest")
+	logsScaffoldCmd.Flags().String("step", "", "Only show the step with this key or id")
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+// writeRunLog creates the log directory of a run with the given step logs.
+func writeRunLog(t *testing.T, worktreePath, id string, steps map[string]string) {
+	t.Helper()
+	dir := filepath.Join(worktreePath, scaffold.LogsDir, id)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range steps {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestPrintScaffoldLogs(t *testing.T) {
+	worktree := t.TempDir()
+	writeRunLog(t, worktree, "20250101-090000", map[string]string{"01-install-1.log": "old\n"})
+	writeRunLog(t, worktree, "20250102-090000", map[string]string{
+		"01-install-1.log": "installing\n",
+		"02-migrate-1.log": "migrating\n",
+	})
+
+	t.Run("latest run", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printScaffoldLogs(&out, worktree, "", ""))
+		assert.Equal(t, "==> 01-install-1.log <==\ninstalling\n\n==> 02-migrate-1.log <==\nmigrating\n", out.String())
+	})
+
+	t.Run("single step", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printScaffoldLogs(&out, worktree, "", "migrate#1"))
+		assert.Equal(t, "migrating\n", out.String())
+	})
+
+	t.Run("older run", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printScaffoldLogs(&out, worktree, "20250101-090000", ""))
+		assert.Equal(t, "old\n", out.String())
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		err := printScaffoldLogs(&bytes.Buffer{}, worktree, "nope", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown run "nope"`)
+	})
+
+	t.Run("unknown step", func(t *testing.T) {
+		err := printScaffoldLogs(&bytes.Buffer{}, worktree, "", "seed#1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no log for step "seed#1"`)
+	})
+}
+
+func TestPrintScaffoldLogs_NoRuns(t *testing.T) {
+	err := printScaffoldLogs(&bytes.Buffer{}, t.TempDir(), "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no scaffold runs recorded")
+}
+
+func TestListScaffoldRuns(t *testing.T) {
+	worktree := t.TempDir()
+	writeRunLog(t, worktree, "20250101-090000", map[string]string{"01-install-1.log": ""})
+	writeRunLog(t, worktree, "20250102-090000", map[string]string{"01-install-1.log": "", "02-migrate-1.log": ""})
+	require.NoError(t, config.WriteLocalState(worktree, config.LocalState{
+		ScaffoldJournal: &config.ScaffoldJournal{RunID: "20250102-090000", Status: config.JournalFailed},
+	}))
+
+	var out bytes.Buffer
+	require.NoError(t, listScaffoldRuns(&out, worktree))
+
+	assert.Equal(t, "RUN              STEPS  STATUS\n"+
+		"20250102-090000  2      failed\n"+
+		"20250101-090000  1      -\n", out.String())
+}
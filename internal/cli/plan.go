@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+// printPlan prints a scaffold plan for people: one block per step with
+// the action, the reason, and the command and files of the step.
+func printPlan(w io.Writer, plan *scaffold.Plan) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Plan for %s", plan.Branch)
+	var details []string
+	if plan.Preset != "" {
+		details = append(details, "preset: "+plan.Preset)
+	}
+	if plan.Profile != "" {
+		details = append(details, "profile: "+plan.Profile)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+	fmt.Fprintf(&b, "\n%s\n", plan.Worktree)
+	if plan.PreFlight != "" {
+		fmt.Fprintf(&b, "\nPre-flight checks fail, the run would stop before the first step:\n%s\n", plan.PreFlight)
+	}
+
+	counts := make(map[string]int)
+	for i, step := range plan.Steps {
+		counts[step.Action]++
+		fmt.Fprintf(&b, "\n%d. %-5s %s  %s\n", i+1, step.Action, step.ID, step.Description)
+		fmt.Fprintf(&b, "   reason:  %s\n", step.Reason)
+		if step.Error != "" {
+			fmt.Fprintf(&b, "   error:   %s\n", step.Error)
+		}
+		if step.Command != "" {
+			fmt.Fprintf(&b, "   command: %s\n", step.Command)
+		}
+		if len(step.Files) > 0 {
+			fmt.Fprintf(&b, "   files:   %s\n", strings.Join(step.Files, ", "))
+		}
+		if len(step.DependsOn) > 0 {
+			fmt.Fprintf(&b, "   after:   %s\n", strings.Join(step.DependsOn, ", "))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%d to run, %d to skip", counts[scaffold.PlanRun], counts[scaffold.PlanSkip])
+	if counts[scaffold.PlanError] > 0 {
+		fmt.Fprintf(&b, ", %d with errors", counts[scaffold.PlanError])
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// printPlanJSON prints a scaffold plan as JSON.
+func printPlanJSON(w io.Writer, plan *scaffold.Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
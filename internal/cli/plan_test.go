@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+func testPlan() *scaffold.Plan {
+	return &scaffold.Plan{
+		Worktree: "/code/myapp/feature-auth",
+		Branch:   "feature/auth",
+		Preset:   "laravel",
+		Steps: []scaffold.PlannedStep{
+			{ID: "install", Name: "php.composer", Description: "Running php.composer (php.composer)", Action: scaffold.PlanRun, Reason: "no condition", Command: "composer install"},
+			{ID: "file.copy#2", Name: "file.copy", Description: "Copying files (file.copy)", Action: scaffold.PlanSkip, Reason: ".env.example does not exist", Files: []string{".env"}, DependsOn: []string{"install"}},
+			{ID: "broken", Name: "bash.run", Description: "Running bash command (bash.run)", Action: scaffold.PlanError, Reason: "no condition", Error: "template replacement failed"},
+		},
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printPlan(&out, testPlan()))
+
+	assert.Equal(t, `Plan for feature/auth (preset: laravel)
+/code/myapp/feature-auth
+
+1. run   install  Running php.composer (php.composer)
+   reason:  no condition
+   command: composer install
+
+2. skip  file.copy#2  Copying files (file.copy)
+   reason:  .env.example does not exist
+   files:   .env
+   after:   install
+
+3. error broken  Running bash command (bash.run)
+   reason:  no condition
+   error:   template replacement failed
+
+1 to run, 1 to skip, 1 with errors
+`, out.String())
+}
+
+func TestPrintPlanJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printPlanJSON(&out, testPlan()))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "feature/auth", decoded["branch"])
+
+	steps := decoded["steps"].([]any)
+	require.Len(t, steps, 3)
+	first := steps[0].(map[string]any)
+	assert.Equal(t, "install", first["id"])
+	assert.Equal(t, "run", first["action"])
+	assert.Equal(t, "composer install", first["command"])
+	assert.NotContains(t, first, "files")
+	assert.Equal(t, []any{"install"}, steps[1].(map[string]any)["dependsOn"])
+}
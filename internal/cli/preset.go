@@ -0,0 +1,358 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/presets"
+	"github.com/naoray/anvil/internal/scaffold"
+	"github.com/naoray/anvil/internal/scaffold/steps"
+	"github.com/naoray/anvil/internal/ui"
+)
+
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Manage scaffold presets",
+	Long: `Manage the presets that provide the default scaffold and cleanup steps.
+
+Besides the built-in presets, presets are loaded from YAML files in
+.anvil/presets/ of the project and in ~/.config/anvil/presets/. A project
+preset hides a user preset of the same name.`,
+}
+
+var presetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in and file presets",
+	Long: `Lists the presets available to the current project in detection order,
+with the file each preset file was loaded from. Outside a linked project
+only the built-in presets and the presets of ~/.config/anvil/presets/ are
+listed.
+
+Examples:
+  anvil preset list`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := loadPresetManager()
+		if err != nil {
+			return err
+		}
+		return listPresets(os.Stdout, manager)
+	},
+}
+
+var presetValidateCmd = &cobra.Command{
+	Use:   "validate [NAME|FILE]",
+	Short: "Check presets for errors without running them",
+	Long: `Checks presets without touching any worktree. Unknown steps, invalid step
+options, unknown condition keys, template syntax errors and dependencies on
+unknown steps are reported.
+
+Without an argument every preset available to the current project is
+checked. NAME checks a single preset; FILE checks a preset file, which may
+extend the presets of the preset directories.
+
+Examples:
+  anvil preset validate
+  anvil preset validate my-company-laravel
+  anvil preset validate ./my-company-laravel.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs, err := presetDirs()
+		if err != nil {
+			return err
+		}
+
+		var selected []presets.Preset
+		switch {
+		case len(args) == 1 && isPresetFile(args[0]):
+			preset, err := presets.LoadFile(args[0], dirs...)
+			if err != nil {
+				return fmt.Errorf("loading preset: %w", err)
+			}
+			selected = []presets.Preset{preset}
+		default:
+			manager, err := presets.LoadManager(dirs...)
+			if err != nil {
+				return fmt.Errorf("loading presets: %w", err)
+			}
+			selected = manager.Presets()
+			if len(args) == 1 {
+				preset, err := getPreset(manager, args[0])
+				if err != nil {
+					return err
+				}
+				selected = []presets.Preset{preset}
+			}
+		}
+
+		registry := steps.NewRegistry()
+		registry.RegisterDefaults()
+		return validatePresets(os.Stdout, scaffold.NewScaffoldManagerWithRegistry(registry), selected)
+	},
+}
+
+var presetShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Print the steps of a preset as YAML",
+	Long: `Prints the scaffold and cleanup steps of a preset as YAML, with their ids,
+dependencies and conditions. Steps of a preset file that extends another
+preset are shown merged into the steps of that preset.
+
+Examples:
+  anvil preset show laravel
+  anvil preset show my-company-laravel`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := loadPresetManager()
+		if err != nil {
+			return err
+		}
+		preset, err := getPreset(manager, args[0])
+		if err != nil {
+			return err
+		}
+		return printPreset(os.Stdout, preset)
+	},
+}
+
+var presetEjectCmd = &cobra.Command{
+	Use:   "eject NAME",
+	Short: "Copy the steps of a preset into anvil.yaml",
+	Long: `Writes the scaffold and cleanup steps of a preset into the anvil.yaml of
+the project root as scaffold.steps and cleanup.steps with override: true,
+so the project owns its whole setup and no longer follows changes to the
+preset.
+
+Steps that anvil.yaml already patches into the preset with before, after,
+replace and disable are merged in first. The rest of anvil.yaml, including
+comments, is kept.
+
+Examples:
+  anvil preset eject laravel
+  anvil preset eject laravel --dry-run   # Print the steps without writing`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pc, err := OpenProjectFromCWD()
+		if err != nil {
+			return err
+		}
+		preset, err := getPreset(pc.PresetManager(), args[0])
+		if err != nil {
+			return err
+		}
+
+		projectCfg := &config.Config{}
+		if _, err := os.Stat(filepath.Join(pc.ProjectPath, config.ProjectConfigFile)); err == nil {
+			if projectCfg, err = config.LoadProject(pc.ProjectPath); err != nil {
+				return fmt.Errorf("loading anvil.yaml: %w", err)
+			}
+		}
+
+		steps, cleanup, err := ejectSteps(preset, projectCfg)
+		if err != nil {
+			return err
+		}
+
+		if mustGetBool(cmd, "dry-run") {
+			ui.PrintInfo(fmt.Sprintf("[DRY RUN] Would write %d scaffold and %d cleanup steps to %s", len(steps), len(cleanup), filepath.Join(pc.ProjectPath, config.ProjectConfigFile)))
+			return printSteps(os.Stdout, "", steps, cleanup)
+		}
+
+		if err := config.SaveProjectSteps(pc.ProjectPath, steps, cleanup); err != nil {
+			return fmt.Errorf("saving anvil.yaml: %w", err)
+		}
+		ui.PrintDone(fmt.Sprintf("Wrote %d scaffold and %d cleanup steps of preset %s to anvil.yaml", len(steps), len(cleanup), preset.Name()))
+		return nil
+	},
+}
+
+// ejectSteps returns the steps preset eject writes: the steps of preset
+// with the steps of the project config merged in, or only the configured
+// steps where the config already overrides the preset.
+func ejectSteps(preset presets.Preset, projectCfg *config.Config) ([]config.StepConfig, []config.CleanupStep, error) {
+	if projectCfg.Scaffold.Override && projectCfg.Cleanup.Override {
+		return nil, nil, fmt.Errorf("anvil.yaml already overrides the scaffold and cleanup steps of the preset")
+	}
+
+	presetSteps := preset.DefaultSteps()
+	if projectCfg.Scaffold.Override {
+		presetSteps = nil
+	}
+	steps, err := scaffold.ApplyStepPatches(presetSteps, projectCfg.Scaffold)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merging the steps of anvil.yaml: %w", err)
+	}
+
+	presetCleanup := preset.CleanupSteps()
+	if projectCfg.Cleanup.Override {
+		presetCleanup = nil
+	}
+	cleanup, err := scaffold.ApplyCleanupPatches(presetCleanup, projectCfg.Cleanup)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merging the cleanup steps of anvil.yaml: %w", err)
+	}
+	return steps, cleanup, nil
+}
+
+// getPreset returns the preset called name.
+func getPreset(manager *presets.Manager, name string) (presets.Preset, error) {
+	preset, ok := manager.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(manager.Available(), ", "))
+	}
+	return preset, nil
+}
+
+// printPreset prints the steps of a preset as YAML, headed by its name,
+// source and description.
+func printPreset(w io.Writer, preset presets.Preset) error {
+	header := preset.Name() + " (built-in)"
+	if file, ok := preset.(*presets.FilePreset); ok {
+		header = fmt.Sprintf("%s (%s)", preset.Name(), file.Path())
+	}
+	if preset.Description() != "" {
+		header += ": " + preset.Description()
+	}
+	return printSteps(w, header, preset.DefaultSteps(), preset.CleanupSteps())
+}
+
+// printSteps prints scaffold and cleanup steps as the scaffold and cleanup
+// sections of anvil.yaml, with comment as head comment.
+func printSteps(w io.Writer, comment string, steps []config.StepConfig, cleanup []config.CleanupStep) error {
+	stepsNode, err := config.EncodeSteps(steps)
+	if err != nil {
+		return err
+	}
+	cleanupNode, err := config.EncodeCleanupSteps(cleanup)
+	if err != nil {
+		return err
+	}
+
+	key := func(value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	}
+	section := func(steps *yaml.Node) *yaml.Node {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key("steps"), steps}}
+	}
+	doc := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: comment,
+		Content: []*yaml.Node{{
+			Kind:    yaml.MappingNode,
+			Tag:     "!!map",
+			Content: []*yaml.Node{key("scaffold"), section(stepsNode), key("cleanup"), section(cleanupNode)},
+		}},
+	}
+
+	content, err := config.MarshalYAML(doc)
+	if err != nil {
+		return fmt.Errorf("marshaling steps: %w", err)
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// presetDirs returns the preset directories of the current project, or
+// only the user's preset directory outside a linked project.
+func presetDirs() ([]string, error) {
+	pc, err := OpenProjectFromCWD()
+	if errors.Is(err, errNotInProject) {
+		return presets.Dirs(""), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening project: %w", err)
+	}
+	return presets.Dirs(pc.ProjectPath), nil
+}
+
+// loadPresetManager returns the presets of the current project, or the
+// built-in and user presets outside a linked project.
+func loadPresetManager() (*presets.Manager, error) {
+	dirs, err := presetDirs()
+	if err != nil {
+		return nil, err
+	}
+	manager, err := presets.LoadManager(dirs...)
+	if err != nil {
+		return nil, fmt.Errorf("loading presets: %w", err)
+	}
+	return manager, nil
+}
+
+// isPresetFile reports whether the argument of preset validate names a
+// preset file rather than a preset.
+func isPresetFile(arg string) bool {
+	ext := filepath.Ext(arg)
+	return ext == ".yaml" || ext == ".yml" || strings.ContainsRune(filepath.ToSlash(arg), '/')
+}
+
+// validatePresets checks the presets with sm and prints the problems found
+// per preset. It fails if any preset has problems.
+func validatePresets(w io.Writer, sm *scaffold.ScaffoldManager, selected []presets.Preset) error {
+	failed := 0
+	for _, preset := range selected {
+		name := preset.Name()
+		if file, ok := preset.(*presets.FilePreset); ok {
+			name = fmt.Sprintf("%s (%s)", name, file.Path())
+		}
+
+		problems := sm.ValidatePreset(preset)
+		if len(problems) == 0 {
+			if _, err := fmt.Fprintf(w, "ok    %s\n", name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		failed++
+		if _, err := fmt.Fprintf(w, "FAIL  %s\n", name); err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			// Validators join several errors with newlines
+			message := strings.ReplaceAll(problem.Error(), "\n", "\n        ")
+			if _, err := fmt.Fprintf(w, "        %s\n", message); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d presets are invalid", failed, len(selected))
+	}
+	return nil
+}
+
+// listPresets prints the presets of manager with their source.
+func listPresets(w io.Writer, manager *presets.Manager) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NAME\tSOURCE\tDESCRIPTION"); err != nil {
+		return err
+	}
+	for _, preset := range manager.Presets() {
+		source := "built-in"
+		if file, ok := preset.(*presets.FilePreset); ok {
+			source = file.Path()
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", preset.Name(), source, preset.Description()); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(presetCmd)
+	presetCmd.AddCommand(presetListCmd)
+	presetCmd.AddCommand(presetValidateCmd)
+	presetCmd.AddCommand(presetShowCmd)
+	presetCmd.AddCommand(presetEjectCmd)
+}
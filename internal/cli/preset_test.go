@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/presets"
+	"github.com/naoray/anvil/internal/scaffold"
+	"github.com/naoray/anvil/internal/scaffold/steps"
+)
+
+func TestListPresets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "my-company-laravel.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("description: Laravel with tenants\nextends: laravel\n"), 0644))
+
+	manager, err := presets.LoadManager(dir)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, listPresets(&out, manager))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Regexp(t, `^NAME\s+SOURCE\s+DESCRIPTION$`, lines[0])
+	assert.Regexp(t, `^my-company-laravel\s+`+regexp.QuoteMeta(path)+`\s+Laravel with tenants$`, lines[1])
+	assert.Regexp(t, `^laravel-shared-db\s+built-in\s+`, lines[2])
+}
+
+func TestValidatePresets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+scaffold:
+  steps:
+    - name: php.laravel.artisan
+      id: migrate
+      args: [migrate]
+    - name: bash.run
+      command: "{{ .SiteName"
+`), 0644))
+
+	manager, err := presets.LoadManager(dir)
+	require.NoError(t, err)
+	broken, _ := manager.Get("broken")
+	laravel, _ := manager.Get("laravel")
+
+	registry := steps.NewRegistry()
+	registry.RegisterDefaults()
+	sm := scaffold.NewScaffoldManagerWithRegistry(registry)
+
+	var out bytes.Buffer
+	require.NoError(t, validatePresets(&out, sm, []presets.Preset{laravel}))
+	assert.Equal(t, "ok    laravel\n", out.String())
+
+	out.Reset()
+	err = validatePresets(&out, sm, []presets.Preset{laravel, broken})
+	require.Error(t, err)
+	assert.Equal(t, "1 of 2 presets are invalid", err.Error())
+	assert.Contains(t, out.String(), "FAIL  broken ("+path+")\n")
+	assert.Contains(t, out.String(), `        step migrate: creating step "php.laravel.artisan": unknown step`)
+	assert.Contains(t, out.String(), "        step bash.run#2: command: invalid template:")
+}
+
+func TestIsPresetFile(t *testing.T) {
+	assert.True(t, isPresetFile("my-company-laravel.yaml"))
+	assert.True(t, isPresetFile("presets/team.yml"))
+	assert.True(t, isPresetFile("./team"))
+	assert.False(t, isPresetFile("my-company-laravel"))
+}
+
+func TestPrintPreset(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printPreset(&out, presets.NewPHP()))
+
+	assert.Equal(t, `# php (built-in): PHP project installed with Composer
+
+scaffold:
+  steps:
+    - name: php.composer
+      id: composer-install
+      depends_on: []
+      args: [install]
+      condition:
+        file_exists: composer.lock
+    - name: php.composer
+      id: composer-update
+      depends_on: [composer-install]
+      args: [update]
+      condition:
+        not:
+          file_exists: composer.lock
+cleanup:
+  steps: []
+`, out.String())
+}
+
+func TestEjectSteps(t *testing.T) {
+	preset := presets.NewLaravel()
+
+	t.Run("merges the steps of anvil.yaml", func(t *testing.T) {
+		steps, cleanup, err := ejectSteps(preset, &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Disable: []string{"herd-link"},
+				Steps:   []config.StepConfig{{Name: "bash.run", ID: "assets", Command: "make assets", StepPatch: config.StepPatch{After: "npm-build"}}},
+			},
+			Cleanup: config.CleanupConfig{Disable: []string{"db-destroy"}},
+		})
+		require.NoError(t, err)
+
+		ids := make([]string, len(steps))
+		for i, step := range steps {
+			ids[i] = step.ID
+			assert.Empty(t, step.After)
+		}
+		assert.NotContains(t, ids, "herd-link")
+		assert.Contains(t, ids, "assets")
+		require.Len(t, cleanup, 1)
+		assert.Equal(t, "herd-unlink", cleanup[0].ID)
+	})
+
+	t.Run("keeps overridden steps", func(t *testing.T) {
+		steps, cleanup, err := ejectSteps(preset, &config.Config{
+			Scaffold: config.ScaffoldConfig{Override: true, Steps: []config.StepConfig{{Name: "bash.run", Command: "make"}}},
+		})
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+		assert.Equal(t, preset.CleanupSteps(), cleanup)
+	})
+
+	t.Run("nothing left to eject", func(t *testing.T) {
+		_, _, err := ejectSteps(preset, &config.Config{
+			Scaffold: config.ScaffoldConfig{Override: true},
+			Cleanup:  config.CleanupConfig{Override: true},
+		})
+		require.Error(t, err)
+	})
+}
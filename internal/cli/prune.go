@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -8,6 +10,7 @@ import (
 
 	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold"
 	"github.com/naoray/anvil/internal/ui"
 )
 
@@ -133,8 +136,14 @@ func pruneProject(pc *ProjectContext, force, dryRun, verbose, quiet bool) error
 			}
 
 			siteName := filepath.Base(wt.Path)
-			if err := pc.ScaffoldManager().RunCleanup(wt.Path, wt.Branch, "", siteName, preset, pc.Config, false, verbose, quiet); err != nil {
+			ctx, stop := interruptContext(context.Background())
+			err := pc.ScaffoldManager().RunCleanup(ctx, wt.Path, wt.Branch, "", siteName, preset, pc.Config, false, verbose, quiet)
+			stop()
+			if err != nil {
 				ui.PrintErrorWithHint("Cleanup failed", err.Error())
+				if errors.Is(err, scaffold.ErrInterrupted) {
+					return err
+				}
 			}
 
 			if err := git.RemoveWorktree(pc.GitDir, wt.Path, true); err != nil {
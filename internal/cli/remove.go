@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	anvilerrors "github.com/naoray/anvil/internal/errors"
 	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold"
 	"github.com/naoray/anvil/internal/ui"
 )
 
@@ -22,7 +24,10 @@ Arguments:
 
 Cleanup steps may include:
   - Removing Herd site links
-  - Database cleanup prompts`,
+  - Database cleanup prompts
+
+--only, --skip and --tag run a subset of the cleanup steps, selected by
+id, name, or tag like scaffold steps.`,
 	Args:              cobra.MaximumNArgs(1),
 	ValidArgsFunction: completeWorktreeNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -35,6 +40,7 @@ Cleanup steps may include:
 		dryRun := mustGetBool(cmd, "dry-run")
 		verbose := mustGetBool(cmd, "verbose")
 		quiet := mustGetBool(cmd, "quiet")
+		selection := stepSelectionFromFlags(cmd)
 
 		currentWorktreePath, err := os.Getwd()
 		if err != nil {
@@ -78,6 +84,17 @@ Cleanup steps may include:
 			return fmt.Errorf("cannot remove main worktree")
 		}
 
+		// Check the selection before anything is removed
+		if !selection.IsZero() {
+			stepsList, err := pc.ScaffoldManager().GetCleanupSteps(pc.Config, targetWorktree.Path, targetWorktree.Branch)
+			if err != nil {
+				return fmt.Errorf("getting cleanup steps: %w", err)
+			}
+			if _, err := scaffold.DescribeSteps(stepsList, selection); err != nil {
+				return err
+			}
+		}
+
 		ui.PrintInfo(fmt.Sprintf("Removing %s at %s", targetWorktree.Branch, targetWorktree.Path))
 
 		deleteBranch := false
@@ -120,8 +137,15 @@ Cleanup steps may include:
 
 			if preset != "" {
 				siteName := filepath.Base(targetWorktree.Path)
-				if err := pc.ScaffoldManager().RunCleanup(targetWorktree.Path, targetWorktree.Branch, "", siteName, preset, pc.Config, false, verbose, quiet); err != nil {
+				ctx, stop := interruptContext(cmd.Context())
+				runOpts := scaffold.RunOptions{Verbose: verbose, Quiet: quiet, Selection: selection, Interactive: ui.ShouldPrompt(cmd, false)}
+				err := pc.ScaffoldManager().RunCleanupWithOptions(ctx, targetWorktree.Path, targetWorktree.Branch, "", siteName, preset, pc.Config, runOpts)
+				stop()
+				if err != nil {
 					ui.PrintErrorWithHint("Cleanup failed", err.Error())
+					if errors.Is(err, scaffold.ErrInterrupted) {
+						return err
+					}
 				}
 			}
 
@@ -162,4 +186,5 @@ func init() {
 
 	removeCmd.Flags().BoolP("force", "f", false, "Skip confirmation and cleanup prompts")
 	removeCmd.Flags().Bool("delete-branch", false, "Also delete the branch after removing worktree")
+	addStepSelectionFlags(removeCmd)
 }
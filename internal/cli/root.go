@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -190,7 +191,11 @@ Commands:
   remove       Remove a worktree
   prune        Remove merged worktrees
   scaffold     Run scaffold steps for a worktree
+  logs         Show the step output of past scaffold runs
+  db           Manage worktree databases and snapshots
   pull-config  Copy anvil.yaml from default branch worktree
+  config       Inspect merged project configuration
+  preset       Manage scaffold presets
   repair       Repair git configuration for existing project
   install      Setup global configuration
   version      Show anvil version
@@ -239,3 +244,27 @@ func mustGetBool(cmd *cobra.Command, name string) bool {
 	}
 	return value
 }
+
+func mustGetStringSlice(cmd *cobra.Command, name string) []string {
+	value, err := cmd.Flags().GetStringSlice(name)
+	if err != nil {
+		panic(fmt.Sprintf("programming error: flag %q not defined: %v", name, err))
+	}
+	return value
+}
+
+func mustGetStringArray(cmd *cobra.Command, name string) []string {
+	value, err := cmd.Flags().GetStringArray(name)
+	if err != nil {
+		panic(fmt.Sprintf("programming error: flag %q not defined: %v", name, err))
+	}
+	return value
+}
+
+func mustGetDuration(cmd *cobra.Command, name string) time.Duration {
+	value, err := cmd.Flags().GetDuration(name)
+	if err != nil {
+		panic(fmt.Sprintf("programming error: flag %q not defined: %v", name, err))
+	}
+	return value
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold"
 	"github.com/naoray/anvil/internal/ui"
 )
 
@@ -20,10 +21,45 @@ Arguments:
             If omitted and inside a worktree, scaffolds the current worktree.
             If omitted and not inside a worktree, prompts for selection.
 
+Every run records the outcome of each step in the worktree's .anvil.local.
+Use --resume to skip the steps that already succeeded in the last run, or
+--from to restart at a specific step (by id, or name:args#position).
+
+--only, --skip and --tag run a subset of the steps. Steps are selected by
+id, by name (all steps of that name), or by name and arguments, e.g.
+node.npm:run-build. --list-steps shows the steps and how to select them.
+
+--plan shows what a run would do without running anything: for every step
+whether it runs or is skipped and why, the command line and the files it
+writes. Conditions are evaluated against the worktree and templates are
+rendered, with placeholders for values that steps would produce. Add
+--json for machine-readable output.
+
+--profile runs the steps of a profile (see profiles: in anvil.yaml)
+instead of the project's default profile; --profile "" runs all steps.
+
+--var KEY=VALUE sets the project variable KEY (see vars: in anvil.yaml),
+which is saved in .anvil.local for later runs, or answers the prompt step
+that stores KEY without asking. Without a terminal or with
+--no-interactive, prompts use their default instead.
+
+Ctrl-C stops the running steps and the commands they started; press it
+again to exit immediately. --timeout limits the duration of the whole run
+(overrides scaffold.timeout).
+
 Examples:
-  anvil scaffold feature-auth       # Scaffold by folder name
-  anvil scaffold auth               # Partial match
-  anvil scaffold feature/auth       # Match by branch name`,
+  anvil scaffold feature-auth          # Scaffold by folder name
+  anvil scaffold auth                  # Partial match
+  anvil scaffold feature/auth          # Match by branch name
+  anvil scaffold auth --resume         # Continue after a failed run
+  anvil scaffold auth --from migrate   # Restart at the step with id "migrate"
+  anvil scaffold auth --only migrate   # Run only the step with id "migrate"
+  anvil scaffold auth --tag assets     # Run only the steps tagged "assets"
+  anvil scaffold auth --list-steps     # List the steps that would run
+  anvil scaffold auth --plan --json    # Show what a run would do, as JSON
+  anvil scaffold auth --var seed=demo  # Set the variable or prompt "seed"
+  anvil scaffold auth --profile ci     # Run the steps of the "ci" profile
+  anvil scaffold auth --timeout 20m    # Give up after 20 minutes`,
 	Args:              cobra.MaximumNArgs(1),
 	ValidArgsFunction: completeWorktreeNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -35,6 +71,23 @@ Examples:
 		dryRun := mustGetBool(cmd, "dry-run")
 		verbose := mustGetBool(cmd, "verbose")
 		quiet := mustGetBool(cmd, "quiet")
+		resume := mustGetBool(cmd, "resume")
+		from := mustGetString(cmd, "from")
+		timeout := mustGetDuration(cmd, "timeout")
+		if timeout < 0 {
+			return fmt.Errorf("--timeout must not be negative")
+		}
+		selection := stepSelectionFromFlags(cmd)
+		vars, err := varsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		listSteps := mustGetBool(cmd, "list-steps")
+		plan := mustGetBool(cmd, "plan")
+		jsonOutput := mustGetBool(cmd, "json")
+		if jsonOutput && !plan {
+			return fmt.Errorf("--json requires --plan")
+		}
 
 		worktrees, err := git.ListWorktreesDetailed(pc.GitDir, pc.CWD, pc.DefaultBranch)
 		if err != nil {
@@ -95,7 +148,7 @@ Examples:
 				return fmt.Errorf("current worktree not found")
 			}
 
-			if ui.IsInteractive() {
+			if ui.IsInteractive() && !listSteps && !plan {
 				confirmed, err := ui.ConfirmScaffold(selectedWorktree.Branch)
 				if err != nil {
 					return err
@@ -120,19 +173,27 @@ Examples:
 		if selectedWorktree == nil {
 			return fmt.Errorf("no worktree selected")
 		}
+		if err := applyWorktreeProfile(cmd, pc.Config, selectedWorktree.Path); err != nil {
+			return err
+		}
 
-		ui.PrintStep(fmt.Sprintf("Scaffolding worktree: %s", selectedWorktree.Branch))
-		ui.PrintInfo(fmt.Sprintf("Path: %s", selectedWorktree.Path))
+		if listSteps {
+			stepsList, err := pc.ScaffoldManager().GetStepsForWorktree(pc.Config, selectedWorktree.Path, selectedWorktree.Branch)
+			if err != nil {
+				return fmt.Errorf("getting scaffold steps: %w", err)
+			}
+			infos, err := scaffold.DescribeSteps(stepsList, selection)
+			if err != nil {
+				return err
+			}
+			return printStepList(cmd.OutOrStdout(), infos)
+		}
 
 		preset := pc.Config.Preset
 		if preset == "" {
 			preset = pc.PresetManager().Detect(selectedWorktree.Path)
 		}
 
-		if verbose && preset != "" {
-			ui.PrintInfo(fmt.Sprintf("Running scaffold for preset: %s", preset))
-		}
-
 		repoName := filepath.Base(pc.ProjectPath)
 		worktreeName := filepath.Base(selectedWorktree.Path)
 
@@ -143,8 +204,44 @@ Examples:
 			siteName = pc.Config.SiteName
 		}
 
-		if err := pc.ScaffoldManager().RunScaffold(selectedWorktree.Path, selectedWorktree.Branch, repoName, siteName, preset, pc.Config, dryRun, verbose, quiet); err != nil {
+		if plan {
+			scaffoldPlan, err := pc.ScaffoldManager().PlanScaffold(selectedWorktree.Path, selectedWorktree.Branch, repoName, siteName, preset, pc.Config, selection, vars)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printPlanJSON(cmd.OutOrStdout(), scaffoldPlan)
+			}
+			return printPlan(cmd.OutOrStdout(), scaffoldPlan)
+		}
+
+		ui.PrintStep(fmt.Sprintf("Scaffolding worktree: %s", selectedWorktree.Branch))
+		ui.PrintInfo(fmt.Sprintf("Path: %s", selectedWorktree.Path))
+
+		if verbose && preset != "" {
+			ui.PrintInfo(fmt.Sprintf("Running scaffold for preset: %s", preset))
+		}
+		if verbose && pc.Config.Profile != "" {
+			ui.PrintInfo(fmt.Sprintf("Using scaffold profile: %s", pc.Config.Profile))
+		}
+
+		runOpts := scaffold.RunOptions{
+			DryRun:      dryRun,
+			Verbose:     verbose,
+			Quiet:       quiet,
+			Resume:      scaffold.ResumeOptions{Resume: resume, From: from},
+			Selection:   selection,
+			Timeout:     timeout,
+			Interactive: ui.ShouldPrompt(cmd, false),
+			Vars:        vars,
+		}
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+		if err := pc.ScaffoldManager().RunScaffoldWithOptions(ctx, selectedWorktree.Path, selectedWorktree.Branch, repoName, siteName, preset, pc.Config, runOpts); err != nil {
 			ui.PrintErrorWithHint("Scaffold steps failed", err.Error())
+			if !dryRun {
+				ui.PrintInfo(fmt.Sprintf("Run 'anvil scaffold %s --resume' to continue from the failed step", filepath.Base(selectedWorktree.Path)))
+			}
 			return err
 		}
 
@@ -155,4 +252,18 @@ Examples:
 
 func init() {
 	rootCmd.AddCommand(scaffoldCmd)
+
+	scaffoldCmd.Flags().Bool("resume", false, "Skip steps that succeeded in the last scaffold run")
+	scaffoldCmd.Flags().String("from", "", "Restart at the given step id, skipping the steps before it")
+	scaffoldCmd.MarkFlagsMutuallyExclusive("resume", "from")
+	addStepSelectionFlags(scaffoldCmd)
+	addVarFlag(scaffoldCmd)
+	addProfileFlag(scaffoldCmd)
+	scaffoldCmd.Flags().Bool("list-steps", false, "List the scaffold steps and their ids without running them")
+	scaffoldCmd.Flags().Bool("plan", false, "Show what each step would do without running anything")
+	scaffoldCmd.Flags().Bool("json", false, "Output the plan as JSON (with --plan)")
+	scaffoldCmd.MarkFlagsMutuallyExclusive("plan", "list-steps")
+	scaffoldCmd.MarkFlagsMutuallyExclusive("plan", "resume")
+	scaffoldCmd.MarkFlagsMutuallyExclusive("plan", "from")
+	scaffoldCmd.Flags().Duration("timeout", 0, "Maximum duration of the scaffold run, e.g. 30m (0 uses scaffold.timeout)")
 }
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+// addStepSelectionFlags adds the flags that select which scaffold or
+// cleanup steps run.
+func addStepSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("only", nil, "Run only the given steps (id, name or name:args; repeatable)")
+	cmd.Flags().StringSlice("skip", nil, "Do not run the given steps (id, name or name:args; repeatable)")
+	cmd.Flags().StringSlice("tag", nil, "Run only the steps with one of the given tags (repeatable)")
+}
+
+// stepSelectionFromFlags returns the step selection of the flags added by
+// addStepSelectionFlags.
+func stepSelectionFromFlags(cmd *cobra.Command) scaffold.StepSelection {
+	return scaffold.StepSelection{
+		Only: mustGetStringSlice(cmd, "only"),
+		Skip: mustGetStringSlice(cmd, "skip"),
+		Tags: mustGetStringSlice(cmd, "tag"),
+	}
+}
+
+// addProfileFlag adds the --profile flag that selects the scaffold profile.
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("profile", "", "Scaffold with the given profile instead of the project's default profile (\"\" for none)")
+}
+
+// applyProfileFlag makes cfg use the profile passed with --profile. An
+// empty value runs all steps even if the project has a default profile.
+func applyProfileFlag(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("profile") {
+		cfg.Profile = mustGetString(cmd, "profile")
+	}
+}
+
+// applyWorktreeProfile makes cfg use the profile passed with --profile, or
+// else the profile the worktree at worktreePath was last scaffolded with,
+// so --resume and later runs select the same steps.
+func applyWorktreeProfile(cmd *cobra.Command, cfg *config.Config, worktreePath string) error {
+	if cmd.Flags().Changed("profile") {
+		cfg.Profile = mustGetString(cmd, "profile")
+		return nil
+	}
+	state, err := config.ReadLocalState(worktreePath)
+	if err != nil {
+		return fmt.Errorf("reading local state: %w", err)
+	}
+	if state.Profile != nil {
+		cfg.Profile = *state.Profile
+	}
+	return nil
+}
+
+// addVarFlag adds the --var flag that sets project variables and answers
+// prompt steps.
+func addVarFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("var", nil, "Set the project variable or answer the prompt KEY (KEY=VALUE; repeatable)")
+}
+
+// varsFromFlags returns the values of the --var flag by name.
+func varsFromFlags(cmd *cobra.Command) (map[string]string, error) {
+	values := mustGetStringArray(cmd, "var")
+	if len(values) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]string, len(values))
+	for _, value := range values {
+		name, val, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: expected KEY=VALUE", value)
+		}
+		vars[name] = val
+	}
+	return vars, nil
+}
+
+// printStepList prints steps with the keys they can be selected by.
+func printStepList(w io.Writer, steps []scaffold.StepInfo) error {
+	if len(steps) == 0 {
+		_, err := fmt.Fprintln(w, "No steps to run.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "STEP\tDESCRIPTION\tTAGS"); err != nil {
+		return err
+	}
+	for _, step := range steps {
+		tags := "-"
+		if len(step.Tags) > 0 {
+			tags = strings.Join(step.Tags, ", ")
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", step.Key, step.Description, tags); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold"
+)
+
+func TestPrintStepList(t *testing.T) {
+	t.Run("steps", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printStepList(&out, []scaffold.StepInfo{
+			{Key: "install", Name: "php.composer", Description: "Running php.composer (php.composer)"},
+			{Key: "node.npm:run-build#2", Name: "node.npm", Description: "Running node.npm (node.npm)", Tags: []string{"assets", "ci"}},
+		}))
+		assert.Equal(t, "STEP                  DESCRIPTION                          TAGS\n"+
+			"install               Running php.composer (php.composer)  -\n"+
+			"node.npm:run-build#2  Running node.npm (node.npm)          assets, ci\n", out.String())
+	})
+
+	t.Run("no steps", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printStepList(&out, nil))
+		assert.Equal(t, "No steps to run.\n", out.String())
+	})
+}
+
+func TestVarsFromFlags(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		addVarFlag(cmd)
+		require.NoError(t, cmd.Flags().Parse(args))
+		return cmd
+	}
+
+	vars, err := varsFromFlags(newCmd())
+	require.NoError(t, err)
+	assert.Nil(t, vars)
+
+	vars, err = varsFromFlags(newCmd("--var", "seed=demo", "--var", "token=a=b,c"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"seed": "demo", "token": "a=b,c"}, vars)
+
+	_, err = varsFromFlags(newCmd("--var", "seed"))
+	require.Error(t, err)
+	assert.Equal(t, `invalid --var "seed": expected KEY=VALUE`, err.Error())
+}
+
+func TestApplyWorktreeProfile(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		addProfileFlag(cmd)
+		require.NoError(t, cmd.Flags().Parse(args))
+		return cmd
+	}
+	worktree := t.TempDir()
+
+	cfg := &config.Config{Profile: "default"}
+	require.NoError(t, applyWorktreeProfile(newCmd(), cfg, worktree))
+	assert.Equal(t, "default", cfg.Profile, "without a recorded profile the project default applies")
+
+	none := ""
+	require.NoError(t, config.WriteLocalState(worktree, config.LocalState{Profile: &none}))
+	require.NoError(t, applyWorktreeProfile(newCmd(), cfg, worktree))
+	assert.Equal(t, "", cfg.Profile, "the recorded profile wins over the project default")
+
+	require.NoError(t, applyWorktreeProfile(newCmd("--profile", "ci"), cfg, worktree))
+	assert.Equal(t, "ci", cfg.Profile, "--profile wins over the recorded profile")
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -300,13 +302,13 @@ Configuration can be set via flags, project config (anvil.yaml), or interactivel
 		}
 
 		if shouldSave {
-			pc.Config.Sync = config.SyncConfig{
+			syncCfg := config.SyncConfig{
 				Upstream:  upstream,
 				Strategy:  strategy,
 				Remote:    remote,
 				AutoStash: &autoStash,
 			}
-			if err := config.SaveProject(pc.ProjectPath, pc.Config); err != nil {
+			if err := saveSyncConfig(pc.ProjectPath, syncCfg); err != nil {
 				ui.PrintError(fmt.Sprintf("Failed to save sync config: %v", err))
 			} else {
 				ui.PrintSuccess("Saved sync settings to anvil.yaml")
@@ -328,3 +330,17 @@ func init() {
 	syncCmd.Flags().BoolP("yes", "y", false, "Skip confirmations and run with chosen values")
 	syncCmd.Flags().Bool("no-auto-stash", false, "Disable automatic stashing of all changes before sync")
 }
+
+// saveSyncConfig saves the sync settings to the anvil.yaml at the project
+// root. Only that layer is written back, so values of the global config
+// and of the default branch worktree do not end up in the committed file.
+func saveSyncConfig(projectPath string, syncCfg config.SyncConfig) error {
+	rootCfg := &config.Config{}
+	if _, err := os.Stat(filepath.Join(projectPath, config.ProjectConfigFile)); err == nil {
+		if rootCfg, err = config.LoadProject(projectPath); err != nil {
+			return fmt.Errorf("loading anvil.yaml: %w", err)
+		}
+	}
+	rootCfg.Sync = syncCfg
+	return config.SaveProject(projectPath, rootCfg)
+}
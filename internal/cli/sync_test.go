@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/git"
@@ -228,6 +229,44 @@ func TestSyncCommand_SaveConfig(t *testing.T) {
 	assert.Equal(t, "origin", loadedConfig.Sync.Remote)
 }
 
+func TestSaveSyncConfig_OnlyWritesRootLayer(t *testing.T) {
+	projectDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, config.ProjectConfigFile), []byte("# Team settings\nscaffold:\n  steps: []\n"), 0644))
+
+	// The merged config carries the values of the global project entry
+	resolved, err := config.ResolveProjectConfig(projectDir, t.TempDir(), &config.ProjectInfo{
+		Path:     projectDir,
+		Preset:   "laravel",
+		SiteName: "shop",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "laravel", resolved.Config.Preset)
+
+	autoStash := true
+	require.NoError(t, saveSyncConfig(projectDir, config.SyncConfig{Upstream: "develop", Strategy: "rebase", Remote: "origin", AutoStash: &autoStash}))
+
+	content, err := os.ReadFile(filepath.Join(projectDir, config.ProjectConfigFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "preset")
+	assert.NotContains(t, string(content), "site_name")
+	assert.Contains(t, string(content), "# Team settings")
+
+	loaded, err := config.LoadProject(projectDir)
+	require.NoError(t, err)
+	assert.Equal(t, "develop", loaded.Sync.Upstream)
+	assert.Equal(t, "rebase", loaded.Sync.Strategy)
+
+	t.Run("creates the file", func(t *testing.T) {
+		projectDir := t.TempDir()
+		require.NoError(t, saveSyncConfig(projectDir, config.SyncConfig{Upstream: "main"}))
+
+		loaded, err := config.LoadProject(projectDir)
+		require.NoError(t, err)
+		assert.Equal(t, "main", loaded.Sync.Upstream)
+		assert.Empty(t, loaded.SiteName)
+	})
+}
+
 func TestSyncCommand_DoesNotStashWhenRemoteMissing(t *testing.T) {
 	ensureSyncTestFlags(t)
 
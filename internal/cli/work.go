@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold"
 	"github.com/naoray/anvil/internal/ui"
 )
 
@@ -23,7 +25,16 @@ Arguments:
   PATH    Optional custom path (defaults to sanitised branch name)
 
 If no branch is provided, interactive mode allows selection from
-available branches or entering a new branch name.`,
+available branches or entering a new branch name.
+
+--only, --skip and --tag run a subset of the scaffold steps (see
+'anvil scaffold --help'); --list-steps lists the steps without creating
+a worktree. --var KEY=VALUE sets a project variable or answers the prompt
+step that stores KEY.
+
+--profile selects a scaffold profile (see profiles: in anvil.yaml) instead
+of the project's default profile, e.g. --profile minimal for a throwaway
+worktree.`,
 	Args: cobra.RangeArgs(0, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pc, err := OpenProjectFromCWD()
@@ -35,6 +46,42 @@ available branches or entering a new branch name.`,
 		dryRun := mustGetBool(cmd, "dry-run")
 		verbose := mustGetBool(cmd, "verbose")
 		quiet := mustGetBool(cmd, "quiet")
+		selection := stepSelectionFromFlags(cmd)
+		vars, err := varsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		applyProfileFlag(cmd, pc.Config)
+
+		// Check the selection before creating the worktree. The worktree
+		// does not exist yet, so the preset is detected from the default
+		// branch worktree.
+		listSteps := mustGetBool(cmd, "list-steps")
+		if listSteps || !selection.IsZero() {
+			stepsList, err := pc.ScaffoldManager().GetStepsForWorktree(pc.Config, pc.DefaultBranchWorktreePath(), pc.DefaultBranch)
+			if err != nil {
+				return fmt.Errorf("getting scaffold steps: %w", err)
+			}
+			infos, err := scaffold.DescribeSteps(stepsList, selection)
+			if err != nil {
+				return err
+			}
+			if listSteps {
+				return printStepList(cmd.OutOrStdout(), infos)
+			}
+		}
+
+		// The new worktree has no saved variables yet, so required
+		// variables must be passed now
+		skipScaffold := mustGetBool(cmd, "skip-scaffold")
+		if !skipScaffold {
+			if err := scaffold.CheckProfile(pc.Config); err != nil {
+				return err
+			}
+			if err := scaffold.CheckVars(pc.Config.Vars, vars); err != nil {
+				return err
+			}
+		}
 
 		var branch string
 		if len(args) > 0 {
@@ -116,7 +163,6 @@ available branches or entering a new branch name.`,
 			}
 		}
 
-		skipScaffold := mustGetBool(cmd, "skip-scaffold")
 		if skipScaffold {
 			if !quiet {
 				ui.PrintInfo("Scaffold skipped (run 'anvil scaffold' to set up later)")
@@ -130,6 +176,9 @@ available branches or entering a new branch name.`,
 			if verbose && preset != "" {
 				ui.PrintInfo(fmt.Sprintf("Running scaffold for preset: %s", preset))
 			}
+			if verbose && pc.Config.Profile != "" {
+				ui.PrintInfo(fmt.Sprintf("Using scaffold profile: %s", pc.Config.Profile))
+			}
 
 			repoName := filepath.Base(filepath.Dir(absWorktreePath))
 			folderName := filepath.Base(absWorktreePath)
@@ -141,8 +190,21 @@ available branches or entering a new branch name.`,
 				siteName = pc.Config.SiteName
 			}
 
-			if err := pc.ScaffoldManager().RunScaffold(absWorktreePath, branch, repoName, siteName, preset, pc.Config, false, verbose, quiet); err != nil {
+			ctx, stop := interruptContext(cmd.Context())
+			runOpts := scaffold.RunOptions{
+				Verbose:     verbose,
+				Quiet:       quiet,
+				Selection:   selection,
+				Interactive: ui.ShouldPrompt(cmd, false),
+				Vars:        vars,
+			}
+			err := pc.ScaffoldManager().RunScaffoldWithOptions(ctx, absWorktreePath, branch, repoName, siteName, preset, pc.Config, runOpts)
+			stop()
+			if err != nil {
 				ui.PrintErrorWithHint("Scaffold steps failed", err.Error())
+				if errors.Is(err, scaffold.ErrInterrupted) {
+					return err
+				}
 			}
 
 			// Check if .anvil.local should be gitignored
@@ -177,4 +239,11 @@ func init() {
 	workCmd.Flags().StringP("base", "b", "", "Base branch for new worktree")
 	workCmd.Flags().Bool("no-track", false, "Skip setting up remote tracking for new branches")
 	workCmd.Flags().Bool("skip-scaffold", false, "Skip scaffold steps (run 'anvil scaffold' later)")
+	addStepSelectionFlags(workCmd)
+	addVarFlag(workCmd)
+	addProfileFlag(workCmd)
+	workCmd.Flags().Bool("list-steps", false, "List the scaffold steps and their ids without creating a worktree")
+	workCmd.MarkFlagsMutuallyExclusive("skip-scaffold", "only")
+	workCmd.MarkFlagsMutuallyExclusive("skip-scaffold", "tag")
+	workCmd.MarkFlagsMutuallyExclusive("skip-scaffold", "profile")
 }
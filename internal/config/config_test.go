@@ -189,6 +189,218 @@ scaffold:
 	assert.False(t, *step.Enabled)
 }
 
+func TestStepConfig_Unmarshal_DependsOn(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `scaffold:
+  steps:
+    - name: php.composer
+      id: composer
+      depends_on: []
+    - name: php.laravel
+      id: migrate
+      depends_on: [composer]
+    - name: herd
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	require.Len(t, cfg.Scaffold.Steps, 3)
+
+	assert.Equal(t, "composer", cfg.Scaffold.Steps[0].ID)
+	assert.NotNil(t, cfg.Scaffold.Steps[0].DependsOn, "an empty depends_on list must be distinguishable from a missing one")
+	assert.Empty(t, cfg.Scaffold.Steps[0].DependsOn)
+	assert.Equal(t, []string{"composer"}, cfg.Scaffold.Steps[1].DependsOn)
+	assert.Empty(t, cfg.Scaffold.Steps[2].ID)
+	assert.Nil(t, cfg.Scaffold.Steps[2].DependsOn)
+}
+
+func TestStepConfig_Unmarshal_Retry(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `scaffold:
+  steps:
+    - name: php.composer
+      args: ["install"]
+      retries: 3
+      retry_delay: 5s
+      retry_on:
+        exit_codes: [1, 255]
+        output: "(?i)connection reset"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	require.Len(t, cfg.Scaffold.Steps, 1)
+
+	step := cfg.Scaffold.Steps[0]
+	assert.Equal(t, 3, step.Retries)
+	assert.Equal(t, "5s", step.RetryDelay)
+	require.NotNil(t, step.RetryOn)
+	assert.Equal(t, []int{1, 255}, step.RetryOn.ExitCodes)
+	assert.Equal(t, "(?i)connection reset", step.RetryOn.Output)
+}
+
+func TestStepConfig_Unmarshal_CommandOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `scaffold:
+  steps:
+    - name: bash.run
+      command: make build
+      cwd: frontend
+      shell: zsh
+      env:
+        APP_ENV: testing
+        NodeOptions: "--max-old-space-size=4096"
+    - name: node.npm
+      args: ["install"]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	require.Len(t, cfg.Scaffold.Steps, 2)
+
+	step := cfg.Scaffold.Steps[0]
+	assert.Equal(t, "frontend", step.Cwd)
+	assert.Equal(t, "zsh", step.Shell)
+	assert.Equal(t, map[string]string{"APP_ENV": "testing", "NodeOptions": "--max-old-space-size=4096"}, step.Env, "env names keep their case")
+	assert.Empty(t, cfg.Scaffold.Steps[1].Env)
+}
+
+func TestStepConfig_Unmarshal_Prompt(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `scaffold:
+  steps:
+    - name: prompt.select
+      message: Seed dataset
+      options: [none, demo]
+      default: demo
+      store_as: seed
+    - name: prompt.confirm
+      message: Start the worker?
+      default: true
+      store_as: worker
+    - name: prompt.input
+      message: API token
+      store_as: api_token
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	require.Len(t, cfg.Scaffold.Steps, 3)
+
+	step := cfg.Scaffold.Steps[0]
+	assert.Equal(t, "Seed dataset", step.Message)
+	assert.Equal(t, []string{"none", "demo"}, step.Options)
+	require.NotNil(t, step.Default)
+	assert.Equal(t, "demo", *step.Default)
+
+	require.NotNil(t, cfg.Scaffold.Steps[1].Default)
+	confirmed, err := ParseConfirmAnswer(*cfg.Scaffold.Steps[1].Default)
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+
+	assert.Nil(t, cfg.Scaffold.Steps[2].Default, "prompts without a default have none")
+}
+
+func TestConfig_Unmarshal_Vars(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `vars:
+  TenantName:
+    description: Tenant to create
+    required: true
+  seed:
+    default: "{{ .SiteName }}_demo"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]VarConfig{
+		"TenantName": {Description: "Tenant to create", Required: true},
+		"seed":       {Default: "{{ .SiteName }}_demo"},
+	}, cfg.Vars, "variable names keep their case")
+}
+
+func TestConfig_Unmarshal_Profiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `profile: minimal
+profiles:
+  minimal:
+    description: Throwaway worktrees
+    skip: [npm-build, herd]
+  CI:
+    tags: [ci]
+    steps:
+      - name: bash.run
+        command: make test
+        env:
+          AppEnv: testing
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	assert.Equal(t, "minimal", cfg.Profile)
+	require.Len(t, cfg.Profiles, 2)
+	assert.Equal(t, ProfileConfig{Description: "Throwaway worktrees", Skip: []string{"npm-build", "herd"}}, cfg.Profiles["minimal"])
+
+	ci, ok := cfg.Profiles["CI"]
+	require.True(t, ok, "profile names keep their case")
+	assert.Equal(t, []string{"ci"}, ci.Tags)
+	require.Len(t, ci.Steps, 1)
+	assert.Equal(t, map[string]string{"AppEnv": "testing"}, ci.Steps[0].Env, "env names of profile steps keep their case")
+}
+
+func TestConfig_Unmarshal_StepPatches(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	configContent := `scaffold:
+  disable: [npm-build]
+  steps:
+    - name: php.laravel
+      replace: migrate
+      args: ["migrate"]
+    - name: bash.run
+      after: composer-install
+      command: make
+cleanup:
+  disable: [db-destroy]
+  steps:
+    - name: bash.run
+      before: herd-unlink
+      condition:
+        command: make stop
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "anvil.yaml"), []byte(configContent), 0644))
+
+	cfg, err := LoadProject(tmpDir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"npm-build"}, cfg.Scaffold.Disable)
+	require.Len(t, cfg.Scaffold.Steps, 2)
+	assert.Equal(t, StepPatch{Replace: "migrate"}, cfg.Scaffold.Steps[0].StepPatch)
+	assert.Equal(t, StepPatch{After: "composer-install"}, cfg.Scaffold.Steps[1].StepPatch)
+	assert.Equal(t, []string{"db-destroy"}, cfg.Cleanup.Disable)
+	require.Len(t, cfg.Cleanup.Steps, 1)
+	assert.Equal(t, StepPatch{Before: "herd-unlink"}, cfg.Cleanup.Steps[0].StepPatch)
+	assert.Equal(t, "make stop", cfg.Cleanup.Steps[0].GetConditionString("command"))
+}
+
 func loadGlobalFromTestDir(testDir string) (*GlobalConfig, error) {
 	v := viper.New()
 
@@ -121,6 +121,76 @@ custom_field: custom_value
 	})
 }
 
+func TestSaveProjectSteps(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "anvil.yaml")
+	initial := `# Team setup
+preset: laravel # keep in sync with the link
+scaffold:
+  timeout: 20m
+  disable: [herd-link]
+  steps:
+    - name: bash.run
+      command: make assets
+cleanup:
+  disable: [db-destroy]
+`
+	if err := os.WriteFile(configPath, []byte(initial), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	steps := []StepConfig{
+		{Name: "php.composer", ID: "composer-install", DependsOn: []string{}, Args: []string{"install"}, ConditionHolder: ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
+		{Name: "bash.run", Command: "make assets"},
+	}
+	cleanup := []CleanupStep{{Name: "herd", ID: "herd-unlink"}}
+	if err := SaveProjectSteps(tmpDir, steps, cleanup); err != nil {
+		t.Fatalf("SaveProjectSteps failed: %v", err)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	want := `# Team setup
+preset: laravel # keep in sync with the link
+scaffold:
+  timeout: 20m
+  steps:
+    - name: php.composer
+      id: composer-install
+      depends_on: []
+      args: [install]
+      condition:
+        file_exists: composer.lock
+    - name: bash.run
+      command: make assets
+  override: true
+cleanup:
+  override: true
+  steps:
+    - name: herd
+      id: herd-unlink
+`
+	if string(content) != want {
+		t.Errorf("unexpected config:\n%s\nwant:\n%s", content, want)
+	}
+
+	loaded, err := LoadProject(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to load project: %v", err)
+	}
+	if !loaded.Scaffold.Override || !loaded.Cleanup.Override {
+		t.Error("expected scaffold.override and cleanup.override to be set")
+	}
+	if len(loaded.Scaffold.Steps) != 2 || loaded.Scaffold.Steps[0].DependsOn == nil {
+		t.Errorf("expected the steps to round-trip with an empty depends_on, got %+v", loaded.Scaffold.Steps)
+	}
+	if len(loaded.Scaffold.Disable) != 0 || len(loaded.Cleanup.Disable) != 0 {
+		t.Error("expected the disable lists to be removed")
+	}
+}
+
 func TestGlobalConfigNewFields(t *testing.T) {
 	t.Run("round-trip SetupComplete and DefaultProjectsRoot", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -150,6 +220,24 @@ func TestGlobalConfigNewFields(t *testing.T) {
 		}
 	})
 
+	t.Run("round-trip database defaults", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+		want := DatabaseDefaults{Host: "db.internal", Port: "3307", Username: "anvil", Password: "secret"}
+		if err := SaveGlobalConfig(&GlobalConfig{DetectedTools: map[string]bool{}, Database: want}); err != nil {
+			t.Fatalf("SaveGlobalConfig failed: %v", err)
+		}
+
+		loaded, err := LoadOrCreateGlobalConfig()
+		if err != nil {
+			t.Fatalf("LoadOrCreateGlobalConfig failed: %v", err)
+		}
+		if loaded.Database != want {
+			t.Errorf("expected database defaults %+v, got %+v", want, loaded.Database)
+		}
+	})
+
 	t.Run("SetupComplete defaults to false when not set", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		t.Setenv("XDG_CONFIG_HOME", tmpDir)
@@ -178,6 +266,7 @@ func TestSaveGlobalConfig_PreservesProjectNamesWithDots(t *testing.T) {
 				DefaultBranch: "main",
 				Preset:        "laravel",
 				SiteName:      "virovet-diagnostik.de",
+				Profile:       "minimal",
 			},
 		},
 	}
@@ -211,6 +300,9 @@ func TestSaveGlobalConfig_PreservesProjectNamesWithDots(t *testing.T) {
 	if project.Path != "/Users/test/Workspace/virovet-diagnostik.de" {
 		t.Errorf("expected project path to round-trip, got %q", project.Path)
 	}
+	if project.Profile != "minimal" {
+		t.Errorf("expected project profile to round-trip, got %q", project.Profile)
+	}
 }
 
 func TestLoadGlobalConfig_RecoversNestedDottedProjectNames(t *testing.T) {
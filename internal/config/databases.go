@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DatabasesFile is the file in the global config directory that records
+// the databases anvil created, so cleanup only drops databases of its own.
+const DatabasesFile = "databases.yaml"
+
+// DatabaseRecord is a database created by anvil for a worktree.
+type DatabaseRecord struct {
+	Engine   DatabaseEngine `yaml:"engine"`
+	Host     string         `yaml:"host"` // host:port of the server
+	Name     string         `yaml:"name"`
+	Project  string         `yaml:"project,omitempty"`
+	Worktree string         `yaml:"worktree"`
+	Created  time.Time      `yaml:"created"`
+}
+
+// OwnedBy reports whether the database belongs to the worktree at path.
+func (r DatabaseRecord) OwnedBy(path string) bool {
+	return samePath(r.Worktree, path)
+}
+
+// OnServer reports whether the database is on the server of engine at
+// address (host:port).
+func (r DatabaseRecord) OnServer(engine DatabaseEngine, address string) bool {
+	return r.Engine == engine && NormalizeDatabaseHost(r.Engine, r.Host) == NormalizeDatabaseHost(engine, address)
+}
+
+// sameDatabase reports whether two records are about the same database.
+func (r DatabaseRecord) sameDatabase(other DatabaseRecord) bool {
+	return r.OnServer(other.Engine, other.Host) && r.Name == other.Name
+}
+
+// NormalizeDatabaseHost returns the form of a server address the registry
+// records and compares, so one server is recognized however it is written:
+// host names are lowercased, localhost and loopback addresses become
+// 127.0.0.1, and an address without a port gets the default port of engine.
+func NormalizeDatabaseHost(engine DatabaseEngine, address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = strings.Trim(address, "[]"), ""
+	}
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); host == "" || host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		host = "127.0.0.1"
+	}
+	if port == "" {
+		switch engine {
+		case DBEngineMySQL:
+			port = "3306"
+		case DBEnginePgSQL:
+			port = "5432"
+		default:
+			return host
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+type databaseRegistry struct {
+	Databases []DatabaseRecord `yaml:"databases"`
+}
+
+// databasesMu serializes read-modify-write cycles of the registry within
+// the process, like localStateMu; a lock file serializes them across
+// processes.
+var databasesMu sync.Mutex
+
+// How long to wait for the registry lock, and when a lock left behind by a
+// killed process is taken over.
+const (
+	registryLockTimeout = 15 * time.Second
+	registryLockStale   = 10 * time.Second
+)
+
+// ReadDatabaseRegistry returns the databases anvil created, oldest first.
+func ReadDatabaseRegistry() ([]DatabaseRecord, error) {
+	path, err := databasesPath()
+	if err != nil {
+		return nil, err
+	}
+	return readDatabaseRegistry(path)
+}
+
+// RegisterDatabase records a database anvil created, replacing an earlier
+// record of the same database.
+func RegisterDatabase(record DatabaseRecord) error {
+	if record.Created.IsZero() {
+		record.Created = time.Now()
+	}
+	record.Host = NormalizeDatabaseHost(record.Engine, record.Host)
+	if path, err := normalizeProjectPath(record.Worktree); err == nil {
+		record.Worktree = path
+	}
+	return updateDatabaseRegistry(func(records []DatabaseRecord) []DatabaseRecord {
+		records = removeDatabaseRecords(records, record)
+		return append(records, record)
+	})
+}
+
+// UnregisterDatabases removes the records of dropped databases.
+func UnregisterDatabases(dropped ...DatabaseRecord) error {
+	if len(dropped) == 0 {
+		return nil
+	}
+	return updateDatabaseRegistry(func(records []DatabaseRecord) []DatabaseRecord {
+		return removeDatabaseRecords(records, dropped...)
+	})
+}
+
+func removeDatabaseRecords(records []DatabaseRecord, remove ...DatabaseRecord) []DatabaseRecord {
+	kept := records[:0]
+	for _, record := range records {
+		removed := false
+		for _, r := range remove {
+			if record.sameDatabase(r) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+func databasesPath() (string, error) {
+	dir, err := GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, DatabasesFile), nil
+}
+
+func readDatabaseRegistry(path string) ([]DatabaseRecord, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading database registry: %w", err)
+	}
+	var registry databaseRegistry
+	if err := yaml.Unmarshal(content, &registry); err != nil {
+		return nil, fmt.Errorf("parsing database registry %s: %w", path, err)
+	}
+	return registry.Databases, nil
+}
+
+// updateDatabaseRegistry replaces the records of the registry with the
+// result of update while holding the registry lock.
+func updateDatabaseRegistry(update func(records []DatabaseRecord) []DatabaseRecord) error {
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+
+	path, err := databasesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	unlock, err := lockRegistry(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := readDatabaseRegistry(path)
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(databaseRegistry{Databases: update(records)})
+	if err != nil {
+		return fmt.Errorf("marshaling database registry: %w", err)
+	}
+
+	// Write through a temporary file so readers never see a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("writing database registry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp) // best-effort cleanup
+		return fmt.Errorf("writing database registry: %w", err)
+	}
+	return nil
+}
+
+// lockRegistry creates the lock file at path, waiting while another
+// process holds it, and returns the function that removes it.
+func lockRegistry(path string) (func(), error) {
+	deadline := time.Now().Add(registryLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("locking database registry: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > registryLockStale {
+			_ = os.Remove(path) // left behind by a killed process
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("locking database registry: %s is held by another anvil process", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
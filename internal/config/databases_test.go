@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseRegistry(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	worktree := t.TempDir()
+
+	records, err := ReadDatabaseRegistry()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	app := DatabaseRecord{Engine: DBEngineMySQL, Host: "127.0.0.1:3306", Name: "app_swift_runner", Project: "app", Worktree: worktree}
+	require.NoError(t, RegisterDatabase(app))
+	require.NoError(t, RegisterDatabase(DatabaseRecord{Engine: DBEnginePgSQL, Host: "127.0.0.1:5432", Name: "app_swift_runner", Worktree: worktree}))
+	// Registering a database again replaces its record
+	app.Project = "renamed"
+	require.NoError(t, RegisterDatabase(app))
+
+	records, err = ReadDatabaseRegistry()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, DBEnginePgSQL, records[0].Engine)
+	assert.Equal(t, "renamed", records[1].Project)
+	assert.False(t, records[1].Created.IsZero())
+	assert.True(t, records[1].OwnedBy(worktree))
+	assert.True(t, records[1].OwnedBy(worktree+string(filepath.Separator)))
+	assert.False(t, records[1].OwnedBy(t.TempDir()))
+
+	require.NoError(t, UnregisterDatabases(DatabaseRecord{Engine: DBEngineMySQL, Host: "127.0.0.1:3306", Name: "app_swift_runner"}))
+	records, err = ReadDatabaseRegistry()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, DBEnginePgSQL, records[0].Engine)
+}
+
+func TestDatabaseRegistry_Concurrent(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, RegisterDatabase(DatabaseRecord{Engine: DBEngineMySQL, Host: "127.0.0.1:3306", Name: name}))
+		}()
+	}
+	wg.Wait()
+
+	records, err := ReadDatabaseRegistry()
+	require.NoError(t, err)
+	assert.Len(t, records, 6)
+}
+
+func TestDatabaseRegistry_StaleLock(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	lock := filepath.Join(configHome, "anvil", DatabasesFile+".lock")
+	require.NoError(t, os.MkdirAll(filepath.Dir(lock), 0755))
+	require.NoError(t, os.WriteFile(lock, nil, 0600))
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(lock, old, old))
+
+	require.NoError(t, RegisterDatabase(DatabaseRecord{Engine: DBEngineMySQL, Host: "127.0.0.1:3306", Name: "app"}))
+	assert.NoFileExists(t, lock)
+}
+
+func TestNormalizeDatabaseHost(t *testing.T) {
+	tests := []struct {
+		engine  DatabaseEngine
+		address string
+		want    string
+	}{
+		{DBEngineMySQL, "127.0.0.1:3306", "127.0.0.1:3306"},
+		{DBEngineMySQL, "localhost:3306", "127.0.0.1:3306"},
+		{DBEngineMySQL, "LocalHost", "127.0.0.1:3306"},
+		{DBEngineMySQL, "[::1]:3307", "127.0.0.1:3307"},
+		{DBEnginePgSQL, "DB.internal", "db.internal:5432"},
+		{DBEnginePgSQL, "[fd00::1]:5433", "[fd00::1]:5433"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeDatabaseHost(tt.engine, tt.address), tt.address)
+	}
+
+	record := DatabaseRecord{Engine: DBEngineMySQL, Host: "localhost", Name: "app"}
+	assert.True(t, record.OnServer(DBEngineMySQL, "127.0.0.1:3306"))
+	assert.False(t, record.OnServer(DBEnginePgSQL, "127.0.0.1:3306"))
+	assert.False(t, record.OnServer(DBEngineMySQL, "127.0.0.1:3307"))
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SaveProjectSteps writes steps and cleanup steps into the anvil.yaml in
+// path as scaffold.steps and cleanup.steps with override set, so they run
+// instead of the steps of the preset. The disable lists, which only apply
+// to preset steps, are removed. Like SaveProject it preserves the rest of
+// the file, including comments.
+func SaveProjectSteps(path string, steps []StepConfig, cleanup []CleanupStep) error {
+	configPath := filepath.Join(path, ProjectConfigFile)
+
+	doc, root, err := readProjectDocument(configPath)
+	if err != nil {
+		return err
+	}
+	stepsNode, err := EncodeSteps(steps)
+	if err != nil {
+		return err
+	}
+	cleanupNode, err := EncodeCleanupSteps(cleanup)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		name  string
+		steps *yaml.Node
+	}{{"scaffold", stepsNode}, {"cleanup", cleanupNode}} {
+		node := mappingSection(root, section.name)
+		setMappingValue(node, "override", interfaceToNode(true))
+		setMappingValue(node, "steps", section.steps)
+		deleteMappingKey(node, "disable")
+	}
+
+	return writeProjectDocument(configPath, doc)
+}
+
+// EncodeSteps encodes scaffold steps as a YAML sequence. Unlike yaml.Marshal
+// it keeps an empty depends_on, which lets a step start right away instead
+// of waiting for the steps before it, and lists the name, id and
+// dependencies of a step first and its condition last.
+func EncodeSteps(steps []StepConfig) (*yaml.Node, error) {
+	node, err := encodeSequence(steps, len(steps))
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range node.Content {
+		if steps[i].DependsOn != nil && len(steps[i].DependsOn) == 0 {
+			item.Content = append(item.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "depends_on"},
+				&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle})
+		}
+		orderStepKeys(item)
+	}
+	return node, nil
+}
+
+// EncodeCleanupSteps encodes cleanup steps as a YAML sequence, see
+// EncodeSteps.
+func EncodeCleanupSteps(steps []CleanupStep) (*yaml.Node, error) {
+	node, err := encodeSequence(steps, len(steps))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range node.Content {
+		orderStepKeys(item)
+	}
+	return node, nil
+}
+
+// encodeSequence encodes a slice of n items, which is an empty sequence
+// rather than null if n is 0.
+func encodeSequence(items any, n int) (*yaml.Node, error) {
+	if n == 0 {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(items); err != nil {
+		return nil, fmt.Errorf("encoding steps: %w", err)
+	}
+	return node, nil
+}
+
+// stepKeyRanks orders the keys of an encoded step; other keys rank between
+// depends_on and condition and keep their order.
+var stepKeyRanks = map[string]int{"name": 0, "id": 1, "depends_on": 2, "condition": 4}
+
+// orderStepKeys reorders the keys of an encoded step by stepKeyRanks and
+// writes lists of scalars such as args on a single line.
+func orderStepKeys(step *yaml.Node) {
+	if step.Kind != yaml.MappingNode {
+		return
+	}
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(step.Content)/2)
+	for i := 0; i+1 < len(step.Content); i += 2 {
+		pairs = append(pairs, pair{step.Content[i], step.Content[i+1]})
+		if value := step.Content[i+1]; value.Kind == yaml.SequenceNode && scalarsOnly(value) {
+			value.Style |= yaml.FlowStyle
+		}
+	}
+	rank := func(p pair) int {
+		if r, ok := stepKeyRanks[p.key.Value]; ok {
+			return r
+		}
+		return 3
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return rank(pairs[i]) < rank(pairs[j]) })
+
+	step.Content = step.Content[:0]
+	for _, p := range pairs {
+		step.Content = append(step.Content, p.key, p.value)
+	}
+}
+
+// scalarsOnly reports whether a sequence holds only scalars.
+func scalarsOnly(node *yaml.Node) bool {
+	for _, child := range node.Content {
+		if child.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// mappingSection returns the mapping under key in root, replacing a value
+// that is not a mapping and adding the key if it is missing.
+func mappingSection(root *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			if root.Content[i+1].Kind != yaml.MappingNode {
+				root.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			return root.Content[i+1]
+		}
+	}
+	section := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, section)
+	return section
+}
+
+// setMappingValue sets key in a mapping node, keeping its position and the
+// comments of the key if it exists.
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// deleteMappingKey removes key and its value from a mapping node.
+func deleteMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -4,15 +4,71 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // LocalState represents worktree-local state that should never be committed
 type LocalState struct {
-	DbSuffix string `yaml:"db_suffix"`
+	DbSuffix        string            `yaml:"db_suffix"`
+	Ports           map[string]int    `yaml:"ports,omitempty"`   // Ports allocated with {{ .Port.<name> }}
+	Vars            map[string]string `yaml:"vars,omitempty"`    // Values of the project variables the worktree was scaffolded with
+	Profile         *string           `yaml:"profile,omitempty"` // Profile the worktree was last scaffolded with, "" for none
+	ScaffoldJournal *ScaffoldJournal  `yaml:"scaffold_journal,omitempty"`
 }
 
+// Scaffold journal statuses.
+const (
+	JournalRunning     = "running"
+	JournalSucceeded   = "succeeded"
+	JournalFailed      = "failed"
+	JournalInterrupted = "interrupted"
+	JournalSkipped     = "skipped"
+)
+
+// ScaffoldJournal records the outcome of the last scaffold run of a worktree
+// so an interrupted or failed run can be resumed.
+type ScaffoldJournal struct {
+	Status     string            `yaml:"status"`
+	RunID      string            `yaml:"run_id,omitempty"` // Name of the run's log directory
+	StartedAt  time.Time         `yaml:"started_at"`
+	FinishedAt *time.Time        `yaml:"finished_at,omitempty"`
+	Vars       map[string]string `yaml:"vars,omitempty"`
+	Steps      []JournalEntry    `yaml:"steps,omitempty"`
+}
+
+// JournalEntry is the recorded outcome of a single scaffold step.
+type JournalEntry struct {
+	Key         string    `yaml:"key"`
+	Name        string    `yaml:"name"`
+	Fingerprint string    `yaml:"fingerprint,omitempty"`
+	Status      string    `yaml:"status"`
+	DurationMs  int64     `yaml:"duration_ms"`
+	Attempts    int       `yaml:"attempts,omitempty"`
+	Log         string    `yaml:"log,omitempty"` // Log file, relative to the worktree
+	Error       string    `yaml:"error,omitempty"`
+	FinishedAt  time.Time `yaml:"finished_at"`
+}
+
+// Entry returns the journal entry for the given step key.
+func (j *ScaffoldJournal) Entry(key string) (JournalEntry, bool) {
+	if j == nil {
+		return JournalEntry{}, false
+	}
+	for _, entry := range j.Steps {
+		if entry.Key == key {
+			return entry, true
+		}
+	}
+	return JournalEntry{}, false
+}
+
+// localStateMu serializes read-modify-write cycles of .anvil.local, which
+// may be written by several scaffold steps running concurrently.
+var localStateMu sync.Mutex
+
 // ReadLocalState reads worktree-local state from .anvil.local
 func ReadLocalState(worktreePath string) (*LocalState, error) {
 	configPath := filepath.Join(worktreePath, LocalStateFile)
@@ -36,6 +92,9 @@ func ReadLocalState(worktreePath string) (*LocalState, error) {
 
 // WriteLocalState writes worktree-local state to .anvil.local
 func WriteLocalState(worktreePath string, data LocalState) error {
+	localStateMu.Lock()
+	defer localStateMu.Unlock()
+
 	configPath := filepath.Join(worktreePath, LocalStateFile)
 
 	// Read existing state if it exists
@@ -54,6 +113,32 @@ func WriteLocalState(worktreePath string, data LocalState) error {
 	if data.DbSuffix != "" {
 		existing["db_suffix"] = data.DbSuffix
 	}
+	if len(data.Ports) > 0 {
+		ports, _ := existing["ports"].(map[string]any)
+		if ports == nil {
+			ports = make(map[string]any)
+		}
+		for name, port := range data.Ports {
+			ports[name] = port
+		}
+		existing["ports"] = ports
+	}
+	if len(data.Vars) > 0 {
+		vars, _ := existing["vars"].(map[string]any)
+		if vars == nil {
+			vars = make(map[string]any)
+		}
+		for name, value := range data.Vars {
+			vars[name] = value
+		}
+		existing["vars"] = vars
+	}
+	if data.Profile != nil {
+		existing["profile"] = *data.Profile
+	}
+	if data.ScaffoldJournal != nil {
+		existing["scaffold_journal"] = data.ScaffoldJournal
+	}
 
 	// Marshal and write
 	content, err := yaml.Marshal(existing)
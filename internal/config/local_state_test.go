@@ -164,3 +164,128 @@ func TestWriteLocalState_EmptyDbSuffix(t *testing.T) {
 		t.Errorf("expected db_suffix 'original' to be preserved, got: %v", data["db_suffix"])
 	}
 }
+
+func TestWriteLocalState_ScaffoldJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := WriteLocalState(tmpDir, LocalState{DbSuffix: "sunset"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	journal := &ScaffoldJournal{
+		Status: JournalFailed,
+		Vars:   map[string]string{"AppKey": "base64:abc"},
+		Steps: []JournalEntry{
+			{Key: "composer-install", Name: "php.composer", Fingerprint: "abc123", Status: JournalSucceeded, DurationMs: 1200},
+			{Key: "migrate", Name: "php.laravel", Status: JournalFailed, Error: "exit status 1"},
+		},
+	}
+	if err := WriteLocalState(tmpDir, LocalState{ScaffoldJournal: journal}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state, err := ReadLocalState(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if state.DbSuffix != "sunset" {
+		t.Errorf("expected db_suffix 'sunset' to be preserved, got: %s", state.DbSuffix)
+	}
+	if state.ScaffoldJournal == nil {
+		t.Fatal("expected scaffold journal to be read back")
+	}
+	if state.ScaffoldJournal.Status != JournalFailed {
+		t.Errorf("expected status %q, got: %q", JournalFailed, state.ScaffoldJournal.Status)
+	}
+	if state.ScaffoldJournal.Vars["AppKey"] != "base64:abc" {
+		t.Errorf("expected AppKey var, got: %v", state.ScaffoldJournal.Vars)
+	}
+
+	entry, ok := state.ScaffoldJournal.Entry("composer-install")
+	if !ok {
+		t.Fatal("expected composer-install entry")
+	}
+	if entry.Fingerprint != "abc123" || entry.DurationMs != 1200 || entry.Status != JournalSucceeded {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if _, ok := state.ScaffoldJournal.Entry("missing"); ok {
+		t.Error("expected no entry for unknown key")
+	}
+}
+
+func TestWriteLocalState_MergesPorts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := WriteLocalState(tmpDir, LocalState{DbSuffix: "sunset", Ports: map[string]int{"app": 20001}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WriteLocalState(tmpDir, LocalState{Ports: map[string]int{"vite": 20002}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state, err := ReadLocalState(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.DbSuffix != "sunset" {
+		t.Errorf("expected DbSuffix to be preserved, got: %s", state.DbSuffix)
+	}
+	if state.Ports["app"] != 20001 || state.Ports["vite"] != 20002 {
+		t.Errorf("expected both ports, got: %v", state.Ports)
+	}
+}
+
+func TestScaffoldJournal_EntryNil(t *testing.T) {
+	var journal *ScaffoldJournal
+
+	if _, ok := journal.Entry("anything"); ok {
+		t.Error("expected nil journal to have no entries")
+	}
+}
+
+func TestWriteLocalState_MergesVars(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := WriteLocalState(tmpDir, LocalState{DbSuffix: "sunset", Vars: map[string]string{"seed": "demo", "Tenant": "acme"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WriteLocalState(tmpDir, LocalState{Vars: map[string]string{"seed": "full"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state, err := ReadLocalState(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Vars["seed"] != "full" || state.Vars["Tenant"] != "acme" {
+		t.Errorf("expected merged vars, got: %v", state.Vars)
+	}
+}
+
+func TestWriteLocalState_Profile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	state, err := ReadLocalState(tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Profile != nil {
+		t.Errorf("expected no profile before the first run, got: %q", *state.Profile)
+	}
+
+	minimal, none := "minimal", ""
+	for _, profile := range []*string{&minimal, &none} {
+		if err := WriteLocalState(tmpDir, LocalState{Profile: profile}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		state, err := ReadLocalState(tmpDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state.Profile == nil || *state.Profile != *profile {
+			t.Errorf("expected profile %q to be recorded, got: %v", *profile, state.Profile)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSource identifies the layer a resolved configuration value came from.
+type ConfigSource string
+
+const (
+	// SourceGlobal is the linked project entry in the global config.
+	SourceGlobal ConfigSource = "global"
+	// SourceDefaultBranch is anvil.yaml in the default branch worktree.
+	SourceDefaultBranch ConfigSource = "default-branch"
+	// SourceProject is anvil.yaml in the project root.
+	SourceProject ConfigSource = "project"
+)
+
+// ConfigLayer is a single configuration source taking part in resolution.
+type ConfigLayer struct {
+	Source ConfigSource
+	Path   string // Path to the anvil.yaml file; empty for the global layer
+	Config *Config
+	keys   map[string]bool
+}
+
+// Has reports whether the layer explicitly sets the given resolvable key.
+func (l ConfigLayer) Has(key string) bool {
+	return l.keys[key]
+}
+
+// ResolvedConfig is the merged project configuration together with the
+// layer each value was taken from.
+type ResolvedConfig struct {
+	Config  *Config
+	Layers  []ConfigLayer
+	sources map[string]ConfigLayer
+}
+
+// Resolvable keys. Sections whose values are lists or condition maps are
+// resolved as a whole: the highest layer that defines them wins, so a
+// project root copy of anvil.yaml never duplicates the default branch steps.
+const (
+	keySiteName          = "site_name"
+	keyPreset            = "preset"
+	keyDefaultBranch     = "default_branch"
+	keyEditorCmd         = "editor_cmd"
+	keyProfile           = "profile"
+	keyScaffoldPreFlight = "scaffold.pre_flight"
+	keyScaffoldSteps     = "scaffold.steps"
+	keyScaffoldOverride  = "scaffold.override"
+	keyScaffoldTimeout   = "scaffold.timeout"
+	keyScaffoldDisable   = "scaffold.disable"
+	keyCleanupSteps      = "cleanup.steps"
+	keyCleanupOverride   = "cleanup.override"
+	keyCleanupDisable    = "cleanup.disable"
+	keySyncUpstream      = "sync.upstream"
+	keySyncStrategy      = "sync.strategy"
+	keySyncRemote        = "sync.remote"
+	keySyncAutoStash     = "sync.auto_stash"
+	keyToolsPrefix       = "tools."
+	keyVarsPrefix        = "vars."
+	keyProfilesPrefix    = "profiles."
+)
+
+var resolvableKeys = []string{
+	keySiteName,
+	keyPreset,
+	keyDefaultBranch,
+	keyEditorCmd,
+	keyProfile,
+	keyScaffoldPreFlight,
+	keyScaffoldSteps,
+	keyScaffoldOverride,
+	keyScaffoldTimeout,
+	keyScaffoldDisable,
+	keyCleanupSteps,
+	keyCleanupOverride,
+	keyCleanupDisable,
+	keySyncUpstream,
+	keySyncStrategy,
+	keySyncRemote,
+	keySyncAutoStash,
+}
+
+// ResolveProjectConfig merges the project configuration layers.
+// Precedence from lowest to highest:
+//  1. The linked project entry in the global config (info)
+//  2. anvil.yaml in the default branch worktree (defaultBranchPath)
+//  3. anvil.yaml in the project root (projectPath)
+//
+// Missing files are skipped. A path that is identical to a higher layer's
+// path is only loaded once.
+func ResolveProjectConfig(projectPath, defaultBranchPath string, info *ProjectInfo) (*ResolvedConfig, error) {
+	resolved := &ResolvedConfig{
+		Config:  &Config{},
+		sources: make(map[string]ConfigLayer),
+	}
+
+	if info != nil {
+		resolved.apply(globalLayer(info))
+	}
+
+	if defaultBranchPath != "" && !samePath(defaultBranchPath, projectPath) {
+		layer, err := loadProjectLayer(SourceDefaultBranch, defaultBranchPath)
+		if err != nil {
+			return nil, err
+		}
+		if layer != nil {
+			resolved.apply(*layer)
+		}
+	}
+
+	if projectPath != "" {
+		layer, err := loadProjectLayer(SourceProject, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		if layer != nil {
+			resolved.apply(*layer)
+		}
+	}
+
+	return resolved, nil
+}
+
+// Source returns the layer that provided the given key.
+// The second return value is false if no layer set the key.
+func (r *ResolvedConfig) Source(key string) (ConfigLayer, bool) {
+	layer, ok := r.sources[key]
+	return layer, ok
+}
+
+// Keys returns all keys that were set by at least one layer, sorted.
+func (r *ResolvedConfig) Keys() []string {
+	keys := make([]string, 0, len(r.sources))
+	for key := range r.sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value returns the resolved value for a key in its Go representation.
+func (r *ResolvedConfig) Value(key string) any {
+	cfg := r.Config
+	switch key {
+	case keySiteName:
+		return cfg.SiteName
+	case keyPreset:
+		return cfg.Preset
+	case keyDefaultBranch:
+		return cfg.DefaultBranch
+	case keyEditorCmd:
+		return cfg.EditorCmd
+	case keyProfile:
+		return cfg.Profile
+	case keyScaffoldPreFlight:
+		return cfg.Scaffold.PreFlight
+	case keyScaffoldSteps:
+		return cfg.Scaffold.Steps
+	case keyScaffoldOverride:
+		return cfg.Scaffold.Override
+	case keyScaffoldTimeout:
+		return cfg.Scaffold.Timeout
+	case keyScaffoldDisable:
+		return cfg.Scaffold.Disable
+	case keyCleanupSteps:
+		return cfg.Cleanup.Steps
+	case keyCleanupOverride:
+		return cfg.Cleanup.Override
+	case keyCleanupDisable:
+		return cfg.Cleanup.Disable
+	case keySyncUpstream:
+		return cfg.Sync.Upstream
+	case keySyncStrategy:
+		return cfg.Sync.Strategy
+	case keySyncRemote:
+		return cfg.Sync.Remote
+	case keySyncAutoStash:
+		return cfg.Sync.AutoStash
+	}
+	if name, ok := strings.CutPrefix(key, keyToolsPrefix); ok {
+		return cfg.Tools[name]
+	}
+	if name, ok := strings.CutPrefix(key, keyVarsPrefix); ok {
+		return cfg.Vars[name]
+	}
+	if name, ok := strings.CutPrefix(key, keyProfilesPrefix); ok {
+		return cfg.Profiles[name]
+	}
+	return nil
+}
+
+// Locate returns the anvil.yaml file and line of the value at path, e.g.
+// "scaffold.steps[2].condition.file_exists". If the path does not exist in
+// full, the line of its deepest existing parent is returned. ok is false
+// if no anvil.yaml layer set the value.
+func (r *ResolvedConfig) Locate(path string) (file string, line int, ok bool) {
+	var layer ConfigLayer
+	for key, l := range r.sources {
+		if path == key || strings.HasPrefix(path, key+".") || strings.HasPrefix(path, key+"[") {
+			layer, ok = l, true
+			break
+		}
+	}
+	if !ok || layer.Path == "" {
+		return "", 0, false
+	}
+
+	data, err := os.ReadFile(layer.Path)
+	if err != nil {
+		return "", 0, false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return "", 0, false
+	}
+
+	node := doc.Content[0]
+	line = node.Line
+	for _, segment := range splitConfigPath(path) {
+		child, childLine := childNode(node, segment)
+		if child == nil {
+			break
+		}
+		node, line = child, childLine
+	}
+	return layer.Path, line, true
+}
+
+// splitConfigPath splits "a.b[2].c" into "a", "b", "[2]" and "c".
+func splitConfigPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for {
+			i := strings.Index(part, "[")
+			if i < 0 {
+				break
+			}
+			if i > 0 {
+				segments = append(segments, part[:i])
+			}
+			end := strings.Index(part, "]")
+			if end < i {
+				break
+			}
+			segments = append(segments, part[i:end+1])
+			part = part[end+1:]
+		}
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+// childNode returns the map value or list item of node addressed by
+// segment, or nil if there is none. The line is that of the map key, or of
+// the list item.
+func childNode(node *yaml.Node, segment string) (*yaml.Node, int) {
+	if index, ok := strings.CutPrefix(segment, "["); ok {
+		i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+		if err != nil || node.Kind != yaml.SequenceNode || i < 0 || i >= len(node.Content) {
+			return nil, 0
+		}
+		return node.Content[i], node.Content[i].Line
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		// Viper lowercases keys, so match them regardless of case
+		if strings.EqualFold(node.Content[i].Value, segment) {
+			return node.Content[i+1], node.Content[i].Line
+		}
+	}
+	return nil, 0
+}
+
+func (r *ResolvedConfig) apply(layer ConfigLayer) {
+	r.Layers = append(r.Layers, layer)
+
+	dst := r.Config
+	src := layer.Config
+	set := func(key string, assign func()) {
+		if layer.Has(key) {
+			assign()
+			r.sources[key] = layer
+		}
+	}
+
+	set(keySiteName, func() { dst.SiteName = src.SiteName })
+	set(keyPreset, func() { dst.Preset = src.Preset })
+	set(keyDefaultBranch, func() { dst.DefaultBranch = src.DefaultBranch })
+	set(keyEditorCmd, func() { dst.EditorCmd = src.EditorCmd })
+	set(keyProfile, func() { dst.Profile = src.Profile })
+	set(keyScaffoldPreFlight, func() { dst.Scaffold.PreFlight = src.Scaffold.PreFlight })
+	set(keyScaffoldSteps, func() { dst.Scaffold.Steps = src.Scaffold.Steps })
+	set(keyScaffoldOverride, func() { dst.Scaffold.Override = src.Scaffold.Override })
+	set(keyScaffoldTimeout, func() { dst.Scaffold.Timeout = src.Scaffold.Timeout })
+	set(keyScaffoldDisable, func() { dst.Scaffold.Disable = src.Scaffold.Disable })
+	set(keyCleanupSteps, func() { dst.Cleanup.Steps = src.Cleanup.Steps })
+	set(keyCleanupOverride, func() { dst.Cleanup.Override = src.Cleanup.Override })
+	set(keyCleanupDisable, func() { dst.Cleanup.Disable = src.Cleanup.Disable })
+	set(keySyncUpstream, func() { dst.Sync.Upstream = src.Sync.Upstream })
+	set(keySyncStrategy, func() { dst.Sync.Strategy = src.Sync.Strategy })
+	set(keySyncRemote, func() { dst.Sync.Remote = src.Sync.Remote })
+	set(keySyncAutoStash, func() { dst.Sync.AutoStash = src.Sync.AutoStash })
+
+	for name, tool := range src.Tools {
+		set(keyToolsPrefix+name, func() {
+			if dst.Tools == nil {
+				dst.Tools = make(map[string]ToolConfig)
+			}
+			dst.Tools[name] = tool
+		})
+	}
+	for name, def := range src.Vars {
+		set(keyVarsPrefix+name, func() {
+			if dst.Vars == nil {
+				dst.Vars = make(map[string]VarConfig)
+			}
+			dst.Vars[name] = def
+		})
+	}
+	for name, profile := range src.Profiles {
+		set(keyProfilesPrefix+name, func() {
+			if dst.Profiles == nil {
+				dst.Profiles = make(map[string]ProfileConfig)
+			}
+			dst.Profiles[name] = profile
+		})
+	}
+}
+
+// globalLayer converts the linked project entry into a configuration layer.
+// Only non-empty fields count as set.
+func globalLayer(info *ProjectInfo) ConfigLayer {
+	layer := ConfigLayer{
+		Source: SourceGlobal,
+		Config: &Config{
+			SiteName:      info.SiteName,
+			Preset:        info.Preset,
+			DefaultBranch: info.DefaultBranch,
+			EditorCmd:     info.EditorCmd,
+			Profile:       info.Profile,
+		},
+		keys: make(map[string]bool),
+	}
+	for key, value := range map[string]string{
+		keySiteName:      info.SiteName,
+		keyPreset:        info.Preset,
+		keyDefaultBranch: info.DefaultBranch,
+		keyEditorCmd:     info.EditorCmd,
+		keyProfile:       info.Profile,
+	} {
+		if value != "" {
+			layer.keys[key] = true
+		}
+	}
+	return layer
+}
+
+// loadProjectLayer loads anvil.yaml from dir as a configuration layer.
+// Returns nil without error if the file does not exist.
+func loadProjectLayer(source ConfigSource, dir string) (*ConfigLayer, error) {
+	path := filepath.Join(dir, ProjectConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("checking %s: %w", path, err)
+	}
+
+	cfg, v, err := loadProjectViper(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+
+	layer := &ConfigLayer{
+		Source: source,
+		Path:   path,
+		Config: cfg,
+		keys:   make(map[string]bool),
+	}
+	for _, key := range resolvableKeys {
+		if v.IsSet(key) {
+			layer.keys[key] = true
+		}
+	}
+	for name := range cfg.Tools {
+		layer.keys[keyToolsPrefix+name] = true
+	}
+	for name := range cfg.Vars {
+		layer.keys[keyVarsPrefix+name] = true
+	}
+	for name := range cfg.Profiles {
+		layer.keys[keyProfilesPrefix+name] = true
+	}
+
+	return layer, nil
+}
+
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	na, errA := normalizeProjectPath(a)
+	nb, errB := normalizeProjectPath(b)
+	return errA == nil && errB == nil && na == nb
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProjectConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(content), 0644))
+}
+
+func TestResolveProjectConfig_GlobalOnly(t *testing.T) {
+	info := &ProjectInfo{
+		Path:          t.TempDir(),
+		DefaultBranch: "main",
+		Preset:        "laravel",
+		SiteName:      "my-site",
+		Profile:       "minimal",
+	}
+
+	resolved, err := ResolveProjectConfig(info.Path, "", info)
+
+	require.NoError(t, err)
+	assert.Equal(t, "laravel", resolved.Config.Preset)
+	assert.Equal(t, "minimal", resolved.Config.Profile)
+	assert.Equal(t, "my-site", resolved.Config.SiteName)
+	assert.Equal(t, "main", resolved.Config.DefaultBranch)
+
+	layer, ok := resolved.Source("preset")
+	require.True(t, ok)
+	assert.Equal(t, SourceGlobal, layer.Source)
+
+	_, ok = resolved.Source("editor_cmd")
+	assert.False(t, ok, "empty global values should not count as set")
+}
+
+func TestResolveProjectConfig_Precedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	mainDir := filepath.Join(tmpDir, "worktrees", "main")
+
+	writeProjectConfig(t, mainDir, `preset: laravel-shared-db
+editor_cmd: zed
+scaffold:
+  pre_flight:
+    condition:
+      command_exists: php
+  steps:
+    - name: bash.run
+      command: echo main
+cleanup:
+  steps:
+    - name: herd
+tools:
+  php:
+    version_file: .php-version
+vars:
+  seed:
+    default: demo
+  TenantName:
+    required: true
+sync:
+  upstream: develop
+  strategy: merge
+`)
+	writeProjectConfig(t, projectDir, `editor_cmd: code
+scaffold:
+  steps:
+    - name: bash.run
+      command: echo root
+  override: true
+tools:
+  node:
+    version_file: .nvmrc
+vars:
+  seed:
+    default: full
+sync:
+  strategy: rebase
+`)
+
+	info := &ProjectInfo{Path: projectDir, DefaultBranch: "main", Preset: "laravel", SiteName: "site"}
+
+	resolved, err := ResolveProjectConfig(projectDir, mainDir, info)
+	require.NoError(t, err)
+
+	cfg := resolved.Config
+	assert.Equal(t, "site", cfg.SiteName)
+	assert.Equal(t, "laravel-shared-db", cfg.Preset)
+	assert.Equal(t, "code", cfg.EditorCmd)
+	require.Len(t, cfg.Scaffold.Steps, 1)
+	assert.Equal(t, "echo root", cfg.Scaffold.Steps[0].Command)
+	assert.True(t, cfg.Scaffold.Override)
+	require.NotNil(t, cfg.Scaffold.PreFlight)
+	assert.Equal(t, "php", cfg.Scaffold.PreFlight.Condition["command_exists"])
+	require.Len(t, cfg.Cleanup.Steps, 1)
+	assert.Equal(t, "herd", cfg.Cleanup.Steps[0].Name)
+	assert.Equal(t, ".php-version", cfg.Tools["php"].VersionFile)
+	assert.Equal(t, ".nvmrc", cfg.Tools["node"].VersionFile)
+	assert.Equal(t, map[string]VarConfig{"seed": {Default: "full"}, "TenantName": {Required: true}}, cfg.Vars)
+	assert.Equal(t, "develop", cfg.Sync.Upstream)
+	assert.Equal(t, "rebase", cfg.Sync.Strategy)
+
+	tests := map[string]ConfigSource{
+		"site_name":           SourceGlobal,
+		"preset":              SourceDefaultBranch,
+		"editor_cmd":          SourceProject,
+		"scaffold.steps":      SourceProject,
+		"scaffold.override":   SourceProject,
+		"scaffold.pre_flight": SourceDefaultBranch,
+		"cleanup.steps":       SourceDefaultBranch,
+		"tools.php":           SourceDefaultBranch,
+		"tools.node":          SourceProject,
+		"vars.seed":           SourceProject,
+		"vars.TenantName":     SourceDefaultBranch,
+		"sync.upstream":       SourceDefaultBranch,
+		"sync.strategy":       SourceProject,
+	}
+	for key, want := range tests {
+		layer, ok := resolved.Source(key)
+		require.True(t, ok, "key %s should be set", key)
+		assert.Equal(t, want, layer.Source, "source of %s", key)
+	}
+
+	layer, _ := resolved.Source("editor_cmd")
+	assert.Equal(t, filepath.Join(projectDir, ProjectConfigFile), layer.Path)
+	assert.Len(t, resolved.Layers, 3)
+}
+
+func TestResolveProjectConfig_SamePathLoadedOnce(t *testing.T) {
+	projectDir := t.TempDir()
+	writeProjectConfig(t, projectDir, "preset: php\n")
+
+	resolved, err := ResolveProjectConfig(projectDir, projectDir, nil)
+
+	require.NoError(t, err)
+	assert.Len(t, resolved.Layers, 1)
+	layer, ok := resolved.Source("preset")
+	require.True(t, ok)
+	assert.Equal(t, SourceProject, layer.Source)
+}
+
+func TestResolveProjectConfig_MissingFilesSkipped(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	resolved, err := ResolveProjectConfig(filepath.Join(tmpDir, "project"), filepath.Join(tmpDir, "main"), nil)
+
+	require.NoError(t, err)
+	assert.Empty(t, resolved.Layers)
+	assert.Empty(t, resolved.Keys())
+}
+
+func TestResolveProjectConfig_InvalidFile(t *testing.T) {
+	projectDir := t.TempDir()
+	writeProjectConfig(t, projectDir, "scaffold:\n  steps: not-a-list\n")
+
+	_, err := ResolveProjectConfig(projectDir, "", nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "anvil.yaml")
+}
+
+func TestResolvedConfig_Locate(t *testing.T) {
+	projectDir := t.TempDir()
+	writeProjectConfig(t, projectDir, `preset: laravel
+scaffold:
+  steps:
+    - name: bash.run
+      command: echo one
+    - name: bash.run
+      command: echo two
+      condition:
+        any:
+          - file_exists: a
+          - file_exist: b
+`)
+
+	resolved, err := ResolveProjectConfig(projectDir, "", &ProjectInfo{Path: projectDir})
+	require.NoError(t, err)
+	path := filepath.Join(projectDir, ProjectConfigFile)
+
+	tests := []struct {
+		path string
+		line int
+	}{
+		{"scaffold.steps[1]", 6},
+		{"scaffold.steps[1].condition", 8},
+		{"scaffold.steps[1].condition.any[1].file_exist", 11},
+		{"scaffold.steps[1].condition.any[5]", 9},
+		{"preset", 1},
+	}
+	for _, tt := range tests {
+		file, line, ok := resolved.Locate(tt.path)
+		require.True(t, ok, tt.path)
+		assert.Equal(t, path, file, tt.path)
+		assert.Equal(t, tt.line, line, tt.path)
+	}
+
+	_, _, ok := resolved.Locate("cleanup.steps[0]")
+	assert.False(t, ok, "keys not set in anvil.yaml cannot be located")
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 // StepValidator is an interface for step-specific configuration validation.
@@ -51,6 +54,25 @@ func (c FileCopyConfig) Validate() error {
 	return nil
 }
 
+// FileTemplateConfig represents configuration for file.template step
+type FileTemplateConfig struct {
+	BaseStepConfig
+	From      string `mapstructure:"from"`
+	To        string `mapstructure:"to"`
+	Overwrite *bool  `mapstructure:"overwrite"`
+}
+
+// Validate checks that required fields are present for file.template step
+func (c FileTemplateConfig) Validate() error {
+	if c.From == "" {
+		return fmt.Errorf("file.template: 'from' is required")
+	}
+	if c.To == "" {
+		return fmt.Errorf("file.template: 'to' is required")
+	}
+	return nil
+}
+
 // BashRunConfig represents configuration for bash.run step
 type BashRunConfig struct {
 	BaseStepConfig
@@ -147,6 +169,21 @@ func (c DbCreateConfig) Validate() error {
 	return nil
 }
 
+// DbCloneConfig represents configuration for db.clone step
+type DbCloneConfig struct {
+	BaseStepConfig
+	Args   []string `mapstructure:"args"`
+	Type   string   `mapstructure:"type"`
+	Source string   `mapstructure:"source"`
+}
+
+// Validate checks that the db.clone step config is valid.
+// All fields are optional for db.clone; the source defaults to the
+// default branch worktree.
+func (c DbCloneConfig) Validate() error {
+	return nil
+}
+
 // DbDestroyConfig represents configuration for db.destroy step
 type DbDestroyConfig struct {
 	BaseStepConfig
@@ -160,10 +197,72 @@ func (c DbDestroyConfig) Validate() error {
 	return nil
 }
 
+// PromptConfig represents configuration for prompt.input, prompt.select and
+// prompt.confirm steps
+type PromptConfig struct {
+	BaseStepConfig
+	Message string   `mapstructure:"message"`
+	Options []string `mapstructure:"options"`
+	Default *string  `mapstructure:"default"`
+	StoreAs string   `mapstructure:"store_as"`
+	Secret  bool     `mapstructure:"secret"`
+}
+
+// Validate checks that required fields are present for prompt steps
+func (c PromptConfig) Validate() error {
+	if c.Message == "" {
+		return fmt.Errorf("%s: 'message' is required", c.Name)
+	}
+	if c.StoreAs == "" {
+		return fmt.Errorf("%s: 'store_as' is required", c.Name)
+	}
+	if c.Name != StepPromptSelect && len(c.Options) > 0 {
+		return fmt.Errorf("%s: 'options' is only supported by %s", c.Name, StepPromptSelect)
+	}
+	if c.Name != StepPromptInput && c.Secret {
+		return fmt.Errorf("%s: 'secret' is only supported by %s", c.Name, StepPromptInput)
+	}
+
+	// Defaults that are templates are checked when the step runs
+	hasDefault := c.Default != nil && !strings.Contains(*c.Default, "{{")
+	switch c.Name {
+	case StepPromptSelect:
+		if len(c.Options) == 0 {
+			return fmt.Errorf("%s: 'options' is required", c.Name)
+		}
+		if hasDefault && !slices.Contains(c.Options, *c.Default) {
+			return fmt.Errorf("%s: default %q is not one of the options", c.Name, *c.Default)
+		}
+	case StepPromptConfirm:
+		if hasDefault {
+			if _, err := ParseConfirmAnswer(*c.Default); err != nil {
+				return fmt.Errorf("%s: invalid default: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseConfirmAnswer parses the answer of a prompt.confirm step, which is
+// true/false, yes/no, y/n or 1/0 in any case.
+func ParseConfirmAnswer(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a yes/no answer", value)
+}
+
 // ValidateStepConfig validates a StepConfig based on its step type.
 // The stepName parameter is used to determine the step type for validation.
 // This is the main entry point for step validation.
 func ValidateStepConfig(stepName string, cfg StepConfig) error {
+	if err := ValidateCommandOptions(stepName, cfg); err != nil {
+		return err
+	}
+
 	base := BaseStepConfig{
 		Name:      stepName,
 		Enabled:   cfg.Enabled,
@@ -177,6 +276,13 @@ func ValidateStepConfig(stepName string, cfg StepConfig) error {
 			From:           cfg.From,
 			To:             cfg.To,
 		}.Validate()
+	case StepFileTemplate:
+		return FileTemplateConfig{
+			BaseStepConfig: base,
+			From:           cfg.From,
+			To:             cfg.To,
+			Overwrite:      cfg.Overwrite,
+		}.Validate()
 	case StepBashRun:
 		return BashRunConfig{
 			BaseStepConfig: base,
@@ -218,12 +324,28 @@ func ValidateStepConfig(stepName string, cfg StepConfig) error {
 			Args:           cfg.Args,
 			Type:           cfg.Type,
 		}.Validate()
+	case StepDbClone:
+		return DbCloneConfig{
+			BaseStepConfig: base,
+			Args:           cfg.Args,
+			Type:           cfg.Type,
+			Source:         cfg.Source,
+		}.Validate()
 	case StepDbDestroy:
 		return DbDestroyConfig{
 			BaseStepConfig: base,
 			Args:           cfg.Args,
 			Type:           cfg.Type,
 		}.Validate()
+	case StepPromptInput, StepPromptSelect, StepPromptConfirm:
+		return PromptConfig{
+			BaseStepConfig: base,
+			Message:        cfg.Message,
+			Options:        cfg.Options,
+			Default:        cfg.Default,
+			StoreAs:        cfg.StoreAs,
+			Secret:         cfg.Secret,
+		}.Validate()
 	default:
 		// Binary steps (php, npm, composer, etc.) and unknown steps
 		return BinaryStepConfig{
@@ -233,3 +355,47 @@ func ValidateStepConfig(stepName string, cfg StepConfig) error {
 		}.Validate()
 	}
 }
+
+// Shells lists the shells bash.run and command.run can run commands with.
+var Shells = []string{"bash", "sh", "zsh"}
+
+// ValidateCommandOptions checks the env, cwd and shell options, which only
+// steps that run commands support.
+func ValidateCommandOptions(stepName string, cfg StepConfig) error {
+	switch stepName {
+	case StepFileCopy, StepFileTemplate, StepEnvRead, StepEnvWrite, StepEnvCopy, StepDbCreate, StepDbClone, StepDbDestroy,
+		StepPromptInput, StepPromptSelect, StepPromptConfirm:
+		switch {
+		case len(cfg.Env) > 0:
+			return fmt.Errorf("%s: 'env' is only supported by steps that run commands", stepName)
+		case cfg.Cwd != "":
+			return fmt.Errorf("%s: 'cwd' is only supported by steps that run commands", stepName)
+		case cfg.Shell != "":
+			return fmt.Errorf("%s: 'shell' is only supported by bash.run and command.run", stepName)
+		}
+		return nil
+	}
+
+	for name := range cfg.Env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("%s: invalid environment variable name %q", stepName, name)
+		}
+	}
+	if cfg.Cwd != "" {
+		if filepath.IsAbs(cfg.Cwd) {
+			return fmt.Errorf("%s: 'cwd' must be relative to the worktree, got %q", stepName, cfg.Cwd)
+		}
+		if clean := filepath.Clean(cfg.Cwd); clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s: 'cwd' must be inside the worktree, got %q", stepName, cfg.Cwd)
+		}
+	}
+	if cfg.Shell != "" {
+		if stepName != StepBashRun && stepName != StepCommandRun {
+			return fmt.Errorf("%s: 'shell' is only supported by bash.run and command.run", stepName)
+		}
+		if !slices.Contains(Shells, cfg.Shell) {
+			return fmt.Errorf("%s: unsupported shell %q (supported: %s)", stepName, cfg.Shell, strings.Join(Shells, ", "))
+		}
+	}
+	return nil
+}
@@ -39,6 +39,24 @@ func TestValidateStepConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "file.copy: 'to' is required",
 		},
+		{
+			name:     "file.template with all required fields",
+			stepName: "file.template",
+			cfg: StepConfig{
+				From: "phpunit.xml.tmpl",
+				To:   "phpunit.xml",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "file.template missing to",
+			stepName: "file.template",
+			cfg: StepConfig{
+				From: "phpunit.xml.tmpl",
+			},
+			wantErr: true,
+			errMsg:  "file.template: 'to' is required",
+		},
 		{
 			name:     "bash.run with command",
 			stepName: "bash.run",
@@ -195,6 +213,141 @@ func TestValidateStepConfig(t *testing.T) {
 			cfg:      StepConfig{},
 			wantErr:  false,
 		},
+		{
+			name:     "bash.run with env, cwd and shell",
+			stepName: "bash.run",
+			cfg: StepConfig{
+				Command: "make build",
+				Env:     map[string]string{"APP_ENV": "testing"},
+				Cwd:     "frontend",
+				Shell:   "zsh",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "bash.run with unsupported shell",
+			stepName: "bash.run",
+			cfg: StepConfig{
+				Command: "make build",
+				Shell:   "fish",
+			},
+			wantErr: true,
+			errMsg:  `bash.run: unsupported shell "fish" (supported: bash, sh, zsh)`,
+		},
+		{
+			name:     "binary step with shell",
+			stepName: "node.npm",
+			cfg: StepConfig{
+				Args:  []string{"install"},
+				Shell: "bash",
+			},
+			wantErr: true,
+			errMsg:  "node.npm: 'shell' is only supported by bash.run and command.run",
+		},
+		{
+			name:     "binary step with cwd outside the worktree",
+			stepName: "node.npm",
+			cfg: StepConfig{
+				Args: []string{"install"},
+				Cwd:  "../other",
+			},
+			wantErr: true,
+			errMsg:  `node.npm: 'cwd' must be inside the worktree, got "../other"`,
+		},
+		{
+			name:     "command.run with absolute cwd",
+			stepName: "command.run",
+			cfg: StepConfig{
+				Command: "make",
+				Cwd:     "/tmp",
+			},
+			wantErr: true,
+			errMsg:  `command.run: 'cwd' must be relative to the worktree, got "/tmp"`,
+		},
+		{
+			name:     "command.run with invalid env name",
+			stepName: "command.run",
+			cfg: StepConfig{
+				Command: "make",
+				Env:     map[string]string{"A=B": "c"},
+			},
+			wantErr: true,
+			errMsg:  `command.run: invalid environment variable name "A=B"`,
+		},
+		{
+			name:     "file.copy with env",
+			stepName: "file.copy",
+			cfg: StepConfig{
+				From: "source.txt",
+				To:   "dest.txt",
+				Env:  map[string]string{"APP_ENV": "testing"},
+			},
+			wantErr: true,
+			errMsg:  "file.copy: 'env' is only supported by steps that run commands",
+		},
+		{
+			name:     "prompt.input with message and store_as",
+			stepName: "prompt.input",
+			cfg: StepConfig{
+				Message: "API token",
+				StoreAs: "api_token",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "prompt.input missing store_as",
+			stepName: "prompt.input",
+			cfg: StepConfig{
+				Message: "API token",
+			},
+			wantErr: true,
+			errMsg:  "prompt.input: 'store_as' is required",
+		},
+		{
+			name:     "prompt.select with secret",
+			stepName: "prompt.select",
+			cfg: StepConfig{
+				Message: "Seed dataset",
+				Options: []string{"none", "demo"},
+				StoreAs: "seed",
+				Secret:  true,
+			},
+			wantErr: true,
+			errMsg:  "prompt.select: 'secret' is only supported by prompt.input",
+		},
+		{
+			name:     "prompt.select missing options",
+			stepName: "prompt.select",
+			cfg: StepConfig{
+				Message: "Seed dataset",
+				StoreAs: "seed",
+			},
+			wantErr: true,
+			errMsg:  "prompt.select: 'options' is required",
+		},
+		{
+			name:     "prompt.select with default that is not an option",
+			stepName: "prompt.select",
+			cfg: StepConfig{
+				Message: "Seed dataset",
+				Options: []string{"none", "demo"},
+				Default: stringPtr("full"),
+				StoreAs: "seed",
+			},
+			wantErr: true,
+			errMsg:  `prompt.select: default "full" is not one of the options`,
+		},
+		{
+			name:     "prompt.confirm with invalid default",
+			stepName: "prompt.confirm",
+			cfg: StepConfig{
+				Message: "Load fixtures?",
+				Default: stringPtr("maybe"),
+				StoreAs: "fixtures",
+			},
+			wantErr: true,
+			errMsg:  `prompt.confirm: invalid default: "maybe" is not a yes/no answer`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -594,3 +747,7 @@ func TestBinaryStepConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func stringPtr(s string) *string {
+	return &s
+}
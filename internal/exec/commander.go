@@ -4,10 +4,14 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // Commander defines the interface for executing commands.
@@ -22,12 +26,36 @@ type Commander interface {
 // This is the production implementation that actually runs commands.
 type RealCommander struct{}
 
+// killWaitDelay bounds how long Run waits for output pipes to close after
+// the command was killed because its context was cancelled.
+const killWaitDelay = 5 * time.Second
+
 // Run executes the command using exec.CommandContext.
 // The command is executed in the specified directory with the provided arguments.
+// When ctx is cancelled, the command and every process it started are killed.
+// The output is also streamed to the writer registered with WithOutput, and
+// the variables registered with WithEnv are added to the environment.
 func (c *RealCommander) Run(ctx context.Context, dir string, command string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, command, args...)
 	cmd.Dir = dir
-	return cmd.CombinedOutput()
+	if env := Env(ctx); len(env) > 0 {
+		// Later entries win, so the added variables override inherited ones
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.WaitDelay = killWaitDelay
+	configureProcessGroup(cmd)
+
+	var output bytes.Buffer
+	var w io.Writer = &output
+	if streamed := OutputWriter(ctx); streamed != nil {
+		w = io.MultiWriter(&output, streamed)
+	}
+	// Using the same writer for both makes the command share a single pipe,
+	// which keeps stdout and stderr lines in order.
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	return output.Bytes(), err
 }
 
 // CommandExecutor provides a higher-level interface for common execution patterns.
@@ -62,11 +90,16 @@ func (e *CommandExecutor) RunBinary(ctx context.Context, dir string, binary stri
 // RunBash executes a command through bash -c.
 // This is useful for complex commands that require bash features.
 func (e *CommandExecutor) RunBash(ctx context.Context, dir string, command string) ([]byte, error) {
-	return e.commander.Run(ctx, dir, "bash", "-c", command)
+	return e.RunInShell(ctx, dir, "bash", command)
 }
 
 // RunShell executes a command through sh -c.
 // This is more portable than bash but has fewer features.
 func (e *CommandExecutor) RunShell(ctx context.Context, dir string, command string) ([]byte, error) {
-	return e.commander.Run(ctx, dir, "sh", "-c", command)
+	return e.RunInShell(ctx, dir, "sh", command)
+}
+
+// RunInShell executes a command through the given shell (e.g. "zsh") with -c.
+func (e *CommandExecutor) RunInShell(ctx context.Context, dir string, shell string, command string) ([]byte, error) {
+	return e.commander.Run(ctx, dir, shell, "-c", command)
 }
@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"testing"
@@ -208,3 +209,58 @@ func TestMockCommander_ErrorResponse(t *testing.T) {
 		t.Errorf("expected 'error output', got: %s", string(output))
 	}
 }
+
+func TestRealCommander_Run_StreamsOutput(t *testing.T) {
+	commander := &RealCommander{}
+	var first, second bytes.Buffer
+	ctx := WithOutput(WithOutput(context.Background(), &first), &second)
+
+	output, err := commander.Run(ctx, ".", "sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if string(output) != "out\nerr\n" {
+		t.Errorf("expected combined output, got: %q", string(output))
+	}
+	if first.String() != string(output) || second.String() != string(output) {
+		t.Errorf("expected output to be streamed to both writers, got %q and %q", first.String(), second.String())
+	}
+}
+
+func TestOutputWriter_Unset(t *testing.T) {
+	if w := OutputWriter(context.Background()); w != nil {
+		t.Errorf("expected no output writer, got %v", w)
+	}
+}
+
+func TestRealCommander_Run_WithEnv(t *testing.T) {
+	t.Setenv("ANVIL_TEST_INHERITED", "inherited")
+	t.Setenv("ANVIL_TEST_OVERRIDDEN", "inherited")
+	commander := &RealCommander{}
+	ctx := WithEnv(context.Background(), "ANVIL_TEST_OVERRIDDEN=outer", "ANVIL_TEST_ADDED=added")
+	ctx = WithEnv(ctx, "ANVIL_TEST_OVERRIDDEN=inner")
+
+	output, err := commander.Run(ctx, ".", "sh", "-c", `echo "$ANVIL_TEST_INHERITED $ANVIL_TEST_OVERRIDDEN $ANVIL_TEST_ADDED"`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if string(output) != "inherited inner added\n" {
+		t.Errorf("expected 'inherited inner added\\n', got: %q", string(output))
+	}
+}
+
+func TestCommandExecutor_RunInShell(t *testing.T) {
+	mock := NewMockCommander()
+	executor := NewCommandExecutor(mock)
+	ctx := WithEnv(context.Background(), "FOO=bar")
+
+	if _, err := executor.RunInShell(ctx, "/worktree", "zsh", "echo $FOO"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !mock.WasCalled("zsh", "-c", "echo $FOO") {
+		t.Errorf("expected zsh -c to be called, got: %+v", mock.Calls)
+	}
+	if env := mock.LastCall().Env; len(env) != 1 || env[0] != "FOO=bar" {
+		t.Errorf("expected env [FOO=bar], got: %v", env)
+	}
+}
@@ -0,0 +1,25 @@
+package exec
+
+import "context"
+
+type envKey struct{}
+
+// WithEnv returns a copy of ctx that makes commands run with it get the
+// given "KEY=value" environment variables in addition to the environment
+// of anvil. Variables added by nested calls take precedence.
+func WithEnv(ctx context.Context, env ...string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	existing := Env(ctx)
+	merged := make([]string, 0, len(existing)+len(env))
+	merged = append(merged, existing...)
+	merged = append(merged, env...)
+	return context.WithValue(ctx, envKey{}, merged)
+}
+
+// Env returns the environment variables registered with WithEnv, or nil.
+func Env(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
@@ -28,6 +28,9 @@ type CommandCall struct {
 
 	// Args contains all arguments passed to the command.
 	Args []string
+
+	// Env contains the variables registered with WithEnv.
+	Env []string
 }
 
 // CommandResponse defines the response for a specific command.
@@ -55,11 +58,15 @@ func (m *MockCommander) Run(ctx context.Context, dir string, command string, arg
 		Dir:     dir,
 		Command: command,
 		Args:    args,
+		Env:     Env(ctx),
 	}
 	m.Calls = append(m.Calls, call)
 
 	key := buildCommandKey(command, args)
 	if resp, ok := m.Responses[key]; ok {
+		if w := OutputWriter(ctx); w != nil {
+			_, _ = w.Write(resp.Output)
+		}
 		return resp.Output, resp.Err
 	}
 
@@ -0,0 +1,26 @@
+package exec
+
+import (
+	"context"
+	"io"
+)
+
+type outputKey struct{}
+
+// WithOutput returns a copy of ctx that makes commands run with it write
+// their combined stdout and stderr to w while they run. Writers added by
+// nested calls all receive the output.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	if existing := OutputWriter(ctx); existing != nil {
+		w = io.MultiWriter(existing, w)
+	}
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// OutputWriter returns the writer registered with WithOutput, or nil.
+// Callers that receive a non-nil writer need not repeat the command output
+// in their error messages, since it has already been captured.
+func OutputWriter(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputKey{}).(io.Writer)
+	return w
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package exec
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureProcessGroup starts the command in its own process group and
+// makes cancellation kill the whole group, so that grandchildren spawned by
+// package managers or shell pipelines do not outlive the command.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// A negative pid signals every process in the group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRealCommander_Run_KillsProcessGroupOnCancel(t *testing.T) {
+	commander := &RealCommander{}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The background sleep inherits the output pipe; unless the whole
+	// process group is killed, Run would block until it exits.
+	start := time.Now()
+	_, err := commander.Run(ctx, ".", "sh", "-c", "sleep 30 & sleep 30; wait")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected error for cancelled command, got nil")
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("expected command to be killed promptly, took %s", elapsed)
+	}
+}
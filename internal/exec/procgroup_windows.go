@@ -0,0 +1,19 @@
+//go:build windows
+
+package exec
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// configureProcessGroup makes cancellation kill the command together with
+// all processes it started.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
@@ -0,0 +1,35 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ChangedFiles returns the paths, relative to the worktree root, that differ
+// between the merge base of base and HEAD and the working tree. Untracked
+// files that are not ignored are included.
+func ChangedFiles(worktreePath, base string) ([]string, error) {
+	output, err := exec.Command("git", "-C", worktreePath, "merge-base", base, "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("finding merge base with %s: %w", base, err)
+	}
+	mergeBase := strings.TrimSpace(string(output))
+
+	diff, err := exec.Command("git", "-C", worktreePath, "diff", "--name-only", "--no-renames", mergeBase, "--").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing changed files: %w", err)
+	}
+	untracked, err := exec.Command("git", "-C", worktreePath, "ls-files", "--others", "--exclude-standard").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(diff)+string(untracked), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,59 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestChangedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", tmpDir}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run("init", "-b", "main")
+	run("config", "user.name", "Test User")
+	run("config", "user.email", "test@example.com")
+	write("composer.lock", "v1")
+	write("package.json", "{}")
+	write(".gitignore", "vendor/\n")
+	run("add", ".")
+	run("commit", "-m", "Initial commit")
+
+	run("checkout", "-b", "feature")
+	write("composer.lock", "v2")
+	run("commit", "-am", "Update composer.lock")
+	write("package.json", `{"name": "app"}`)
+	write("notes.md", "new")
+	if err := os.MkdirAll(filepath.Join(tmpDir, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write("vendor/autoload.php", "")
+
+	files, err := ChangedFiles(tmpDir, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slices.Sort(files)
+	want := []string{"composer.lock", "notes.md", "package.json"}
+	if !slices.Equal(files, want) {
+		t.Errorf("expected %v, got %v", want, files)
+	}
+
+	if _, err := ChangedFiles(tmpDir, "unknown"); err == nil {
+		t.Error("expected error for unknown base")
+	}
+}
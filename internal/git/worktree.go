@@ -221,6 +221,16 @@ func GetDefaultBranch(gitDir string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// ShortSHA returns the abbreviated hash of the commit checked out in the
+// worktree.
+func ShortSHA(worktreePath string) (string, error) {
+	output, err := exec.Command("git", "-C", worktreePath, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("reading HEAD commit: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // FetchOrigin fetches from the origin remote to update remote-tracking refs.
 func FetchOrigin(gitDir string) error {
 	cmd := exec.Command("git", "-C", gitDir, "fetch", "origin")
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -852,3 +853,17 @@ func TestIsMerged_AgainstRemoteRef(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, merged, "feature should be detected as merged against origin/main")
 }
+
+func TestShortSHA(t *testing.T) {
+	repoDir := createTestRepo(t)
+
+	sha, err := ShortSHA(repoDir)
+	assert.NoError(t, err)
+	full, err := exec.Command("git", "-C", repoDir, "rev-parse", "HEAD").Output()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(sha), 7)
+	assert.True(t, strings.HasPrefix(string(full), sha))
+
+	_, err = ShortSHA(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,291 @@
+package presets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// ProjectPresetsDir is the directory of a project that holds its preset
+// files, relative to the project root.
+const ProjectPresetsDir = ".anvil/presets"
+
+// FilePreset is a preset defined in a YAML file.
+type FilePreset struct {
+	basePreset
+	path   string
+	detect *types.Condition
+}
+
+// Path returns the file the preset is defined in.
+func (p *FilePreset) Path() string {
+	return p.path
+}
+
+// Detect reports whether the detect conditions of the preset hold for the
+// project at path. Presets without detect conditions are never detected.
+func (p *FilePreset) Detect(path string) bool {
+	if p.detect == nil {
+		return false
+	}
+	ok, err := p.detect.Evaluate(&types.ScaffoldContext{WorktreePath: path})
+	return err == nil && ok
+}
+
+// presetFile is the format of a preset file. Steps and cleanup steps can
+// be patched into the steps of the extended preset like in anvil.yaml.
+type presetFile struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Extends     string         `yaml:"extends"`
+	Detect      map[string]any `yaml:"detect"`
+	Scaffold    struct {
+		Steps    []config.StepConfig `yaml:"steps"`
+		Disable  []string            `yaml:"disable"`
+		Override bool                `yaml:"override"`
+	} `yaml:"scaffold"`
+	Cleanup config.CleanupConfig `yaml:"cleanup"`
+
+	path string
+}
+
+// Dirs returns the directories preset files are loaded from for the
+// project at projectPath, in precedence order: the project's own presets,
+// then the presets of the user. An empty projectPath leaves out the
+// project directory.
+func Dirs(projectPath string) []string {
+	var dirs []string
+	if projectPath != "" {
+		dirs = append(dirs, filepath.Join(projectPath, filepath.FromSlash(ProjectPresetsDir)))
+	}
+	// Best-effort: without a home directory there are no user presets
+	if configDir, err := config.GetGlobalConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "presets"))
+	}
+	return dirs
+}
+
+// LoadFiles loads the presets defined in the *.yaml and *.yml files of
+// dirs, which are in precedence order: a preset hides presets of the same
+// name in later directories. Missing directories are skipped. Presets may
+// extend other file presets and built-in presets, but not replace a
+// built-in preset.
+func LoadFiles(dirs ...string) ([]*FilePreset, error) {
+	files, names, err := readPresetDirs(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &presetResolver{files: files, loaded: make(map[string]*FilePreset, len(files))}
+	presets := make([]*FilePreset, 0, len(names))
+	for _, name := range names {
+		preset, err := r.resolve(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+// LoadFile loads the preset defined in the file at path. It may extend the
+// presets of the files in dirs, see LoadFiles, and hides a preset of the
+// same name in them.
+func LoadFile(path string, dirs ...string) (*FilePreset, error) {
+	file, err := readPresetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, builtIn := builtInPreset(file.Name); builtIn {
+		return nil, fmt.Errorf("%s: preset %q is built in, pick another name", file.path, file.Name)
+	}
+	files, _, err := readPresetDirs(dirs)
+	if err != nil {
+		return nil, err
+	}
+	files[file.Name] = file
+
+	r := &presetResolver{files: files, loaded: make(map[string]*FilePreset)}
+	return r.resolve(file.Name, nil)
+}
+
+// readPresetDirs reads the preset files of dirs by name, and the names in
+// the order the presets were found in.
+func readPresetDirs(dirs []string) (map[string]*presetFile, []string, error) {
+	files := make(map[string]*presetFile)
+	var names []string
+	for _, dir := range dirs {
+		loaded, err := readPresetDir(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range loaded {
+			if _, hidden := files[file.Name]; hidden {
+				continue
+			}
+			if _, builtIn := builtInPreset(file.Name); builtIn {
+				return nil, nil, fmt.Errorf("%s: preset %q is built in, pick another name", file.path, file.Name)
+			}
+			files[file.Name] = file
+			names = append(names, file.Name)
+		}
+	}
+	return files, names, nil
+}
+
+// presetResolver builds file presets, resolving the presets they extend.
+type presetResolver struct {
+	files  map[string]*presetFile
+	loaded map[string]*FilePreset
+}
+
+// resolve builds the preset called name. chain holds the presets that
+// extend it, to report extends cycles.
+func (r *presetResolver) resolve(name string, chain []string) (*FilePreset, error) {
+	if preset, ok := r.loaded[name]; ok {
+		return preset, nil
+	}
+	file := r.files[name]
+	if idx := slices.Index(chain, name); idx >= 0 {
+		return nil, fmt.Errorf("%s: extends cycle: %s", file.path, strings.Join(append(chain[idx:], name), " -> "))
+	}
+
+	var parent Preset
+	if file.Extends != "" {
+		if _, ok := r.files[file.Extends]; ok {
+			var err error
+			if parent, err = r.resolve(file.Extends, append(chain, name)); err != nil {
+				return nil, err
+			}
+		} else if builtIn, ok := builtInPreset(file.Extends); ok {
+			parent = builtIn
+		} else {
+			return nil, fmt.Errorf("%s: extends unknown preset %q", file.path, file.Extends)
+		}
+	}
+
+	preset, err := newFilePreset(file, parent)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.path, err)
+	}
+	r.loaded[name] = preset
+	return preset, nil
+}
+
+// readPresetDir reads the preset files of dir sorted by file name.
+func readPresetDir(dir string) ([]*presetFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading presets: %w", err)
+	}
+
+	var files []*presetFile
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		file, err := readPresetFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[file.Name]; ok {
+			return nil, fmt.Errorf("%s: preset %q is already defined in %s", file.path, file.Name, other)
+		}
+		seen[file.Name] = file.path
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// readPresetFile parses a preset file. The preset is named after the file
+// unless the file sets a name.
+func readPresetFile(path string) (*presetFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading preset: %w", err)
+	}
+	defer f.Close()
+
+	file := &presetFile{path: path}
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return file, nil
+}
+
+// newFilePreset builds the preset of a file. Its steps are patched into the
+// steps of parent, if any; scaffold.override and cleanup.override drop the
+// scaffold and cleanup steps of parent.
+func newFilePreset(file *presetFile, parent Preset) (*FilePreset, error) {
+	var detect *types.Condition
+	if len(file.Detect) > 0 {
+		var err error
+		if detect, err = types.CompileCondition(file.Detect); err != nil {
+			return nil, fmt.Errorf("detect: %w", err)
+		}
+	}
+
+	var baseSteps []config.StepConfig
+	var baseCleanup []config.CleanupStep
+	if parent != nil {
+		if !file.Scaffold.Override {
+			baseSteps = parent.DefaultSteps()
+		}
+		if !file.Cleanup.Override {
+			baseCleanup = parent.CleanupSteps()
+		}
+	}
+
+	steps, err := scaffold.ApplyStepPatches(baseSteps, config.ScaffoldConfig{
+		Steps:   file.Scaffold.Steps,
+		Disable: file.Scaffold.Disable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cleanup, err := scaffold.ApplyCleanupPatches(baseCleanup, file.Cleanup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePreset{
+		basePreset: basePreset{
+			name:         file.Name,
+			description:  file.Description,
+			defaultSteps: steps,
+			cleanupSteps: cleanup,
+		},
+		path:   file.path,
+		detect: detect,
+	}, nil
+}
+
+// builtInPreset returns the built-in preset called name.
+func builtInPreset(name string) (Preset, bool) {
+	for _, preset := range builtInPresets {
+		if preset.Name() == name {
+			return preset, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,255 @@
+package presets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+// writePreset writes a preset file to dir.
+func writePreset(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func stepIDs(steps []config.StepConfig) []string {
+	ids := make([]string, len(steps))
+	for i, step := range steps {
+		ids[i] = step.ID
+	}
+	return ids
+}
+
+func TestLoadFiles(t *testing.T) {
+	t.Run("extends a built-in preset", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writePreset(t, dir, "my-company-laravel.yaml", `
+description: Laravel with our tenant setup
+extends: laravel
+detect:
+  file_exists: tenants.json
+scaffold:
+  disable: [herd-link]
+  steps:
+    - name: php.laravel
+      id: tenants
+      args: ["tenants:create"]
+      after: migrate
+cleanup:
+  steps:
+    - name: bash.run
+      id: tenants-drop
+      before: db-destroy
+      condition:
+        command: php artisan tenants:drop
+`)
+
+		loaded, err := LoadFiles(dir)
+		require.NoError(t, err)
+		require.Len(t, loaded, 1)
+		preset := loaded[0]
+
+		assert.Equal(t, "my-company-laravel", preset.Name(), "named after the file")
+		assert.Equal(t, "Laravel with our tenant setup", preset.Description())
+		assert.Equal(t, path, preset.Path())
+
+		ids := stepIDs(preset.DefaultSteps())
+		assert.NotContains(t, ids, "herd-link")
+		assert.Equal(t, []string{"migrate", "tenants", "npm-build", "storage-link"}, ids[len(ids)-4:])
+		assert.Empty(t, preset.DefaultSteps()[len(ids)-3].After, "patches are resolved")
+
+		cleanup := preset.CleanupSteps()
+		require.Len(t, cleanup, 3)
+		assert.Equal(t, []string{"herd-unlink", "tenants-drop", "db-destroy"}, []string{cleanup[0].ID, cleanup[1].ID, cleanup[2].ID})
+
+		project := t.TempDir()
+		assert.False(t, preset.Detect(project))
+		require.NoError(t, os.WriteFile(filepath.Join(project, "tenants.json"), []byte("[]"), 0644))
+		assert.True(t, preset.Detect(project))
+	})
+
+	t.Run("extends a file preset", func(t *testing.T) {
+		dir := t.TempDir()
+		writePreset(t, dir, "child.yml", `
+extends: base
+scaffold:
+  steps:
+    - name: bash.run
+      id: build
+      command: make assets
+      replace: build
+`)
+		writePreset(t, dir, "base.yaml", `
+name: base
+scaffold:
+  steps:
+    - name: bash.run
+      id: install
+      command: make install
+    - name: bash.run
+      id: build
+      command: make build
+`)
+
+		loaded, err := LoadFiles(dir)
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, "base", loaded[0].Name())
+		assert.Equal(t, "child", loaded[1].Name())
+
+		steps := loaded[1].DefaultSteps()
+		assert.Equal(t, []string{"install", "build"}, stepIDs(steps))
+		assert.Equal(t, "make assets", steps[1].Command)
+		assert.False(t, loaded[1].Detect(t.TempDir()), "presets without detect are never detected")
+	})
+
+	t.Run("override drops the steps of the extended preset", func(t *testing.T) {
+		dir := t.TempDir()
+		writePreset(t, dir, "lean.yaml", `
+extends: laravel
+scaffold:
+  override: true
+  steps:
+    - name: php.composer
+      args: [install]
+`)
+
+		loaded, err := LoadFiles(dir)
+		require.NoError(t, err)
+		require.Len(t, loaded[0].DefaultSteps(), 1)
+		assert.Equal(t, NewLaravel().CleanupSteps(), loaded[0].CleanupSteps(), "cleanup steps are kept")
+	})
+
+	t.Run("earlier directories hide later ones", func(t *testing.T) {
+		project, user := t.TempDir(), t.TempDir()
+		path := writePreset(t, project, "team.yaml", "description: project\n")
+		writePreset(t, user, "team.yaml", "description: user\n")
+		writePreset(t, user, "solo.yaml", "description: solo\n")
+
+		loaded, err := LoadFiles(project, user, filepath.Join(user, "missing"))
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, path, loaded[0].Path())
+		assert.Equal(t, "project", loaded[0].Description())
+		assert.Equal(t, "solo", loaded[1].Name())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			files map[string]string
+			want  string
+		}{
+			{
+				name:  "built-in name",
+				files: map[string]string{"a.yaml": "name: laravel\n"},
+				want:  `preset "laravel" is built in`,
+			},
+			{
+				name:  "unknown extended preset",
+				files: map[string]string{"a.yaml": "extends: rails\n"},
+				want:  `extends unknown preset "rails"`,
+			},
+			{
+				name:  "extends cycle",
+				files: map[string]string{"a.yaml": "extends: b\n", "b.yaml": "extends: a\n"},
+				want:  "extends cycle: a -> b -> a",
+			},
+			{
+				name:  "same name twice",
+				files: map[string]string{"a.yaml": "name: team\n", "b.yaml": "name: team\n"},
+				want:  `preset "team" is already defined in`,
+			},
+			{
+				name:  "unknown field",
+				files: map[string]string{"a.yaml": "scaffold:\n  timeout: 10m\n"},
+				want:  "field timeout not found",
+			},
+			{
+				name:  "invalid detect condition",
+				files: map[string]string{"a.yaml": "detect:\n  file_exist: artisan\n"},
+				want:  "detect:",
+			},
+			{
+				name:  "unknown patch target",
+				files: map[string]string{"a.yaml": "extends: php\nscaffold:\n  steps:\n    - name: bash.run\n      after: migrate\n"},
+				want:  `scaffold.steps[0]: after: unknown step id "migrate"`,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				for name, content := range tt.files {
+					writePreset(t, dir, name, content)
+				}
+
+				_, err := LoadFiles(dir)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.want)
+			})
+		}
+	})
+}
+
+func TestLoadManager(t *testing.T) {
+	dir := t.TempDir()
+	writePreset(t, dir, "my-company-laravel.yaml", `
+extends: laravel
+detect:
+  all:
+    - file_exists: artisan
+    - file_exists: tenants.json
+`)
+
+	manager, err := LoadManager(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my-company-laravel", "laravel-shared-db", "laravel", "php"}, manager.Available())
+
+	_, ok := manager.Get("my-company-laravel")
+	assert.True(t, ok)
+
+	project := t.TempDir()
+	for _, name := range []string{"composer.json", "artisan"} {
+		require.NoError(t, os.WriteFile(filepath.Join(project, name), []byte("{}"), 0644))
+	}
+	assert.Equal(t, "laravel", manager.Detect(project))
+
+	require.NoError(t, os.WriteFile(filepath.Join(project, "tenants.json"), []byte("[]"), 0644))
+	assert.Equal(t, "my-company-laravel", manager.Detect(project), "file presets are detected first")
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	writePreset(t, dir, "base.yaml", `
+scaffold:
+  steps:
+    - name: bash.run
+      id: install
+      command: make install
+`)
+	path := writePreset(t, t.TempDir(), "child.yaml", `
+extends: base
+scaffold:
+  steps:
+    - name: php.laravel.artisan
+      after: install
+      args: [migrate]
+`)
+
+	preset, err := LoadFile(path, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "child", preset.Name())
+	assert.Equal(t, []string{"install", ""}, stepIDs(preset.DefaultSteps()))
+
+	problems := validationManager().ValidatePreset(preset)
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), `unknown step "php.laravel.artisan"`)
+}
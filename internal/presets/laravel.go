@@ -15,24 +15,25 @@ type Laravel struct {
 func NewLaravel() *Laravel {
 	return &Laravel{
 		basePreset: basePreset{
-			name: "laravel",
+			name:        "laravel",
+			description: "Laravel application with a database per worktree",
 			defaultSteps: []config.StepConfig{
-				{Name: "php.composer", Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
-				{Name: "php.composer", Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
-				{Name: config.StepFileCopy, From: ".env.example", To: ".env"},
-				{Name: "php.laravel", Args: []string{"key:generate", "--show", "--no-interaction", "--no-ansi"}, StoreAs: "AppKey", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
-				{Name: config.StepEnvWrite, Key: "APP_KEY", Value: "{{ .AppKey }}", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
-				{Name: config.StepDbCreate, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_contains": map[string]any{"file": ".env", "key": "DB_CONNECTION"}}}},
-				{Name: config.StepEnvWrite, Key: "DB_DATABASE", Value: "{{ .DatabaseName }}", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_contains": map[string]any{"file": ".env", "key": "DB_CONNECTION"}}}},
-				{Name: "node.npm", Args: []string{"ci"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
-				{Name: "php.laravel", Args: []string{"migrate:fresh", "--seed", "--no-interaction"}},
-				{Name: "node.npm", Args: []string{"run", "build"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
-				{Name: "php.laravel", Args: []string{"storage:link", "--no-interaction"}},
-				{Name: "herd", Args: []string{"link", "--secure", "{{ .SiteName }}"}},
+				{Name: "php.composer", ID: "composer-install", DependsOn: []string{}, Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
+				{Name: "php.composer", ID: "composer-update", DependsOn: []string{"composer-install"}, Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
+				{Name: config.StepFileCopy, ID: "env-file", DependsOn: []string{}, From: ".env.example", To: ".env"},
+				{Name: "php.laravel", ID: "key-generate", DependsOn: []string{"composer-update", "env-file"}, Args: []string{"key:generate", "--show", "--no-interaction", "--no-ansi"}, StoreAs: "AppKey", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
+				{Name: config.StepEnvWrite, ID: "app-key", DependsOn: []string{"key-generate"}, Key: "APP_KEY", Value: "{{ .AppKey }}", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
+				{Name: config.StepDbCreate, ID: "db-create", DependsOn: []string{"env-file"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_contains": map[string]any{"file": ".env", "key": "DB_CONNECTION"}}}},
+				{Name: config.StepEnvWrite, ID: "db-env", DependsOn: []string{"db-create"}, Key: "DB_DATABASE", Value: "{{ .DatabaseName }}", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_contains": map[string]any{"file": ".env", "key": "DB_CONNECTION"}}}},
+				{Name: "node.npm", ID: "npm-ci", DependsOn: []string{}, Args: []string{"ci"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
+				{Name: "php.laravel", ID: "migrate", DependsOn: []string{"app-key", "db-env"}, Args: []string{"migrate:fresh", "--seed", "--no-interaction"}},
+				{Name: "node.npm", ID: "npm-build", DependsOn: []string{"npm-ci", "composer-update"}, Args: []string{"run", "build"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
+				{Name: "php.laravel", ID: "storage-link", DependsOn: []string{"app-key"}, Args: []string{"storage:link", "--no-interaction"}},
+				{Name: "herd", ID: "herd-link", DependsOn: []string{}, Args: []string{"link", "--secure", "{{ .SiteName }}"}},
 			},
 			cleanupSteps: []config.CleanupStep{
-				{Name: "herd"},
-				{Name: config.StepDbDestroy},
+				{Name: "herd", ID: "herd-unlink"},
+				{Name: config.StepDbDestroy, ID: "db-destroy"},
 			},
 		},
 	}
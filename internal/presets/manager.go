@@ -3,6 +3,7 @@ package presets
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/naoray/anvil/internal/scaffold"
@@ -11,6 +12,7 @@ import (
 
 type Manager struct {
 	presets map[string]Preset
+	order   []string // Preset names in detection order
 }
 
 func NewManager() *Manager {
@@ -23,7 +25,31 @@ func NewManager() *Manager {
 	return m
 }
 
+// LoadManager creates a manager with the presets defined in the preset files
+// of dirs, see LoadFiles, and the built-in presets. File presets are
+// detected before built-in presets, as they are usually more specific.
+func LoadManager(dirs ...string) (*Manager, error) {
+	filePresets, err := LoadFiles(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		presets: make(map[string]Preset),
+	}
+	for _, p := range filePresets {
+		m.Register(p)
+	}
+	for _, p := range builtInPresets {
+		m.Register(p)
+	}
+	return m, nil
+}
+
 func (m *Manager) Register(preset Preset) {
+	if _, ok := m.presets[preset.Name()]; !ok {
+		m.order = append(m.order, preset.Name())
+	}
 	m.presets[preset.Name()] = preset
 }
 
@@ -42,18 +68,27 @@ var builtInPresets = []Preset{
 	NewPHP(),
 }
 
-// RegisterAllWithScaffold registers all built-in presets with a scaffold manager
-func RegisterAllWithScaffold(m *scaffold.ScaffoldManager) {
-	for _, p := range builtInPresets {
-		m.RegisterPreset(p)
+// RegisterWithScaffold registers all presets of the manager with a scaffold manager
+func (m *Manager) RegisterWithScaffold(sm *scaffold.ScaffoldManager) {
+	for _, p := range m.Presets() {
+		sm.RegisterPreset(p)
 	}
 }
 
+// Presets returns the presets in detection order.
+func (m *Manager) Presets() []Preset {
+	presets := make([]Preset, len(m.order))
+	for i, name := range m.order {
+		presets[i] = m.presets[name]
+	}
+	return presets
+}
+
 func (m *Manager) Detect(path string) string {
-	// Iterate in priority order (most specific first) using the ordered slice
-	// instead of the map to ensure deterministic detection.
-	// builtInPresets is ordered from most specific (Laravel) to least specific (PHP).
-	for _, preset := range builtInPresets {
+	// Iterate in registration order (most specific first) instead of the map
+	// to ensure deterministic detection. builtInPresets is ordered from most
+	// specific (Laravel) to least specific (PHP).
+	for _, preset := range m.Presets() {
 		if preset.Detect(path) {
 			return preset.Name()
 		}
@@ -69,12 +104,9 @@ func (m *Manager) Suggest(path string) string {
 	return "php"
 }
 
+// Available returns the names of the presets in detection order.
 func (m *Manager) Available() []string {
-	names := make([]string, 0, len(m.presets))
-	for name := range m.presets {
-		names = append(names, name)
-	}
-	return names
+	return slices.Clone(m.order)
 }
 
 func PromptForPreset(m *Manager, suggested string) (string, error) {
@@ -14,10 +14,11 @@ type PHP struct {
 func NewPHP() *PHP {
 	return &PHP{
 		basePreset: basePreset{
-			name: "php",
+			name:        "php",
+			description: "PHP project installed with Composer",
 			defaultSteps: []config.StepConfig{
-				{Name: "php.composer", Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
-				{Name: "php.composer", Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
+				{Name: "php.composer", ID: "composer-install", DependsOn: []string{}, Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
+				{Name: "php.composer", ID: "composer-update", DependsOn: []string{"composer-install"}, Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
 			},
 			cleanupSteps: nil,
 		},
@@ -6,6 +6,7 @@ import (
 
 type Preset interface {
 	Name() string
+	Description() string
 	Detect(path string) bool
 	DefaultSteps() []config.StepConfig
 	CleanupSteps() []config.CleanupStep
@@ -13,6 +14,7 @@ type Preset interface {
 
 type basePreset struct {
 	name         string
+	description  string
 	defaultSteps []config.StepConfig
 	cleanupSteps []config.CleanupStep
 }
@@ -21,6 +23,10 @@ func (p *basePreset) Name() string {
 	return p.name
 }
 
+func (p *basePreset) Description() string {
+	return p.description
+}
+
 func (p *basePreset) DefaultSteps() []config.StepConfig {
 	return p.defaultSteps
 }
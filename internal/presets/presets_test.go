@@ -7,6 +7,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/scaffold"
+	"github.com/naoray/anvil/internal/scaffold/steps"
 )
 
 func TestLaravelPreset_Detect(t *testing.T) {
@@ -157,6 +160,54 @@ func TestPHPPreset_CleanupSteps(t *testing.T) {
 	assert.Nil(t, steps)
 }
 
+func TestBuiltInPresets_DependencyEdges(t *testing.T) {
+	for _, preset := range builtInPresets {
+		t.Run(preset.Name(), func(t *testing.T) {
+			seen := make(map[string]bool)
+			for _, step := range preset.DefaultSteps() {
+				assert.NotEmpty(t, step.ID, "step %s should have an id", step.Name)
+				assert.NotNil(t, step.DependsOn, "step %s should declare its dependencies", step.ID)
+				for _, dep := range step.DependsOn {
+					assert.True(t, seen[dep], "step %s depends on %q which is not defined before it", step.ID, dep)
+				}
+				assert.False(t, seen[step.ID], "duplicate step id %q", step.ID)
+				seen[step.ID] = true
+			}
+		})
+	}
+}
+
+func TestBuiltInPresets_AssetBuildWaitsForComposer(t *testing.T) {
+	for _, preset := range []Preset{NewLaravel(), NewLaravelSharedDB()} {
+		t.Run(preset.Name(), func(t *testing.T) {
+			for _, step := range preset.DefaultSteps() {
+				if step.ID == "npm-build" {
+					// Vite plugins read vendor assets and run artisan
+					assert.ElementsMatch(t, []string{"npm-ci", "composer-update"}, step.DependsOn)
+					return
+				}
+			}
+			t.Fatal("no npm-build step")
+		})
+	}
+}
+
+// validationManager returns a scaffold manager with the built-in steps.
+func validationManager() *scaffold.ScaffoldManager {
+	registry := steps.NewRegistry()
+	registry.RegisterDefaults()
+	return scaffold.NewScaffoldManagerWithRegistry(registry)
+}
+
+func TestBuiltInPresets_Validate(t *testing.T) {
+	manager := validationManager()
+	for _, preset := range builtInPresets {
+		t.Run(preset.Name(), func(t *testing.T) {
+			assert.Empty(t, manager.ValidatePreset(preset), "every step of the preset can be created")
+		})
+	}
+}
+
 func TestManager_RegisterAndGet(t *testing.T) {
 	m := NewManager()
 
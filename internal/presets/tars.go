@@ -14,22 +14,23 @@ type LaravelSharedDB struct {
 func NewLaravelSharedDB() *LaravelSharedDB {
 	return &LaravelSharedDB{
 		basePreset: basePreset{
-			name: "laravel-shared-db",
+			name:        "laravel-shared-db",
+			description: "Laravel application whose worktrees share one database",
 			defaultSteps: []config.StepConfig{
-				{Name: "php.composer", Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
-				{Name: "php.composer", Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
-				{Name: config.StepFileCopy, From: ".env.example", To: ".env"},
-				{Name: "php.laravel.artisan", Args: []string{"key:generate", "--no-interaction", "--no-ansi"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
+				{Name: "php.composer", ID: "composer-install", DependsOn: []string{}, Args: []string{"install"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "composer.lock"}}},
+				{Name: "php.composer", ID: "composer-update", DependsOn: []string{"composer-install"}, Args: []string{"update"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"not": map[string]any{"file_exists": "composer.lock"}}}},
+				{Name: config.StepFileCopy, ID: "env-file", DependsOn: []string{}, From: ".env.example", To: ".env"},
+				{Name: "php.laravel", ID: "key-generate", DependsOn: []string{"composer-update", "env-file"}, Args: []string{"key:generate", "--no-interaction", "--no-ansi"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"env_file_missing": "APP_KEY"}}},
 				// NO db.create - shared database across all worktrees
 				// NO env.write for DB_DATABASE - preserve the shared database name
-				{Name: "node.npm", Args: []string{"ci"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
+				{Name: "node.npm", ID: "npm-ci", DependsOn: []string{}, Args: []string{"ci"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
 				// NO migrate:fresh - database already exists with shared data
-				{Name: "node.npm", Args: []string{"run", "build"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
-				{Name: "php.laravel.artisan", Args: []string{"storage:link", "--no-interaction"}},
-				{Name: "herd", Args: []string{"link", "--secure", "{{ .SiteName }}"}},
+				{Name: "node.npm", ID: "npm-build", DependsOn: []string{"npm-ci", "composer-update"}, Args: []string{"run", "build"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "package-lock.json"}}},
+				{Name: "php.laravel", ID: "storage-link", DependsOn: []string{"key-generate"}, Args: []string{"storage:link", "--no-interaction"}},
+				{Name: "herd", ID: "herd-link", DependsOn: []string{}, Args: []string{"link", "--secure", "{{ .SiteName }}"}},
 			},
 			cleanupSteps: []config.CleanupStep{
-				{Name: "herd"},
+				{Name: "herd", ID: "herd-unlink"},
 				// NO db.destroy - don't delete the shared database
 			},
 		},
@@ -2,6 +2,7 @@ package scaffold
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -366,3 +367,150 @@ func TestConditionEvaluator_fileHasScript(t *testing.T) {
 		assert.False(t, result)
 	})
 }
+
+func TestConditionEvaluator_Combinators(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pnpm-lock.yaml"), nil, 0644))
+
+	ctx := &types.ScaffoldContext{WorktreePath: tmpDir}
+
+	tests := []struct {
+		name      string
+		condition map[string]any
+		want      bool
+	}{
+		{"any with one match", map[string]any{"any": []any{
+			map[string]any{"file_exists": "yarn.lock"},
+			map[string]any{"file_exists": "pnpm-lock.yaml"},
+		}}, true},
+		{"any without match", map[string]any{"any": []any{
+			map[string]any{"file_exists": "yarn.lock"},
+			map[string]any{"file_exists": "package-lock.json"},
+		}}, false},
+		{"any with a map", map[string]any{"any": map[string]any{
+			"file_exists": "yarn.lock",
+			"os":          runtime.GOOS,
+		}}, true},
+		{"all with every match", map[string]any{"all": []any{
+			map[string]any{"file_exists": "pnpm-lock.yaml"},
+			map[string]any{"os": runtime.GOOS},
+		}}, true},
+		{"all with one mismatch", map[string]any{"all": []any{
+			map[string]any{"file_exists": "pnpm-lock.yaml"},
+			map[string]any{"file_exists": "yarn.lock"},
+		}}, false},
+		{"not any", map[string]any{"not": map[string]any{"any": []any{
+			map[string]any{"file_exists": "yarn.lock"},
+			map[string]any{"file_exists": "package-lock.json"},
+		}}}, true},
+		{"nested any inside all", map[string]any{"all": []any{
+			map[string]any{"os": runtime.GOOS},
+			map[string]any{"any": []any{
+				map[string]any{"file_exists": "yarn.lock"},
+				map[string]any{"file_exists": "pnpm-lock.yaml"},
+			}},
+		}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ctx.EvaluateCondition(tt.condition)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestConditionEvaluator_Predicates(t *testing.T) {
+	ctx := &types.ScaffoldContext{
+		WorktreePath:  t.TempDir(),
+		Branch:        "feature/auth",
+		DefaultBranch: "main",
+		Preset:        "laravel",
+		Vars:          map[string]string{"DbDriver": "pgsql"},
+	}
+
+	tests := []struct {
+		name      string
+		condition map[string]any
+		want      bool
+	}{
+		{"branch_matches glob", map[string]any{"branch_matches": "feature/*"}, true},
+		{"branch_matches glob mismatch", map[string]any{"branch_matches": "release/*"}, false},
+		{"branch_matches list", map[string]any{"branch_matches": []any{"release/*", "feature/*"}}, true},
+		{"branch_matches regex", map[string]any{"branch_matches": "/^feature/(auth|login)$/"}, true},
+		{"var_equals", map[string]any{"var_equals": map[string]any{"name": "DbDriver", "value": "pgsql"}}, true},
+		{"var_equals mismatch", map[string]any{"var_equals": map[string]any{"name": "DbDriver", "value": "mysql"}}, false},
+		{"var_equals unset", map[string]any{"var_equals": map[string]any{"name": "Other", "value": "pgsql"}}, false},
+		{"var_set", map[string]any{"var_set": "DbDriver"}, true},
+		{"var_set unset", map[string]any{"var_set": []any{"DbDriver", "Other"}}, false},
+		{"preset_is", map[string]any{"preset_is": "laravel"}, true},
+		{"preset_is list", map[string]any{"preset_is": []any{"php", "node"}}, false},
+		{"is_default_branch false", map[string]any{"is_default_branch": false}, true},
+		{"is_default_branch true", map[string]any{"is_default_branch": true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ctx.EvaluateCondition(tt.condition)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	t.Run("branch_matches invalid regex", func(t *testing.T) {
+		_, err := ctx.EvaluateCondition(map[string]any{"branch_matches": "/feature/(/"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "branch_matches")
+	})
+}
+
+func TestConditionEvaluator_FileChangedSince(t *testing.T) {
+	tmpDir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", tmpDir}, args...)...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	git("init", "-b", "main")
+	git("config", "user.name", "Test User")
+	git("config", "user.email", "test@example.com")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "database", "migrations"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "composer.lock"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package-lock.json"), []byte("v1"), 0644))
+	git("add", ".")
+	git("commit", "-m", "Initial commit")
+	git("checkout", "-b", "feature/auth")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "database", "migrations", "create_users.php"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "composer.lock"), []byte("v2"), 0644))
+
+	ctx := &types.ScaffoldContext{WorktreePath: tmpDir, Branch: "feature/auth", DefaultBranch: "main"}
+
+	tests := []struct {
+		name  string
+		value any
+		want  bool
+	}{
+		{"changed file", "composer.lock", true},
+		{"unchanged file", "package-lock.json", false},
+		{"directory", "database/migrations", true},
+		{"glob", []any{"package*.json", "*.lock"}, true},
+		{"explicit base", map[string]any{"files": []any{"composer.lock"}, "base": "feature/auth"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ctx.EvaluateCondition(map[string]any{"file_changed_since": tt.value})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	t.Run("unknown base", func(t *testing.T) {
+		_, err := ctx.EvaluateCondition(map[string]any{"file_changed_since": map[string]any{"file": "composer.lock", "base": "nope"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file_changed_since")
+	})
+}
@@ -0,0 +1,148 @@
+package scaffold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// configuredStep wraps a step created by the registry together with the
+// configuration it was created from. It exposes the step-independent
+// options (enabled, id, depends_on, tags, timeout, retries) to the executor without every step
+// implementation having to carry them.
+type configuredStep struct {
+	types.ScaffoldStep
+	cfg config.StepConfig
+}
+
+func newConfiguredStep(step types.ScaffoldStep, cfg config.StepConfig) *configuredStep {
+	return &configuredStep{ScaffoldStep: step, cfg: cfg}
+}
+
+// IsEnabled reports whether the step is enabled. Steps are enabled unless
+// explicitly disabled with `enabled: false`.
+func (s *configuredStep) IsEnabled() bool {
+	return s.cfg.Enabled == nil || *s.cfg.Enabled
+}
+
+// ID returns the step id used to reference it from depends_on.
+func (s *configuredStep) ID() string {
+	return s.cfg.ID
+}
+
+// DependsOn returns the ids of the steps this step waits for.
+// A nil slice means the step has no explicit dependencies.
+func (s *configuredStep) DependsOn() []string {
+	return s.cfg.DependsOn
+}
+
+// Tags returns the tags the step can be selected by with --tag.
+func (s *configuredStep) Tags() []string {
+	return s.cfg.Tags
+}
+
+// Timeout returns the maximum duration of the step, or zero if the step
+// has no timeout. Invalid values are rejected by parseTimeout when the
+// step is created.
+func (s *configuredStep) Timeout() time.Duration {
+	timeout, _ := parseTimeout(s.cfg.Timeout)
+	return timeout
+}
+
+// retryPolicy returns the retry options of the step. Invalid values are
+// rejected by newRetryPolicy when the step is created.
+func (s *configuredStep) retryPolicy() retryPolicy {
+	policy, _ := newRetryPolicy(s.cfg)
+	return policy
+}
+
+// CheckCondition forwards to the wrapped step, so condition evaluation
+// errors fail the step instead of skipping it.
+func (s *configuredStep) CheckCondition(ctx *types.ScaffoldContext) (bool, error) {
+	return stepCondition(s.ScaffoldStep, ctx)
+}
+
+// GetArgs forwards to the wrapped step so step descriptions keep working.
+func (s *configuredStep) GetArgs() []string {
+	if argGetter, ok := s.ScaffoldStep.(interface{ GetArgs() []string }); ok {
+		return argGetter.GetArgs()
+	}
+	return nil
+}
+
+// Plan forwards to the wrapped step. Steps that do not implement it have
+// no command or files to report.
+func (s *configuredStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	if planner, ok := s.ScaffoldStep.(interface {
+		Plan(*types.ScaffoldContext) (types.StepPlan, error)
+	}); ok {
+		return planner.Plan(ctx)
+	}
+	return types.StepPlan{}, nil
+}
+
+// Interactive forwards to the wrapped step.
+func (s *configuredStep) Interactive() bool {
+	return stepInteractive(s.ScaffoldStep)
+}
+
+// SkipReason forwards to the wrapped step.
+func (s *configuredStep) SkipReason(ctx *types.ScaffoldContext) string {
+	return stepSkipReason(s.ScaffoldStep, ctx)
+}
+
+// storedVar returns the variable the step stores its result in, or "".
+func (s *configuredStep) storedVar() string {
+	if s.cfg.StoreAs == "" && s.cfg.Name == config.StepEnvRead {
+		return s.cfg.Key
+	}
+	return s.cfg.StoreAs
+}
+
+// unjournaledVar returns the variable the step stores that is kept out of
+// the scaffold journal, or "". Values read with env.read are often
+// credentials, and reading them again is cheap; secret prompt answers are
+// asked for again.
+func (s *configuredStep) unjournaledVar() string {
+	if s.cfg.Name == config.StepEnvRead || s.cfg.Secret {
+		return s.storedVar()
+	}
+	return ""
+}
+
+// Fingerprint returns a short hash of the step configuration. The scaffold
+// journal uses it to detect steps whose configuration changed since they
+// last succeeded. Tags and patch operations only select and place steps,
+// so they are left out.
+func (s *configuredStep) Fingerprint() string {
+	cfg := s.cfg
+	cfg.Tags = nil
+	cfg.StepPatch = config.StepPatch{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// parseTimeout parses a timeout setting such as "90s" or "10m".
+// An empty value means no timeout.
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", value)
+	}
+	return timeout, nil
+}
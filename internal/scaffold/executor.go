@@ -1,178 +1,749 @@
 package scaffold
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/naoray/anvil/internal/config"
+	anvil_exec "github.com/naoray/anvil/internal/exec"
 	"github.com/naoray/anvil/internal/scaffold/types"
 	"github.com/naoray/anvil/internal/ui"
 )
 
+// ErrInterrupted is wrapped by the error of a step that was stopped because
+// the scaffold run was cancelled, for example with Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrTimeout is wrapped by the error of a step that exceeded its own
+// timeout or the timeout of the whole scaffold run.
+var ErrTimeout = errors.New("timed out")
+
+// liveTailLines is the number of output lines shown below the spinner of
+// a running step.
+const liveTailLines = 3
+
 type ExecutionResult struct {
-	Step    types.ScaffoldStep
-	Error   error
-	Skipped bool
+	Step     types.ScaffoldStep
+	Key      string
+	Error    error
+	Skipped  bool
+	Resumed  bool // Skipped because it already succeeded in a previous run
+	Excluded bool // Skipped because it is not part of the step selection
+	Attempts int  // Number of times the step was run, including retries
+	Duration time.Duration
 }
 
 type StepExecutor struct {
 	steps        []types.ScaffoldStep
 	ctx          *types.ScaffoldContext
 	opts         types.StepOptions
+	concurrency  int
+	timeout      time.Duration
+	runCtx       context.Context
+	journal      *Journal
+	runLog       *RunLog
+	resume       ResumeOptions
+	alreadyDone  map[int]bool
+	selection    StepSelection
+	excluded     map[int]bool
 	results      []ExecutionResult
 	mu           sync.Mutex
 	completedCnt int
 	skippedCnt   int
+	resumedCnt   int
+	excludedCnt  int
+	retriedCnt   int
+	currentStep  int
+	activeSteps  int
+	taskList     *ui.TaskList
 }
 
 func NewStepExecutor(steps []types.ScaffoldStep, ctx *types.ScaffoldContext, opts types.StepOptions) *StepExecutor {
 	return &StepExecutor{
-		steps: steps,
-		ctx:   ctx,
-		opts:  opts,
+		steps:       steps,
+		ctx:         ctx,
+		opts:        opts,
+		concurrency: 1,
 	}
 }
 
+// SetConcurrency sets how many independent steps may run at the same time.
+// Values below 1 are treated as 1, which runs steps one after another.
+func (e *StepExecutor) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	e.concurrency = n
+}
+
+// SetJournal records step outcomes in journal and skips the steps of the
+// previous run selected by resume.
+func (e *StepExecutor) SetJournal(journal *Journal, resume ResumeOptions) {
+	e.journal = journal
+	e.resume = resume
+}
+
+// SetSelection runs only the steps selected by selection. Steps left out
+// are skipped, and steps depending on them run as if they had succeeded.
+func (e *StepExecutor) SetSelection(selection StepSelection) {
+	e.selection = selection
+}
+
+// SetRunLog writes the output of every step to a log file of log.
+func (e *StepExecutor) SetRunLog(log *RunLog) {
+	e.runLog = log
+}
+
+// SetTimeout bounds the duration of the whole run. Zero means no limit.
+func (e *StepExecutor) SetTimeout(d time.Duration) {
+	e.timeout = d
+}
+
+// Execute runs all steps without a cancellation context.
+// See ExecuteContext.
 func (e *StepExecutor) Execute() error {
+	return e.ExecuteContext(context.Background())
+}
+
+// ExecuteContext runs all steps, honoring the dependencies declared with depends_on.
+// Steps without depends_on wait for every step listed before them, so a
+// configuration without dependencies runs in the order it was provided
+// (preset steps first, followed by config steps). When the concurrency is
+// greater than one, steps whose dependencies are satisfied run in parallel.
+//
+// Cancelling ctx stops the running steps and kills the commands they
+// started; the returned error names the step that was stopped.
+func (e *StepExecutor) ExecuteContext(ctx context.Context) error {
 	e.results = make([]ExecutionResult, 0, len(e.steps))
 	e.completedCnt = 0
 	e.skippedCnt = 0
+	e.resumedCnt = 0
+	e.excludedCnt = 0
+	e.retriedCnt = 0
+	e.currentStep = 0
+
+	graph, err := buildStepGraph(e.steps)
+	if err != nil {
+		return err
+	}
+
+	e.alreadyDone, err = e.resolveAlreadyDone()
+	if err != nil {
+		return err
+	}
+
+	e.excluded, err = e.selection.excluded(e.steps)
+	if err != nil {
+		return err
+	}
 
 	// Count active steps for progress tracking
-	activeSteps := e.countActiveSteps()
-	currentStep := 0
+	e.activeSteps = e.countActiveSteps()
 
-	// Execute steps sequentially in the order they were provided
-	// Preset steps come first, followed by config steps
-	for _, step := range e.steps {
-		// Check if step is enabled
-		enabled := true
-		if stepConfig, ok := step.(interface{ IsEnabled() bool }); ok {
-			enabled = stepConfig.IsEnabled()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	e.runCtx = ctx
+
+	if e.journal != nil {
+		if e.resume.Resume || e.resume.From != "" {
+			if previous := e.journal.Previous(); previous != nil {
+				// Variables set before the run, such as project
+				// variables passed with --var, take precedence
+				current := e.ctx.SnapshotVars()
+				for key, value := range previous.Vars {
+					if _, ok := current[key]; !ok {
+						e.ctx.SetVar(key, value)
+					}
+				}
+			}
+		}
+		runID := ""
+		if e.runLog != nil {
+			runID = e.runLog.ID()
 		}
+		if err := e.journal.start(runID, e.journalVars()); err != nil {
+			e.warnJournal(err)
+		}
+	}
 
-		if !enabled {
-			e.mu.Lock()
-			e.results = append(e.results, ExecutionResult{
-				Step:    step,
-				Skipped: true,
+	if e.workers() > 1 && !e.opts.Verbose && !e.opts.Quiet && !e.opts.DryRun {
+		err = ui.RunWithTaskList(func(tl *ui.TaskList) error {
+			e.taskList = tl
+			defer func() { e.taskList = nil }()
+			return e.schedule(graph, func(step types.ScaffoldStep, opts types.StepOptions, progress stepProgress) error {
+				return e.executeInTaskList(tl, step, opts, progress)
 			})
-			e.skippedCnt++
-			e.mu.Unlock()
-			if e.opts.Verbose {
-				fmt.Printf("Skipping step (disabled): %s\n", step.Name())
+		})
+	} else {
+		err = e.schedule(graph, e.executeStep)
+	}
+
+	if e.journal != nil {
+		if journalErr := e.journal.finish(journalStatus(err), e.journalVars()); journalErr != nil {
+			e.warnJournal(journalErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Print summary if not in quiet mode
+	if !e.opts.Quiet {
+		e.printSummary()
+	}
+
+	return nil
+}
+
+// workers returns how many steps may run at once. Runs that may ask the
+// user for input run one step at a time, so prompts are not mixed up with
+// the progress of other steps.
+func (e *StepExecutor) workers() int {
+	if e.opts.Interactive {
+		for i, step := range e.steps {
+			if !e.excluded[i] && stepInteractive(step) {
+				return 1
 			}
-			continue
 		}
+	}
+	return e.concurrency
+}
 
-		// Check condition
-		if !step.Condition(e.ctx) {
-			e.mu.Lock()
-			e.results = append(e.results, ExecutionResult{
-				Step:    step,
-				Skipped: true,
-			})
-			e.skippedCnt++
-			e.mu.Unlock()
-			if e.opts.Verbose {
-				fmt.Printf("Skipping step (condition not met): %s\n", step.Name())
+// stepInteractive reports whether a step may ask the user for input.
+func stepInteractive(step types.ScaffoldStep) bool {
+	interactive, ok := step.(interface{ Interactive() bool })
+	return ok && interactive.Interactive()
+}
+
+// unjournaledVar returns the variable a step stores that is kept out of the
+// journal, or "". Resumed runs run such steps again instead of skipping them.
+func unjournaledVar(step types.ScaffoldStep) string {
+	configured, ok := step.(*configuredStep)
+	if !ok {
+		return ""
+	}
+	return configured.unjournaledVar()
+}
+
+// journalVars returns the variables saved in the journal: project variables
+// and the variables stored by steps, except those of unjournaledVar.
+func (e *StepExecutor) journalVars() map[string]string {
+	vars := e.ctx.SnapshotVars()
+	for _, step := range e.steps {
+		if name := unjournaledVar(step); name != "" {
+			delete(vars, name)
+		}
+	}
+	return vars
+}
+
+// schedule runs the step graph with a bounded number of workers.
+// Ready steps are started in the order they were provided. After a failure
+// no new steps are started; steps already running are allowed to finish.
+func (e *StepExecutor) schedule(graph []*stepNode, run stepRunner) error {
+	type nodeResult struct {
+		node *stepNode
+		err  error
+	}
+
+	pending := make([]int, len(graph))
+	var ready []*stepNode
+	for i, node := range graph {
+		pending[i] = len(node.deps)
+		if pending[i] == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	done := make(chan nodeResult)
+	running, workers := 0, e.workers()
+	var firstErr error
+
+	for len(ready) > 0 || running > 0 {
+		for firstErr == nil && len(ready) > 0 && running < workers {
+			node := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				done <- nodeResult{node: node, err: e.runNode(node, run)}
+			}()
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-done
+		running--
+		if result.err != nil {
+			if firstErr == nil && result.node.attempts > 1 {
+				firstErr = fmt.Errorf("step %s failed after %d attempts: %w", result.node.step.Name(), result.node.attempts, result.err)
+			} else if firstErr == nil {
+				firstErr = fmt.Errorf("step %s failed: %w", result.node.step.Name(), result.err)
 			}
 			continue
 		}
 
-		// Increment current step counter
-		currentStep++
+		for _, dependent := range result.node.dependents {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = insertByIndex(ready, graph[dependent])
+			}
+		}
+	}
 
-		// Execute the step based on mode
+	return firstErr
+}
+
+// stepProgress identifies a single attempt of a step for progress output.
+type stepProgress struct {
+	current  int // Position among the steps that are actually executed
+	attempt  int // 1 for the first run, incremented for every retry
+	attempts int // Maximum number of attempts
+}
+
+// stepRunner runs a single attempt of a step with the given options.
+type stepRunner func(step types.ScaffoldStep, opts types.StepOptions, progress stepProgress) error
+
+// runNode checks whether a step is enabled and its condition is met, and runs it.
+func (e *StepExecutor) runNode(node *stepNode, run stepRunner) error {
+	step := node.step
+	key := stepKey(step, node.index)
+
+	// Do not start steps once the run was cancelled
+	if err := e.runCtx.Err(); err != nil {
+		return e.contextError(e.runCtx, 0, err)
+	}
+
+	// Skip steps left out by --only, --skip and --tag
+	if e.excluded[node.index] {
+		e.recordExcluded(step, key)
 		if e.opts.Verbose {
-			// Verbose mode: print detailed output
-			fmt.Printf("[%d/%d] Executing step: %s\n", currentStep, activeSteps, step.Name())
-
-			if e.opts.DryRun {
-				fmt.Printf("[DRY-RUN] Would execute: %s\n", step.Name())
-				e.mu.Lock()
-				e.results = append(e.results, ExecutionResult{
-					Step: step,
-				})
-				e.completedCnt++
-				e.mu.Unlock()
-			} else {
-				if err := step.Run(e.ctx, e.opts); err != nil {
-					e.mu.Lock()
-					e.results = append(e.results, ExecutionResult{
-						Step:  step,
-						Error: err,
-					})
-					e.mu.Unlock()
-					return fmt.Errorf("step %s failed: %w", step.Name(), err)
-				}
-				e.mu.Lock()
-				e.results = append(e.results, ExecutionResult{
-					Step: step,
-				})
-				e.completedCnt++
-				e.mu.Unlock()
-				fmt.Printf("✓ [%d/%d] %s completed\n", currentStep, activeSteps, step.Name())
+			fmt.Printf("Skipping step (not selected): %s\n", key)
+		}
+		return nil
+	}
+
+	// Skip steps completed in a previous run
+	if e.alreadyDone[node.index] {
+		e.recordResumed(step, key)
+		if e.opts.Verbose {
+			fmt.Printf("Skipping step (already completed): %s\n", key)
+		}
+		return nil
+	}
+
+	// Check if step is enabled
+	enabled := true
+	if stepConfig, ok := step.(interface{ IsEnabled() bool }); ok {
+		enabled = stepConfig.IsEnabled()
+	}
+
+	if !enabled {
+		e.recordSkipped(step, key)
+		if e.opts.Verbose {
+			fmt.Printf("Skipping step (disabled): %s\n", step.Name())
+		}
+		return nil
+	}
+
+	// Check condition
+	met, err := stepCondition(step, e.ctx)
+	if err != nil {
+		err = fmt.Errorf("evaluating condition: %w", err)
+		e.mu.Lock()
+		e.results = append(e.results, ExecutionResult{Step: step, Key: key, Error: err})
+		e.mu.Unlock()
+		if !e.opts.DryRun {
+			e.journalRecord(step, key, config.JournalFailed, 0, 0, "", err)
+		}
+		return err
+	}
+	if !met {
+		e.recordSkipped(step, key)
+		if e.opts.Verbose {
+			fmt.Printf("Skipping step (condition not met): %s\n", step.Name())
+		}
+		return nil
+	}
+
+	e.mu.Lock()
+	e.currentStep++
+	current := e.currentStep
+	e.mu.Unlock()
+
+	policy := stepRetryPolicy(step)
+	progress := stepProgress{current: current, attempts: policy.attempts()}
+	if e.opts.DryRun {
+		progress.attempts = 1
+	}
+
+	log := e.openStepLog(node.index, key)
+	defer func() { _ = log.Close() }()
+
+	started := time.Now()
+	for progress.attempt = 1; ; progress.attempt++ {
+		log.startAttempt(step.Name() + progress.suffix())
+		attemptStarted := time.Now()
+		err = e.runAttempt(step, run, progress, log)
+		log.finishAttempt(time.Since(attemptStarted), err)
+		if err == nil || progress.attempt >= progress.attempts || e.runCtx.Err() != nil || !policy.retryable(err, log.output()) {
+			break
+		}
+
+		delay := policy.backoff(progress.attempt)
+		e.notifyRetry(step, progress, delay, err)
+		if !e.wait(delay) {
+			err = e.contextError(e.runCtx, 0, err)
+			break
+		}
+	}
+	duration := time.Since(started)
+	node.attempts = progress.attempt
+
+	e.mu.Lock()
+	e.results = append(e.results, ExecutionResult{
+		Step:     step,
+		Key:      key,
+		Error:    err,
+		Attempts: progress.attempt,
+		Duration: duration,
+	})
+	if err == nil {
+		e.completedCnt++
+	}
+	e.retriedCnt += progress.attempt - 1
+	e.mu.Unlock()
+
+	if !e.opts.DryRun {
+		e.journalRecord(step, key, journalStatus(err), duration, progress.attempt, log.path, err)
+	}
+	if err != nil {
+		return withOutputTail(err, log, e.ctx.WorktreePath)
+	}
+	return nil
+}
+
+// openStepLog returns the log that collects the output of a step. Without
+// a run log, or if the log file cannot be created, output is only kept in
+// memory.
+func (e *StepExecutor) openStepLog(index int, key string) *stepLog {
+	if e.runLog == nil || e.opts.DryRun {
+		return &stepLog{}
+	}
+	log, err := e.runLog.openStep(index, key)
+	if err != nil {
+		e.warnJournal(err)
+		return &stepLog{}
+	}
+	return log
+}
+
+// runAttempt runs a single attempt of a step, bounded by the step timeout.
+// The output of the commands the step runs is written to output.
+func (e *StepExecutor) runAttempt(step types.ScaffoldStep, run stepRunner, progress stepProgress, output io.Writer) error {
+	timeout := stepTimeout(step)
+	stepCtx, cancel := e.runCtx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		stepCtx, cancel = context.WithTimeout(e.runCtx, timeout)
+	}
+	defer cancel()
+
+	opts := e.opts
+	opts.Ctx = anvil_exec.WithEnv(anvil_exec.WithOutput(stepCtx, output), e.ctx.StandardEnv()...)
+	err := run(step, opts, progress)
+	return e.contextError(stepCtx, timeout, err)
+}
+
+// wait pauses before a retry. It returns false if the run was cancelled
+// in the meantime.
+func (e *StepExecutor) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-e.runCtx.Done():
+		return false
+	}
+}
+
+// notifyRetry reports a failed attempt that is about to be retried.
+func (e *StepExecutor) notifyRetry(step types.ScaffoldStep, progress stepProgress, delay time.Duration, err error) {
+	if e.opts.Quiet {
+		return
+	}
+	msg := fmt.Sprintf("%s failed (attempt %d/%d), retrying in %s", step.Name(), progress.attempt, progress.attempts, delay)
+	if e.opts.Verbose {
+		msg += ": " + err.Error()
+	}
+	if e.taskList != nil {
+		e.taskList.Print("↻ " + msg)
+		return
+	}
+	ui.PrintWarning(msg)
+}
+
+// contextError explains the failure of a step whose context ended, so that
+// the error reports the timeout or interruption instead of the exit status
+// of the killed command. Other errors are returned unchanged.
+func (e *StepExecutor) contextError(stepCtx context.Context, timeout time.Duration, err error) error {
+	if err == nil || stepCtx.Err() == nil {
+		return err
+	}
+	runErr := e.runCtx.Err()
+	switch {
+	case runErr == nil:
+		return fmt.Errorf("%w after %s: %w", ErrTimeout, timeout, err)
+	case errors.Is(runErr, context.DeadlineExceeded) && e.timeout > 0:
+		return fmt.Errorf("%w: scaffold timeout of %s exceeded: %w", ErrTimeout, e.timeout, err)
+	case errors.Is(runErr, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+}
+
+// journalStatus returns the journal status for a step or run that ended with err.
+func journalStatus(err error) string {
+	switch {
+	case err == nil:
+		return config.JournalSucceeded
+	case errors.Is(err, ErrInterrupted):
+		return config.JournalInterrupted
+	default:
+		return config.JournalFailed
+	}
+}
+
+// stepCondition reports whether the condition of a step is met. Steps that
+// can fail to evaluate their condition report the error instead of
+// treating it as unmet.
+func stepCondition(step types.ScaffoldStep, ctx *types.ScaffoldContext) (bool, error) {
+	if checked, ok := step.(interface {
+		CheckCondition(*types.ScaffoldContext) (bool, error)
+	}); ok {
+		return checked.CheckCondition(ctx)
+	}
+	return step.Condition(ctx), nil
+}
+
+// stepTimeout returns the timeout configured for a step, or zero if the
+// step may run indefinitely.
+func stepTimeout(step types.ScaffoldStep) time.Duration {
+	if timed, ok := step.(interface{ Timeout() time.Duration }); ok {
+		return timed.Timeout()
+	}
+	return 0
+}
+
+func (e *StepExecutor) recordSkipped(step types.ScaffoldStep, key string) {
+	e.mu.Lock()
+	e.results = append(e.results, ExecutionResult{
+		Step:    step,
+		Key:     key,
+		Skipped: true,
+	})
+	e.skippedCnt++
+	e.mu.Unlock()
+
+	e.journalRecord(step, key, config.JournalSkipped, 0, 0, "", nil)
+}
+
+func (e *StepExecutor) recordResumed(step types.ScaffoldStep, key string) {
+	e.mu.Lock()
+	e.results = append(e.results, ExecutionResult{
+		Step:    step,
+		Key:     key,
+		Skipped: true,
+		Resumed: true,
+	})
+	e.resumedCnt++
+	e.mu.Unlock()
+
+	e.keepJournalEntry(key)
+}
+
+func (e *StepExecutor) recordExcluded(step types.ScaffoldStep, key string) {
+	e.mu.Lock()
+	e.results = append(e.results, ExecutionResult{
+		Step:     step,
+		Key:      key,
+		Skipped:  true,
+		Excluded: true,
+	})
+	e.excludedCnt++
+	e.mu.Unlock()
+
+	e.keepJournalEntry(key)
+}
+
+// keepJournalEntry copies the entry of a step that was not run from the
+// previous run, so the step stays skippable on the next resume.
+func (e *StepExecutor) keepJournalEntry(key string) {
+	if e.journal == nil {
+		return
+	}
+	entry, ok := e.journal.Previous().Entry(key)
+	if !ok {
+		return
+	}
+	if err := e.journal.record(entry, e.journalVars()); err != nil {
+		e.warnJournal(err)
+	}
+}
+
+func (e *StepExecutor) journalRecord(step types.ScaffoldStep, key, status string, duration time.Duration, attempts int, logPath string, stepErr error) {
+	if e.journal == nil {
+		return
+	}
+	entry := config.JournalEntry{
+		Key:         key,
+		Name:        step.Name(),
+		Fingerprint: stepFingerprint(step),
+		Status:      status,
+		DurationMs:  duration.Milliseconds(),
+		Attempts:    attempts,
+		Log:         logPath,
+		FinishedAt:  time.Now(),
+	}
+	if stepErr != nil {
+		entry.Error = stepErr.Error()
+	}
+	if err := e.journal.record(entry, e.journalVars()); err != nil {
+		e.warnJournal(err)
+	}
+}
+
+// warnJournal reports a failure to persist the journal. The scaffold run
+// itself continues; only the ability to resume it is affected.
+func (e *StepExecutor) warnJournal(err error) {
+	if !e.opts.Quiet {
+		ui.PrintWarning(err.Error())
+	}
+}
+
+// resolveAlreadyDone returns the indexes of the steps that are skipped
+// because of the resume options.
+func (e *StepExecutor) resolveAlreadyDone() (map[int]bool, error) {
+	done := make(map[int]bool)
+
+	if e.resume.From != "" {
+		from := -1
+		for i, step := range e.steps {
+			if stepKey(step, i) == e.resume.From || stepID(step) == e.resume.From {
+				from = i
+				break
 			}
-		} else if !e.opts.Quiet {
-			// Normal mode: use spinner
-			if e.opts.DryRun {
-				desc := getStepDescription(step)
-				fmt.Printf("[DRY-RUN] [%d/%d] Would execute: %s\n", currentStep, activeSteps, desc)
-				e.mu.Lock()
-				e.results = append(e.results, ExecutionResult{
-					Step: step,
-				})
-				e.completedCnt++
-				e.mu.Unlock()
-			} else {
-				if err := e.executeWithSpinner(step, currentStep, activeSteps); err != nil {
-					e.mu.Lock()
-					e.results = append(e.results, ExecutionResult{
-						Step:  step,
-						Error: err,
-					})
-					e.mu.Unlock()
-					return fmt.Errorf("step %s failed: %w", step.Name(), err)
-				}
-				e.mu.Lock()
-				e.results = append(e.results, ExecutionResult{
-					Step: step,
-				})
-				e.completedCnt++
-				e.mu.Unlock()
+		}
+		if from == -1 {
+			keys := make([]string, len(e.steps))
+			for i, step := range e.steps {
+				keys[i] = stepKey(step, i)
 			}
-		} else {
-			// Quiet mode: silent execution
-			if !e.opts.DryRun {
-				if err := step.Run(e.ctx, e.opts); err != nil {
-					e.mu.Lock()
-					e.results = append(e.results, ExecutionResult{
-						Step:  step,
-						Error: err,
-					})
-					e.mu.Unlock()
-					return fmt.Errorf("step %s failed: %w", step.Name(), err)
-				}
+			return nil, fmt.Errorf("unknown step %q (available: %s)", e.resume.From, strings.Join(keys, ", "))
+		}
+		for i := 0; i < from; i++ {
+			if unjournaledVar(e.steps[i]) == "" {
+				done[i] = true
 			}
-			e.mu.Lock()
-			e.results = append(e.results, ExecutionResult{
-				Step: step,
-			})
-			e.completedCnt++
-			e.mu.Unlock()
 		}
+		return done, nil
 	}
 
-	// Print summary if not in quiet mode
-	if !e.opts.Quiet {
-		e.printSummary()
+	if e.resume.Resume && e.journal != nil {
+		previous := e.journal.Previous()
+		for i, step := range e.steps {
+			entry, ok := previous.Entry(stepKey(step, i))
+			if ok && entry.Status == config.JournalSucceeded && entry.Fingerprint == stepFingerprint(step) && unjournaledVar(step) == "" {
+				done[i] = true
+			}
+		}
 	}
 
+	return done, nil
+}
+
+// executeStep runs a single step using the output mode selected by the options.
+func (e *StepExecutor) executeStep(step types.ScaffoldStep, opts types.StepOptions, progress stepProgress) error {
+	current := progress.current
+	if e.opts.Verbose {
+		// Verbose mode: print detailed output
+		fmt.Printf("[%d/%d] Executing step: %s%s\n", current, e.activeSteps, step.Name(), progress.suffix())
+		opts.Ctx = anvil_exec.WithOutput(opts.Context(), os.Stdout)
+
+		if e.opts.DryRun {
+			fmt.Printf("[DRY-RUN] Would execute: %s\n", step.Name())
+			return nil
+		}
+		if err := step.Run(e.ctx, opts); err != nil {
+			return err
+		}
+		fmt.Printf("✓ [%d/%d] %s completed\n", current, e.activeSteps, step.Name())
+		return nil
+	}
+
+	if e.opts.Quiet {
+		// Quiet mode: silent execution
+		if e.opts.DryRun {
+			return nil
+		}
+		return step.Run(e.ctx, opts)
+	}
+
+	// Normal mode: use spinner
+	if e.opts.DryRun {
+		desc := getStepDescription(step)
+		fmt.Printf("[DRY-RUN] [%d/%d] Would execute: %s\n", current, e.activeSteps, desc)
+		return nil
+	}
+	if e.opts.Interactive && stepInteractive(step) {
+		// A spinner would draw over the prompt
+		return step.Run(e.ctx, opts)
+	}
+	return e.executeWithSpinner(step, opts, progress)
+}
+
+// executeInTaskList runs a step as one line of a task list, so that several
+// concurrently running steps are visible at once.
+func (e *StepExecutor) executeInTaskList(tl *ui.TaskList, step types.ScaffoldStep, opts types.StepOptions, progress stepProgress) error {
+	title := e.stepTitle(step, progress)
+
+	tl.Start(progress.current, title)
+	opts.Ctx = anvil_exec.WithOutput(opts.Context(), tl.Writer(progress.current))
+	err := step.Run(e.ctx, opts)
+	if err != nil {
+		tl.Finish(progress.current, "✗ "+title)
+		return err
+	}
+	tl.Finish(progress.current, "✓ "+title)
 	return nil
 }
 
+// stepTitle returns the progress line shown while a step is running.
+func (e *StepExecutor) stepTitle(step types.ScaffoldStep, progress stepProgress) string {
+	return fmt.Sprintf("[%d/%d] %s%s", progress.current, e.activeSteps, getStepDescription(step), progress.suffix())
+}
+
+// suffix returns the attempt counter shown for retries, or "" for the
+// first attempt.
+func (p stepProgress) suffix() string {
+	if p.attempt <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (attempt %d/%d)", p.attempt, p.attempts)
+}
+
 func (e *StepExecutor) Results() []ExecutionResult {
 	return e.results
 }
@@ -183,22 +754,26 @@ func getStepDescription(step types.ScaffoldStep) string {
 
 	// Map common steps to friendly descriptions
 	descriptions := map[string]string{
-		"php.composer.install": "Installing composer dependencies",
-		"php.composer.update":  "Updating composer dependencies",
-		"node.npm.install":     "Installing npm packages",
-		"node.npm.run":         "Running npm script",
-		"node.yarn.install":    "Installing yarn packages",
-		"node.pnpm.install":    "Installing pnpm packages",
-		"node.bun":             "Running bun",
-		config.StepFileCopy:    "Copying files",
-		"file.template":        "Processing template files",
-		config.StepEnvRead:     "Reading environment variables",
-		config.StepEnvWrite:    "Writing environment variables",
-		config.StepDbCreate:    "Creating database",
-		config.StepDbDestroy:   "Destroying database",
-		config.StepBashRun:     "Running bash command",
-		config.StepCommandRun:  "Running command",
-		"herd":                 "Managing Herd",
+		"php.composer.install":   "Installing composer dependencies",
+		"php.composer.update":    "Updating composer dependencies",
+		"node.npm.install":       "Installing npm packages",
+		"node.npm.run":           "Running npm script",
+		"node.yarn.install":      "Installing yarn packages",
+		"node.pnpm.install":      "Installing pnpm packages",
+		"node.bun":               "Running bun",
+		config.StepFileCopy:      "Copying files",
+		config.StepFileTemplate:  "Processing template files",
+		config.StepEnvRead:       "Reading environment variables",
+		config.StepEnvWrite:      "Writing environment variables",
+		config.StepDbCreate:      "Creating database",
+		config.StepDbClone:       "Cloning database",
+		config.StepDbDestroy:     "Destroying database",
+		config.StepBashRun:       "Running bash command",
+		config.StepCommandRun:    "Running command",
+		config.StepPromptInput:   "Asking for input",
+		config.StepPromptSelect:  "Asking for a choice",
+		config.StepPromptConfirm: "Asking for confirmation",
+		"herd":                   "Managing Herd",
 	}
 
 	baseDesc := descriptions[stepName]
@@ -250,35 +825,33 @@ func getStepDescription(step types.ScaffoldStep) string {
 // countActiveSteps counts steps that will actually run (not skipped)
 func (e *StepExecutor) countActiveSteps() int {
 	count := 0
-	for _, step := range e.steps {
+	for i, step := range e.steps {
+		if e.alreadyDone[i] || e.excluded[i] {
+			continue
+		}
 		enabled := true
 		if stepConfig, ok := step.(interface{ IsEnabled() bool }); ok {
 			enabled = stepConfig.IsEnabled()
 		}
 
-		if enabled && step.Condition(e.ctx) {
+		// Steps whose condition fails to evaluate are counted, since
+		// they are reported as failed rather than skipped
+		if met, err := stepCondition(step, e.ctx); enabled && (met || err != nil) {
 			count++
 		}
 	}
 	return count
 }
 
-// executeWithSpinner runs a step with a spinner showing progress
-func (e *StepExecutor) executeWithSpinner(step types.ScaffoldStep, current, total int) error {
-	desc := getStepDescription(step)
-	title := fmt.Sprintf("[%d/%d] %s", current, total, desc)
+// executeWithSpinner runs a step with a spinner showing progress and the
+// last lines of the step's output
+func (e *StepExecutor) executeWithSpinner(step types.ScaffoldStep, opts types.StepOptions, progress stepProgress) error {
+	title := e.stepTitle(step, progress)
 
-	var stepErr error
-	spinnerErr := ui.RunWithSpinner(title, func() error {
-		stepErr = step.Run(e.ctx, e.opts)
-		return stepErr
+	return ui.RunWithOutputTail(title, liveTailLines, func(out io.Writer) error {
+		opts.Ctx = anvil_exec.WithOutput(opts.Context(), out)
+		return step.Run(e.ctx, opts)
 	})
-
-	if spinnerErr != nil {
-		return spinnerErr
-	}
-
-	return stepErr
 }
 
 // printSummary prints a summary of execution results
@@ -286,7 +859,7 @@ func (e *StepExecutor) printSummary() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	if e.completedCnt > 0 || e.skippedCnt > 0 {
+	if e.completedCnt > 0 || e.skippedCnt > 0 || e.resumedCnt > 0 || e.excludedCnt > 0 {
 		summary := fmt.Sprintf("%d step", e.completedCnt)
 		if e.completedCnt != 1 {
 			summary += "s"
@@ -296,6 +869,17 @@ func (e *StepExecutor) printSummary() {
 		if e.skippedCnt > 0 {
 			summary += fmt.Sprintf(", %d skipped", e.skippedCnt)
 		}
+		if e.resumedCnt > 0 {
+			summary += fmt.Sprintf(", %d already completed", e.resumedCnt)
+		}
+		if e.excludedCnt > 0 {
+			summary += fmt.Sprintf(", %d not selected", e.excludedCnt)
+		}
+		if e.retriedCnt == 1 {
+			summary += ", 1 retry"
+		} else if e.retriedCnt > 1 {
+			summary += fmt.Sprintf(", %d retries", e.retriedCnt)
+		}
 
 		ui.PrintSuccess(summary)
 	}
@@ -1,10 +1,16 @@
 package scaffold
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/scaffold/types"
 )
 
@@ -90,6 +96,41 @@ func TestStepExecutor_Execute_StepFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "step2 failed")
 }
 
+// checkedStep is a step whose condition fails to evaluate.
+type checkedStep struct {
+	mockStep
+	conditionErr error
+}
+
+func (s *checkedStep) CheckCondition(ctx *types.ScaffoldContext) (bool, error) {
+	return false, s.conditionErr
+}
+
+func TestStepExecutor_Execute_ConditionError(t *testing.T) {
+	ctx := &types.ScaffoldContext{
+		WorktreePath: "/tmp",
+		Branch:       "test",
+	}
+
+	step1 := &checkedStep{mockStep: mockStep{name: "step1"}, conditionErr: assert.AnError}
+	step2 := &mockStep{name: "step2", conditionResult: true}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step1, step2}, ctx, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step step1 failed: evaluating condition")
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.False(t, step1.runCalled)
+	assert.False(t, step2.runCalled, "steps after a failed condition should not run")
+
+	results := executor.Results()
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Skipped)
+	assert.ErrorIs(t, results[0].Error, assert.AnError)
+}
+
 func TestStepExecutor_Execute_DryRun(t *testing.T) {
 	ctx := &types.ScaffoldContext{
 		WorktreePath: "/tmp",
@@ -287,3 +328,301 @@ func TestStepExecutor_LaravelPresetStepOrdering(t *testing.T) {
 	assert.Equal(t, "php.laravel storage:link", results[7].Step.Name())
 	assert.Equal(t, "herd", results[8].Step.Name())
 }
+
+// recordingStep records start and finish events and can block until released.
+type recordingStep struct {
+	name    string
+	id      string
+	deps    []string
+	enabled bool
+	err     error
+	release chan struct{}
+	log     *eventLog
+}
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func (s *recordingStep) Name() string                              { return s.name }
+func (s *recordingStep) ID() string                                { return s.id }
+func (s *recordingStep) DependsOn() []string                       { return s.deps }
+func (s *recordingStep) IsEnabled() bool                           { return s.enabled }
+func (s *recordingStep) Condition(ctx *types.ScaffoldContext) bool { return true }
+
+func (s *recordingStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	s.log.add("start " + s.name)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("%s was never released", s.name)
+		}
+	}
+	s.log.add("finish " + s.name)
+	return s.err
+}
+
+func indexOf(events []string, event string) int {
+	for i, e := range events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestStepExecutor_Parallel_IndependentStepsRunConcurrently(t *testing.T) {
+	log := &eventLog{}
+	release := make(chan struct{})
+	composer := &recordingStep{name: "composer", id: "composer", deps: []string{}, enabled: true, release: release, log: log}
+	npm := &recordingStep{name: "npm", id: "npm", deps: []string{}, enabled: true, release: release, log: log}
+	migrate := &recordingStep{name: "migrate", id: "migrate", deps: []string{"composer"}, enabled: true, log: log}
+	build := &recordingStep{name: "build", id: "build", deps: []string{"npm"}, enabled: true, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{composer, npm, migrate, build}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetConcurrency(4)
+
+	// Release both installs only once both have started.
+	go func() {
+		deadline := time.After(5 * time.Second)
+		for {
+			events := log.snapshot()
+			if indexOf(events, "start composer") >= 0 && indexOf(events, "start npm") >= 0 {
+				close(release)
+				return
+			}
+			select {
+			case <-deadline:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	require.NoError(t, executor.Execute())
+
+	events := log.snapshot()
+	assert.Less(t, indexOf(events, "start npm"), indexOf(events, "finish composer"), "npm should start while composer is running")
+	assert.Less(t, indexOf(events, "finish composer"), indexOf(events, "start migrate"))
+	assert.Less(t, indexOf(events, "finish npm"), indexOf(events, "start build"))
+	assert.Len(t, executor.Results(), 4)
+}
+
+func TestStepExecutor_Parallel_StepsWithoutDependsOnWaitForPreviousSteps(t *testing.T) {
+	log := &eventLog{}
+	first := &recordingStep{name: "first", id: "first", deps: []string{}, enabled: true, log: log}
+	second := &recordingStep{name: "second", id: "second", deps: []string{}, enabled: true, log: log}
+	last := &recordingStep{name: "last", enabled: true, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{first, second, last}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetConcurrency(4)
+
+	require.NoError(t, executor.Execute())
+
+	events := log.snapshot()
+	assert.Less(t, indexOf(events, "finish first"), indexOf(events, "start last"))
+	assert.Less(t, indexOf(events, "finish second"), indexOf(events, "start last"))
+}
+
+func TestStepExecutor_Parallel_FailureStopsDependents(t *testing.T) {
+	log := &eventLog{}
+	failing := &recordingStep{name: "failing", id: "failing", deps: []string{}, enabled: true, err: assert.AnError, log: log}
+	independent := &recordingStep{name: "independent", id: "independent", deps: []string{}, enabled: true, log: log}
+	dependent := &recordingStep{name: "dependent", id: "dependent", deps: []string{"failing"}, enabled: true, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{failing, independent, dependent}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetConcurrency(2)
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step failing failed")
+	events := log.snapshot()
+	assert.Equal(t, -1, indexOf(events, "start dependent"))
+	assert.NotEqual(t, -1, indexOf(events, "finish independent"), "steps already running should finish")
+}
+
+func TestStepExecutor_DisabledStepSatisfiesDependents(t *testing.T) {
+	log := &eventLog{}
+	disabled := &recordingStep{name: "disabled", id: "disabled", deps: []string{}, enabled: false, log: log}
+	dependent := &recordingStep{name: "dependent", id: "dependent", deps: []string{"disabled"}, enabled: true, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{disabled, dependent}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	require.NoError(t, executor.Execute())
+
+	assert.Equal(t, []string{"start dependent", "finish dependent"}, log.snapshot())
+	results := executor.Results()
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Skipped)
+	assert.False(t, results[1].Skipped)
+}
+
+func TestStepExecutor_Sequential_HonorsForwardDependencies(t *testing.T) {
+	log := &eventLog{}
+	second := &recordingStep{name: "second", id: "second", deps: []string{"first"}, enabled: true, log: log}
+	first := &recordingStep{name: "first", id: "first", deps: []string{}, enabled: true, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{second, first}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	require.NoError(t, executor.Execute())
+
+	assert.Equal(t, []string{"start first", "finish first", "start second", "finish second"}, log.snapshot())
+}
+
+func TestStepExecutor_Parallel_TaskListOutput(t *testing.T) {
+	log := &eventLog{}
+	a := &recordingStep{name: "a", id: "a", deps: []string{}, enabled: true, log: log}
+	b := &recordingStep{name: "b", id: "b", deps: []string{}, enabled: true, err: assert.AnError, log: log}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{a, b}, &types.ScaffoldContext{}, types.StepOptions{})
+	executor.SetConcurrency(2)
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step b failed")
+}
+
+// promptingStep is a mock step that asks the user for input.
+type promptingStep struct {
+	recordingStep
+}
+
+func (s *promptingStep) Interactive() bool {
+	return true
+}
+
+func TestStepExecutor_InteractiveStepsRunOneAtATime(t *testing.T) {
+	log := &eventLog{}
+	prompt := &promptingStep{recordingStep{name: "prompt", id: "prompt", deps: []string{}, enabled: true, log: log}}
+	install := &recordingStep{name: "install", id: "install", deps: []string{}, enabled: true, log: log}
+	steps := []types.ScaffoldStep{prompt, install}
+
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true, Interactive: true})
+	executor.SetConcurrency(4)
+	assert.Equal(t, 1, executor.workers())
+
+	require.NoError(t, executor.Execute())
+	assert.Equal(t, []string{"start prompt", "finish prompt", "start install", "finish install"}, log.snapshot())
+
+	// Without a terminal prompts do not ask, so steps run in parallel
+	executor = NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetConcurrency(4)
+	assert.Equal(t, 4, executor.workers())
+}
+
+func TestStepExecutor_InvalidGraph(t *testing.T) {
+	step := &recordingStep{name: "a", id: "a", deps: []string{"missing"}, enabled: true, log: &eventLog{}}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown step id")
+	assert.Empty(t, step.log.snapshot())
+}
+
+// blockingStep runs until its context is cancelled.
+type blockingStep struct {
+	mockStep
+	started chan struct{}
+}
+
+func newBlockingStep(name string) *blockingStep {
+	return &blockingStep{mockStep: mockStep{name: name, conditionResult: true}, started: make(chan struct{})}
+}
+
+func (s *blockingStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	s.runCalled = true
+	close(s.started)
+	<-opts.Context().Done()
+	return fmt.Errorf("signal: killed")
+}
+
+func TestStepExecutor_StepTimeout(t *testing.T) {
+	blocking := newBlockingStep("slow")
+	step := newConfiguredStep(blocking, config.StepConfig{Name: "slow", Timeout: "50ms"})
+	after := &mockStep{name: "after", conditionResult: true}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step, after}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, "step slow failed: timed out after 50ms: signal: killed", err.Error())
+	assert.False(t, after.runCalled)
+}
+
+func TestStepExecutor_RunTimeout(t *testing.T) {
+	blocking := newBlockingStep("slow")
+
+	executor := NewStepExecutor([]types.ScaffoldStep{blocking}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetTimeout(50 * time.Millisecond)
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Contains(t, err.Error(), "step slow failed: timed out: scaffold timeout of 50ms exceeded")
+}
+
+func TestStepExecutor_Interrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	blocking := newBlockingStep("php.composer")
+	first := &mockStep{name: "first", conditionResult: true}
+	after := &mockStep{name: "after", conditionResult: true}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{first, blocking, after}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-blocking.started
+		cancel()
+	}()
+
+	err := executor.ExecuteContext(ctx)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, "step php.composer failed: interrupted: signal: killed", err.Error())
+	assert.False(t, after.runCalled)
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, config.JournalInterrupted, state.ScaffoldJournal.Status)
+	entry, ok := state.ScaffoldJournal.Entry("php.composer#2")
+	require.True(t, ok)
+	assert.Equal(t, config.JournalInterrupted, entry.Status)
+}
+
+func TestStepExecutor_CancelledBeforeStart(t *testing.T) {
+	step := &mockStep{name: "first", conditionResult: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	err := executor.ExecuteContext(ctx)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.False(t, step.runCalled)
+}
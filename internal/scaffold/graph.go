@@ -0,0 +1,165 @@
+package scaffold
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// stepNode is a step in the execution graph.
+type stepNode struct {
+	index      int
+	step       types.ScaffoldStep
+	deps       []int
+	dependents []int
+	attempts   int // Set by the executor once the step has run
+}
+
+// stepID returns the configured id of a step, or "" if it has none.
+func stepID(step types.ScaffoldStep) string {
+	if identified, ok := step.(interface{ ID() string }); ok {
+		return identified.ID()
+	}
+	return ""
+}
+
+// stepDependsOn returns the ids a step explicitly depends on.
+// The second return value is false if the step does not declare depends_on.
+func stepDependsOn(step types.ScaffoldStep) ([]string, bool) {
+	if dependent, ok := step.(interface{ DependsOn() []string }); ok {
+		deps := dependent.DependsOn()
+		return deps, deps != nil
+	}
+	return nil, false
+}
+
+// buildStepGraph builds the dependency graph for steps.
+// A step that declares depends_on waits only for the listed steps; an empty
+// list means it can start right away. A step without depends_on waits for
+// every step before it, which keeps configurations without dependencies
+// strictly sequential.
+func buildStepGraph(steps []types.ScaffoldStep) ([]*stepNode, error) {
+	nodes := make([]*stepNode, len(steps))
+	ids := make(map[string]int, len(steps))
+
+	for i, step := range steps {
+		nodes[i] = &stepNode{index: i, step: step}
+		id := stepID(step)
+		if id == "" {
+			continue
+		}
+		if prev, exists := ids[id]; exists {
+			return nil, fmt.Errorf("duplicate step id %q (steps %d and %d)", id, prev+1, i+1)
+		}
+		ids[id] = i
+	}
+
+	for i, node := range nodes {
+		deps, explicit := stepDependsOn(node.step)
+		if !explicit {
+			for j := 0; j < i; j++ {
+				node.deps = append(node.deps, j)
+			}
+			continue
+		}
+
+		seen := make(map[int]bool, len(deps))
+		for _, dep := range deps {
+			j, ok := ids[dep]
+			if !ok {
+				return nil, fmt.Errorf("step %s depends on unknown step id %q", describeNode(node), dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("step %s depends on itself", describeNode(node))
+			}
+			if !seen[j] {
+				seen[j] = true
+				node.deps = append(node.deps, j)
+			}
+		}
+		sort.Ints(node.deps)
+	}
+
+	for _, node := range nodes {
+		for _, dep := range node.deps {
+			nodes[dep].dependents = append(nodes[dep].dependents, node.index)
+		}
+	}
+
+	if cycle := findCycle(nodes); len(cycle) > 0 {
+		names := make([]string, len(cycle))
+		for i, n := range cycle {
+			names[i] = describeNode(n)
+		}
+		return nil, fmt.Errorf("dependency cycle between steps: %s", strings.Join(names, " -> "))
+	}
+
+	return nodes, nil
+}
+
+// findCycle returns the nodes forming a dependency cycle, or nil if the
+// graph is acyclic.
+func findCycle(nodes []*stepNode) []*stepNode {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(nodes))
+	var stack []*stepNode
+
+	var visit func(n *stepNode) []*stepNode
+	visit = func(n *stepNode) []*stepNode {
+		state[n.index] = visiting
+		stack = append(stack, n)
+		for _, dep := range n.deps {
+			switch state[dep] {
+			case visiting:
+				for i, s := range stack {
+					if s.index == dep {
+						cycle := append([]*stepNode{}, stack[i:]...)
+						return append(cycle, nodes[dep])
+					}
+				}
+			case unvisited:
+				if cycle := visit(nodes[dep]); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n.index] = visited
+		return nil
+	}
+
+	for _, n := range nodes {
+		if state[n.index] == unvisited {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// describeNode names a step for error messages, preferring its id.
+func describeNode(n *stepNode) string {
+	if id := stepID(n.step); id != "" {
+		return fmt.Sprintf("%q", id)
+	}
+	return fmt.Sprintf("%s (#%d)", n.step.Name(), n.index+1)
+}
+
+// insertByIndex inserts node into ready, keeping ready ordered by the
+// position the steps were provided in.
+func insertByIndex(ready []*stepNode, node *stepNode) []*stepNode {
+	pos := sort.Search(len(ready), func(i int) bool {
+		return ready[i].index > node.index
+	})
+	ready = append(ready, nil)
+	copy(ready[pos+1:], ready[pos:])
+	ready[pos] = node
+	return ready
+}
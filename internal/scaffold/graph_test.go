@@ -0,0 +1,118 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+func graphTestStep(name, id string, dependsOn []string) types.ScaffoldStep {
+	return newConfiguredStep(&mockStep{name: name, conditionResult: true}, config.StepConfig{
+		Name:      name,
+		ID:        id,
+		DependsOn: dependsOn,
+	})
+}
+
+func nodeDeps(nodes []*stepNode) [][]int {
+	deps := make([][]int, len(nodes))
+	for i, n := range nodes {
+		deps[i] = n.deps
+	}
+	return deps
+}
+
+func TestBuildStepGraph_WithoutDependsOnIsSequential(t *testing.T) {
+	nodes, err := buildStepGraph([]types.ScaffoldStep{
+		&mockStep{name: "a"},
+		&mockStep{name: "b"},
+		&mockStep{name: "c"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{nil, {0}, {0, 1}}, nodeDeps(nodes))
+	assert.Equal(t, []int{1, 2}, nodes[0].dependents)
+}
+
+func TestBuildStepGraph_ExplicitDependencies(t *testing.T) {
+	nodes, err := buildStepGraph([]types.ScaffoldStep{
+		graphTestStep("php.composer", "composer", []string{}),
+		graphTestStep("node.npm", "npm", []string{}),
+		graphTestStep("php.laravel", "migrate", []string{"composer", "composer"}),
+		graphTestStep("node.npm", "build", []string{"npm"}),
+		graphTestStep("herd", "", nil),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{nil, nil, {0}, {1}, {0, 1, 2, 3}}, nodeDeps(nodes))
+}
+
+func TestBuildStepGraph_ForwardReference(t *testing.T) {
+	nodes, err := buildStepGraph([]types.ScaffoldStep{
+		graphTestStep("bash.run", "second", []string{"first"}),
+		graphTestStep("bash.run", "first", []string{}),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1}, nil}, nodeDeps(nodes))
+}
+
+func TestBuildStepGraph_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []types.ScaffoldStep
+		wantErr string
+	}{
+		{
+			name: "unknown id",
+			steps: []types.ScaffoldStep{
+				graphTestStep("bash.run", "a", []string{"missing"}),
+			},
+			wantErr: `step "a" depends on unknown step id "missing"`,
+		},
+		{
+			name: "duplicate id",
+			steps: []types.ScaffoldStep{
+				graphTestStep("bash.run", "a", nil),
+				graphTestStep("bash.run", "a", nil),
+			},
+			wantErr: `duplicate step id "a" (steps 1 and 2)`,
+		},
+		{
+			name: "self dependency",
+			steps: []types.ScaffoldStep{
+				graphTestStep("bash.run", "a", []string{"a"}),
+			},
+			wantErr: `step "a" depends on itself`,
+		},
+		{
+			name: "cycle",
+			steps: []types.ScaffoldStep{
+				graphTestStep("bash.run", "a", []string{"b"}),
+				graphTestStep("bash.run", "b", []string{"a"}),
+			},
+			wantErr: `dependency cycle between steps: "a" -> "b" -> "a"`,
+		},
+		{
+			name: "cycle through implicit dependency",
+			steps: []types.ScaffoldStep{
+				graphTestStep("bash.run", "a", []string{"c"}),
+				graphTestStep("bash.run", "b", nil),
+				graphTestStep("bash.run", "c", []string{"b"}),
+			},
+			wantErr: `dependency cycle between steps: "a" -> "c" -> "b" -> "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildStepGraph(tt.steps)
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package scaffold
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -567,6 +570,32 @@ func TestIntegration_PreFlightChecks(t *testing.T) {
 		assert.Contains(t, err.Error(), "nonexistent.txt")
 	})
 
+	t.Run("pre-flight any passes when one alternative exists", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pnpm-lock.yaml"), nil, 0644))
+
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				PreFlight: &config.PreFlight{
+					Condition: map[string]any{
+						"any": []any{
+							map[string]any{"file_exists": "yarn.lock"},
+							map[string]any{"file_exists": "pnpm-lock.yaml"},
+						},
+					},
+				},
+			},
+		}
+
+		manager := NewScaffoldManager()
+		require.NoError(t, manager.RunScaffold(tmpDir, "test", "testrepo", "testsite", "", cfg, false, false, true))
+
+		require.NoError(t, os.Remove(filepath.Join(tmpDir, "pnpm-lock.yaml")))
+		err := manager.RunScaffold(tmpDir, "test", "testrepo", "testsite", "", cfg, false, false, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Missing files:\n  - yarn.lock\n  - pnpm-lock.yaml")
+	})
+
 	t.Run("pre-flight failure - multiple missing dependencies", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
@@ -637,3 +666,439 @@ func TestIntegration_PreFlightChecks(t *testing.T) {
 		assert.NotContains(t, err.Error(), "exists.txt", "Should not list files that exist")
 	})
 }
+
+func TestIntegration_ConfiguredStepOptions(t *testing.T) {
+	t.Run("disabled steps are skipped", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		disabled := false
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "exit 1", Enabled: &disabled},
+					{Name: "bash.run", Command: "echo ok > marker.txt"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(tmpDir, "marker.txt"))
+	})
+
+	t.Run("parallel dependencies run steps after their dependencies", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", ID: "write", DependsOn: []string{}, Command: "sleep 0.1 && echo first > first.txt"},
+					{Name: "bash.run", ID: "other", DependsOn: []string{}, Command: "echo other > other.txt"},
+					{Name: "bash.run", ID: "read", DependsOn: []string{"write"}, Command: "cp first.txt second.txt"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+		manager.SetParallelDependencies(true)
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "second.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "first\n", string(content))
+		assert.FileExists(t, filepath.Join(tmpDir, "other.txt"))
+	})
+
+	t.Run("unknown dependency fails before any step runs", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "echo ok > marker.txt"},
+					{Name: "bash.run", ID: "late", DependsOn: []string{"missing"}, Command: "true"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown step id "missing"`)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "marker.txt"))
+	})
+
+	t.Run("step timeout kills the command", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "sleep 30", Timeout: "200ms"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		started := time.Now()
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Contains(t, err.Error(), "step bash.run failed: timed out after 200ms")
+		assert.Less(t, time.Since(started), 10*time.Second)
+	})
+
+	t.Run("retries a command that exits with a listed code", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{
+						Name:       "bash.run",
+						Command:    "echo run >> attempts.txt; test $(wc -l < attempts.txt) -ge 2 || exit 3",
+						Retries:    2,
+						RetryDelay: "10ms",
+						RetryOn:    &config.RetryOn{ExitCodes: []int{3}},
+					},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "attempts.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "run\nrun\n", string(content))
+	})
+
+	t.Run("failed command output is logged and tailed", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "echo preparing; echo broken >&2; exit 1"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Last 2 lines of output:\n  preparing\n  broken")
+
+		runs, err := ListRunLogs(tmpDir)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		path, ok := runs[0].StepLog("bash.run#1")
+		require.True(t, ok)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "preparing\nbroken\n")
+	})
+
+	t.Run("invalid timeout is rejected", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "true", Timeout: "soon"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid timeout "soon"`)
+	})
+}
+
+func TestIntegration_ConditionValidation(t *testing.T) {
+	t.Run("invalid step condition names the anvil.yaml line", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, config.ProjectConfigFile), []byte(`scaffold:
+  steps:
+    - name: bash.run
+      command: echo ok > marker.txt
+    - name: bash.run
+      command: echo skipped
+      condition:
+        file_exist: composer.json
+`), 0644))
+		resolved, err := config.ResolveProjectConfig(tmpDir, "", nil)
+		require.NoError(t, err)
+		manager := NewScaffoldManager()
+		manager.SetConfigLocator(resolved)
+
+		err = manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", resolved.Config, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join(tmpDir, config.ProjectConfigFile)+`:8: scaffold.steps[1].condition.file_exist: unknown condition "file_exist" (did you mean "file_exists"?)`)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "marker.txt"), "no step should run with an invalid condition")
+	})
+
+	t.Run("invalid pre-flight condition is rejected", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				PreFlight: &config.PreFlight{
+					Condition: map[string]any{"command_exists": map[string]any{"cmd": "php"}},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid pre-flight condition: scaffold.pre_flight.condition.command_exists.cmd: unknown field")
+	})
+
+	t.Run("condition evaluation errors fail the step", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			DefaultBranch: "main",
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "echo ok > marker.txt", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_changed_since": "composer.lock"}}},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "step bash.run failed: evaluating condition: file_changed_since")
+		assert.NoFileExists(t, filepath.Join(tmpDir, "marker.txt"))
+	})
+}
+
+func TestIntegration_TemplatePorts(t *testing.T) {
+	t.Run("ports are saved to local state and reused", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", Command: "echo {{ .ProjectName }} {{ .Port.app }} > ports.txt"},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+		manager.SetProject("myapp", "/code/myapp/main")
+
+		require.NoError(t, manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true))
+		first, err := os.ReadFile(filepath.Join(tmpDir, "ports.txt"))
+		require.NoError(t, err)
+
+		state, err := config.ReadLocalState(tmpDir)
+		require.NoError(t, err)
+		require.Contains(t, state.Ports, "app")
+		assert.Equal(t, fmt.Sprintf("myapp %d\n", state.Ports["app"]), string(first))
+
+		require.NoError(t, manager.RunScaffold(tmpDir, "test", "myrepo", "myapp", "", cfg, false, false, true))
+		second, err := os.ReadFile(filepath.Join(tmpDir, "ports.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, string(first), string(second))
+	})
+}
+
+func TestIntegration_StepSelection(t *testing.T) {
+	t.Run("scaffold runs only the selected steps", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", ID: "build", Command: "touch build.txt", Tags: []string{"assets"}},
+					{Name: "bash.run", ID: "migrate", Command: "touch migrate.txt", Tags: []string{"db"}},
+					{Name: "bash.run", ID: "seed", Command: "touch seed.txt", Tags: []string{"db"}},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{
+			Quiet:     true,
+			Selection: StepSelection{Tags: []string{"db"}, Skip: []string{"seed"}},
+		})
+		require.NoError(t, err)
+
+		assert.NoFileExists(t, filepath.Join(tmpDir, "build.txt"))
+		assert.FileExists(t, filepath.Join(tmpDir, "migrate.txt"))
+		assert.NoFileExists(t, filepath.Join(tmpDir, "seed.txt"))
+	})
+
+	t.Run("cleanup runs only the selected steps", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg := &config.Config{
+			Cleanup: config.CleanupConfig{
+				Steps: []config.CleanupStep{
+					{Name: "bash.run", ID: "stop", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"command": "touch stop.txt"}}},
+					{Name: "bash.run", ID: "archive", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"command": "touch archive.txt"}}},
+				},
+			},
+		}
+		manager := NewScaffoldManager()
+
+		err := manager.RunCleanupWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{
+			Quiet:     true,
+			Selection: StepSelection{Only: []string{"archive"}},
+		})
+		require.NoError(t, err)
+
+		assert.NoFileExists(t, filepath.Join(tmpDir, "stop.txt"))
+		assert.FileExists(t, filepath.Join(tmpDir, "archive.txt"))
+	})
+}
+
+func TestIntegration_CommandEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "frontend"), 0755))
+
+	cfg := &config.Config{
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{
+					Name:    "bash.run",
+					Command: `printf '%s\n' "$ANVIL_BRANCH" "$ANVIL_SITE_NAME" "$ANVIL_WORKTREE" "$APP_URL" "$PWD" > env.txt`,
+					Env:     map[string]string{"APP_URL": "https://{{ .SiteName }}.test"},
+					Cwd:     "frontend",
+				},
+			},
+		},
+	}
+	manager := NewScaffoldManager()
+
+	err := manager.RunScaffoldWithOptions(context.Background(), tmpDir, "feature/auth", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "frontend", "env.txt"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "feature/auth", lines[0])
+	assert.Equal(t, "myapp", lines[1])
+	assert.Equal(t, tmpDir, lines[2])
+	assert.Equal(t, "https://myapp.test", lines[3])
+	assert.Equal(t, filepath.Join(tmpDir, "frontend"), lines[4])
+}
+
+func TestIntegration_PromptSteps(t *testing.T) {
+	seedDefault := "none"
+	cfg := &config.Config{
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "prompt.select", Message: "Seed dataset", Options: []string{"none", "demo"}, Default: &seedDefault, StoreAs: "seed"},
+				{Name: "prompt.input", Message: "API token", StoreAs: "api_token"},
+				{Name: "bash.run", Command: "echo {{ .seed }}-{{ .api_token }} > answers.txt"},
+			},
+		},
+	}
+
+	t.Run("uses defaults and --var values", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		err := NewScaffoldManager().RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{
+			Quiet: true,
+			Vars:  map[string]string{"api_token": "abc123"},
+		})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "answers.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "none-abc123\n", string(content))
+	})
+
+	t.Run("fails without a value for a prompt", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		err := NewScaffoldManager().RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pass --var api_token=<value> or set a default")
+		assert.NoFileExists(t, filepath.Join(tmpDir, "answers.txt"))
+	})
+}
+
+func TestIntegration_ProjectVars(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Vars: map[string]config.VarConfig{
+			"seed":   {Default: "demo"},
+			"tenant": {Required: true},
+		},
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", Command: "echo {{ .tenant }}-{{ .seed }} > scaffold.txt"},
+			},
+		},
+		Cleanup: config.CleanupConfig{
+			Steps: []config.CleanupStep{
+				{Name: "bash.run", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"command": "echo {{ .tenant }} > cleanup.txt"}}},
+			},
+		},
+	}
+	manager := NewScaffoldManager()
+	run := func(vars map[string]string) error {
+		return manager.RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true, Vars: vars})
+	}
+	readFile := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(tmpDir, name))
+		require.NoError(t, err)
+		return strings.TrimSpace(string(content))
+	}
+
+	err := run(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required variables")
+
+	err = run(map[string]string{"tenant": "acme", "tenat": "typo"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown variable "tenat"`)
+
+	require.NoError(t, run(map[string]string{"tenant": "acme"}))
+	assert.Equal(t, "acme-demo", readFile("scaffold.txt"))
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"seed": "demo", "tenant": "acme"}, state.Vars)
+
+	// Later runs reuse the saved values unless they are overridden
+	require.NoError(t, run(map[string]string{"seed": "full"}))
+	assert.Equal(t, "acme-full", readFile("scaffold.txt"))
+
+	require.NoError(t, manager.RunCleanupWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true}))
+	assert.Equal(t, "acme", readFile("cleanup.txt"))
+}
+
+func TestIntegration_Profile(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Profile: "minimal",
+		Profiles: map[string]config.ProfileConfig{
+			"minimal": {Skip: []string{"build"}},
+		},
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", ID: "install", Command: "touch installed"},
+				{Name: "bash.run", ID: "build", Command: "touch built"},
+			},
+		},
+	}
+
+	manager := NewScaffoldManager()
+	require.NoError(t, manager.RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true}))
+
+	assert.FileExists(t, filepath.Join(tmpDir, "installed"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "built"))
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	require.NotNil(t, state.Profile)
+	assert.Equal(t, "minimal", *state.Profile, ".anvil.local records the profile")
+	require.NotNil(t, state.ScaffoldJournal)
+	require.Len(t, state.ScaffoldJournal.Steps, 1)
+	assert.Equal(t, "install", state.ScaffoldJournal.Steps[0].Key)
+}
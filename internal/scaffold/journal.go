@@ -0,0 +1,143 @@
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// ResumeOptions select which steps of a previously journaled run are skipped.
+type ResumeOptions struct {
+	// Resume skips steps that succeeded in the previous run with an
+	// unchanged configuration.
+	Resume bool
+	// From restarts the run at the step with this key or id; all steps
+	// listed before it are skipped.
+	From string
+}
+
+// Journal records the outcome of every scaffold step in the worktree's
+// .anvil.local so that a failed or interrupted run can be resumed.
+type Journal struct {
+	worktreePath string
+	persist      bool
+	previous     *config.ScaffoldJournal
+	current      config.ScaffoldJournal
+	mu           sync.Mutex
+}
+
+// NewJournal creates a journal for worktreePath. previous is the journal
+// of the last run (may be nil). When persist is false, nothing is written,
+// which is used for dry runs.
+func NewJournal(worktreePath string, previous *config.ScaffoldJournal, persist bool) *Journal {
+	return &Journal{
+		worktreePath: worktreePath,
+		persist:      persist,
+		previous:     previous,
+	}
+}
+
+// Previous returns the journal of the last run, or nil if none was recorded.
+func (j *Journal) Previous() *config.ScaffoldJournal {
+	return j.previous
+}
+
+// Current returns a copy of the journal of the current run.
+func (j *Journal) Current() config.ScaffoldJournal {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	current := j.current
+	current.Steps = append([]config.JournalEntry(nil), j.current.Steps...)
+	return current
+}
+
+func (j *Journal) start(runID string, vars map[string]string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.current = config.ScaffoldJournal{
+		Status:    config.JournalRunning,
+		RunID:     runID,
+		StartedAt: time.Now(),
+		Vars:      vars,
+	}
+	return j.save()
+}
+
+// record adds or replaces the entry for a step and persists the journal.
+func (j *Journal) record(entry config.JournalEntry, vars map[string]string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	replaced := false
+	for i, existing := range j.current.Steps {
+		if existing.Key == entry.Key {
+			j.current.Steps[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		j.current.Steps = append(j.current.Steps, entry)
+	}
+	j.current.Vars = vars
+	return j.save()
+}
+
+func (j *Journal) finish(status string, vars map[string]string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.current.Status = status
+	j.current.FinishedAt = &now
+	j.current.Vars = vars
+	return j.save()
+}
+
+func (j *Journal) save() error {
+	if !j.persist {
+		return nil
+	}
+	current := j.current
+	if err := config.WriteLocalState(j.worktreePath, config.LocalState{ScaffoldJournal: &current}); err != nil {
+		return fmt.Errorf("writing scaffold journal: %w", err)
+	}
+	return nil
+}
+
+// stepKey identifies a step across runs: its id if it has one, otherwise
+// its name, arguments and position, e.g. "node.npm:run-build#3".
+func stepKey(step types.ScaffoldStep, index int) string {
+	if id := stepID(step); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s#%d", stepLabel(step), index+1)
+}
+
+var nonKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// stepLabel returns the name of a step followed by its arguments, e.g.
+// "php.laravel:migrate-seed" for `php artisan migrate --seed`.
+func stepLabel(step types.ScaffoldStep) string {
+	argGetter, ok := step.(interface{ GetArgs() []string })
+	if !ok {
+		return step.Name()
+	}
+	args := strings.Trim(nonKeyChars.ReplaceAllString(strings.ToLower(strings.Join(argGetter.GetArgs(), " ")), "-"), "-")
+	if args == "" {
+		return step.Name()
+	}
+	return step.Name() + ":" + args
+}
+
+// stepFingerprint returns the configuration fingerprint of a step, or ""
+// if the step does not expose one.
+func stepFingerprint(step types.ScaffoldStep) string {
+	if fingerprinted, ok := step.(interface{ Fingerprint() string }); ok {
+		return fingerprinted.Fingerprint()
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+package scaffold
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// varStep stores a variable when it runs.
+type varStep struct {
+	mockStep
+	key, value string
+}
+
+func (s *varStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	s.runCalled = true
+	ctx.SetVar(s.key, s.value)
+	return s.runError
+}
+
+func journaledStep(name, id string, cfg config.StepConfig) (*mockStep, types.ScaffoldStep) {
+	mock := &mockStep{name: name, conditionResult: true}
+	cfg.Name = name
+	cfg.ID = id
+	return mock, newConfiguredStep(mock, cfg)
+}
+
+func TestStepExecutor_Journal_RecordsOutcomes(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, install := journaledStep("php.composer", "install", config.StepConfig{Args: []string{"install"}})
+	failing, migrate := journaledStep("php.laravel", "", config.StepConfig{Args: []string{"migrate"}})
+	failing.runError = assert.AnError
+	skipped := &mockStep{name: "herd", conditionResult: false}
+
+	journal := NewJournal(tmpDir, nil, true)
+	executor := NewStepExecutor([]types.ScaffoldStep{install, migrate, skipped}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(journal, ResumeOptions{})
+
+	err := executor.Execute()
+	require.Error(t, err)
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	require.NotNil(t, state.ScaffoldJournal)
+	assert.Equal(t, config.JournalFailed, state.ScaffoldJournal.Status)
+	assert.NotNil(t, state.ScaffoldJournal.FinishedAt)
+	require.Len(t, state.ScaffoldJournal.Steps, 2, "steps after the failure are not started")
+
+	entry, ok := state.ScaffoldJournal.Entry("install")
+	require.True(t, ok)
+	assert.Equal(t, config.JournalSucceeded, entry.Status)
+	assert.Equal(t, stepFingerprint(install), entry.Fingerprint)
+	assert.NotEmpty(t, entry.Fingerprint)
+
+	entry, ok = state.ScaffoldJournal.Entry("php.laravel#2")
+	require.True(t, ok)
+	assert.Equal(t, config.JournalFailed, entry.Status)
+	assert.Equal(t, assert.AnError.Error(), entry.Error)
+
+	results := executor.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, "install", results[0].Key)
+}
+
+func TestStepExecutor_Journal_Resume(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	newSteps := func(migrateArgs ...string) (*mockStep, *mockStep, *mockStep, []types.ScaffoldStep) {
+		installMock, install := journaledStep("php.composer", "install", config.StepConfig{Args: []string{"install"}})
+		migrateMock, migrate := journaledStep("php.laravel", "migrate", config.StepConfig{Args: migrateArgs})
+		linkMock, link := journaledStep("herd", "link", config.StepConfig{})
+		return installMock, migrateMock, linkMock, []types.ScaffoldStep{install, migrate, link}
+	}
+
+	// First run fails at migrate
+	_, migrateMock, _, steps := newSteps("migrate")
+	migrateMock.runError = assert.AnError
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	require.Error(t, executor.Execute())
+
+	// Resume skips the step that succeeded
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	installMock, migrateMock, linkMock, steps := newSteps("migrate")
+	executor = NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), ResumeOptions{Resume: true})
+	require.NoError(t, executor.Execute())
+
+	assert.False(t, installMock.runCalled)
+	assert.True(t, migrateMock.runCalled)
+	assert.True(t, linkMock.runCalled)
+	assert.True(t, executor.Results()[0].Resumed)
+
+	state, err = config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, config.JournalSucceeded, state.ScaffoldJournal.Status)
+	assert.Len(t, state.ScaffoldJournal.Steps, 3, "resumed steps keep their journal entry")
+
+	// A changed configuration invalidates the journal entry
+	installMock, migrateMock, _, steps = newSteps("migrate:fresh")
+	executor = NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), ResumeOptions{Resume: true})
+	require.NoError(t, executor.Execute())
+
+	assert.False(t, installMock.runCalled)
+	assert.True(t, migrateMock.runCalled)
+}
+
+func TestStepExecutor_Journal_ResumeRestoresVars(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	keyStep := &varStep{mockStep: mockStep{name: "php.laravel", conditionResult: true}, key: "AppKey", value: "base64:secret"}
+	steps := []types.ScaffoldStep{newConfiguredStep(keyStep, config.StepConfig{Name: "php.laravel", ID: "key"})}
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	require.NoError(t, executor.Execute())
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+
+	ctx := &types.ScaffoldContext{}
+	keyStep = &varStep{mockStep: mockStep{name: "php.laravel", conditionResult: true}, key: "AppKey", value: "base64:other"}
+	steps = []types.ScaffoldStep{newConfiguredStep(keyStep, config.StepConfig{Name: "php.laravel", ID: "key"})}
+	executor = NewStepExecutor(steps, ctx, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), ResumeOptions{Resume: true})
+	require.NoError(t, executor.Execute())
+
+	assert.False(t, keyStep.runCalled)
+	assert.Equal(t, "base64:secret", ctx.GetVar("AppKey"))
+}
+
+func TestStepExecutor_Journal_EnvReadIsNotJournaled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	newSteps := func() (*varStep, *mockStep, []types.ScaffoldStep) {
+		password := &varStep{mockStep: mockStep{name: "env.read", conditionResult: true}, key: "DbPassword", value: "s3cret"}
+		deployMock, deploy := journaledStep("bash.run", "deploy", config.StepConfig{Command: "./deploy"})
+		return password, deployMock, []types.ScaffoldStep{
+			newConfiguredStep(password, config.StepConfig{Name: "env.read", ID: "password", Key: "DB_PASSWORD", StoreAs: "DbPassword"}),
+			deploy,
+		}
+	}
+
+	password, deployMock, steps := newSteps()
+	deployMock.runError = assert.AnError
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	require.Error(t, executor.Execute())
+	assert.True(t, password.runCalled)
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.NotContains(t, state.ScaffoldJournal.Vars, "DbPassword")
+	data, err := os.ReadFile(filepath.Join(tmpDir, config.LocalStateFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+
+	for _, resume := range []ResumeOptions{{Resume: true}, {From: "deploy"}} {
+		ctx := &types.ScaffoldContext{}
+		password, _, steps = newSteps()
+		executor = NewStepExecutor(steps, ctx, types.StepOptions{Quiet: true})
+		executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), resume)
+		require.NoError(t, executor.Execute())
+
+		assert.True(t, password.runCalled, "the value is read again")
+		assert.Equal(t, "s3cret", ctx.GetVar("DbPassword"))
+	}
+}
+
+func TestStepExecutor_Journal_SecretPromptIsNotJournaled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	newStep := func() (*varStep, []types.ScaffoldStep) {
+		token := &varStep{mockStep: mockStep{name: "prompt.input", conditionResult: true}, key: "api_token", value: "s3cret"}
+		return token, []types.ScaffoldStep{
+			newConfiguredStep(token, config.StepConfig{Name: "prompt.input", ID: "token", Message: "API token", StoreAs: "api_token", Secret: true}),
+		}
+	}
+
+	_, steps := newStep()
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	require.NoError(t, executor.Execute())
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.NotContains(t, state.ScaffoldJournal.Vars, "api_token")
+
+	token, steps := newStep()
+	executor = NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), ResumeOptions{Resume: true})
+	require.NoError(t, executor.Execute())
+	assert.True(t, token.runCalled, "the secret is asked for again")
+}
+
+func TestStepExecutor_Journal_From(t *testing.T) {
+	first := &mockStep{name: "first", conditionResult: true}
+	second := &mockStep{name: "second", conditionResult: true}
+	thirdMock, third := journaledStep("third", "third", config.StepConfig{})
+
+	t.Run("by generated key", func(t *testing.T) {
+		executor := NewStepExecutor([]types.ScaffoldStep{first, second, third}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+		executor.SetJournal(NewJournal(t.TempDir(), nil, false), ResumeOptions{From: "second#2"})
+
+		require.NoError(t, executor.Execute())
+		assert.False(t, first.runCalled)
+		assert.True(t, second.runCalled)
+		assert.True(t, thirdMock.runCalled)
+	})
+
+	t.Run("unknown step", func(t *testing.T) {
+		executor := NewStepExecutor([]types.ScaffoldStep{first, second, third}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+		executor.SetJournal(NewJournal(t.TempDir(), nil, false), ResumeOptions{From: "missing"})
+
+		err := executor.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown step "missing" (available: first#1, second#2, third)`)
+	})
+}
+
+func TestJournal_NoPersist(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, step := journaledStep("bash.run", "echo", config.StepConfig{Command: "echo"})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	journal := NewJournal(tmpDir, nil, false)
+	executor.SetJournal(journal, ResumeOptions{})
+	require.NoError(t, executor.Execute())
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, config.LocalStateFile))
+	assert.Len(t, journal.Current().Steps, 1)
+}
+
+func TestIntegration_RunScaffoldResume(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", ID: "count", Command: "echo run >> count.txt"},
+				{Name: "bash.run", ID: "gate", Command: "test -f gate.txt"},
+			},
+		},
+	}
+	manager := NewScaffoldManager()
+
+	err := manager.RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true})
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "gate.txt"), nil, 0644))
+	err = manager.RunScaffoldWithOptions(context.Background(), tmpDir, "test", "myrepo", "myapp", "", cfg, RunOptions{Quiet: true, Resume: ResumeOptions{Resume: true}})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "count.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(content), "the succeeded step should not run again")
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, state.DbSuffix)
+	assert.Equal(t, config.JournalSucceeded, state.ScaffoldJournal.Status)
+}
@@ -0,0 +1,288 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogsDir is the directory inside a worktree that holds the output of
+	// scaffold runs, one subdirectory per run.
+	LogsDir = ".anvil/logs"
+
+	// keepLogRuns is the number of runs whose logs are kept per worktree.
+	keepLogRuns = 10
+
+	// failureTailLines is the number of output lines included in the error
+	// of a failed step.
+	failureTailLines = 20
+
+	// maxCapturedOutput bounds the output of a single attempt that is kept
+	// in memory for retry_on matching and the failure tail.
+	maxCapturedOutput = 64 * 1024
+
+	runIDLayout = "20060102-150405"
+)
+
+// unsafeFileChars matches characters that are replaced in log file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RunLog stores the output of every step of a scaffold run in
+// .anvil/logs/<run-id>/ inside the worktree.
+type RunLog struct {
+	worktreePath string
+	id           string
+}
+
+// NewRunLog creates the log of a new run in worktreePath and removes the
+// logs of older runs beyond the retention limit.
+func NewRunLog(worktreePath string, started time.Time) (*RunLog, error) {
+	base := filepath.Join(worktreePath, LogsDir)
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	// Keep logs out of git without requiring a .gitignore entry. The ignore
+	// file lives in the logs directory so committed files elsewhere in
+	// .anvil/, such as presets, stay tracked.
+	ignoreFile := filepath.Join(base, ".gitignore")
+	if _, err := os.Stat(ignoreFile); os.IsNotExist(err) {
+		if err := os.WriteFile(ignoreFile, []byte("*\n"), 0644); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", ignoreFile, err)
+		}
+	}
+
+	id := started.Format(runIDLayout)
+	for n := 2; ; n++ {
+		err := os.Mkdir(filepath.Join(base, id), 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("creating log directory: %w", err)
+		}
+		id = fmt.Sprintf("%s-%d", started.Format(runIDLayout), n)
+	}
+
+	log := &RunLog{worktreePath: worktreePath, id: id}
+	if err := pruneRunLogs(worktreePath, keepLogRuns); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// ID returns the run id, which is also the name of the run's log directory.
+func (l *RunLog) ID() string {
+	return l.id
+}
+
+// Dir returns the absolute path of the run's log directory.
+func (l *RunLog) Dir() string {
+	return filepath.Join(l.worktreePath, LogsDir, l.id)
+}
+
+// stepPath returns the path of a step's log file relative to the worktree.
+func (l *RunLog) stepPath(index int, key string) string {
+	return filepath.Join(LogsDir, l.id, fmt.Sprintf("%02d-%s.log", index+1, logFileName(key)))
+}
+
+// logFileName turns a step key into a file name without extension.
+func logFileName(key string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(key, "-"), "-")
+}
+
+// openStep creates the log of a step.
+func (l *RunLog) openStep(index int, key string) (*stepLog, error) {
+	path := l.stepPath(index, key)
+	file, err := os.OpenFile(filepath.Join(l.worktreePath, path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("creating step log: %w", err)
+	}
+	return &stepLog{file: file, path: path}, nil
+}
+
+// stepLog collects the output of a step. The output is appended to the
+// step's log file, if any, and the output of the current attempt is kept
+// in memory for retry_on matching and error messages.
+type stepLog struct {
+	file *os.File
+	path string // Relative to the worktree, "" without a log file
+
+	mu       sync.Mutex
+	captured []byte
+}
+
+func (l *stepLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.captured = append(l.captured, p...)
+	if over := len(l.captured) - maxCapturedOutput; over > 0 {
+		l.captured = l.captured[over:]
+	}
+	if l.file != nil {
+		// A failing log file must not fail the step
+		_, _ = l.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// startAttempt writes a header for the attempt and resets the captured output.
+func (l *stepLog) startAttempt(title string) {
+	l.mu.Lock()
+	l.captured = nil
+	l.mu.Unlock()
+	l.note(fmt.Sprintf("=== %s (%s) ===", title, time.Now().Format(time.RFC3339)))
+}
+
+// finishAttempt records the outcome of an attempt.
+func (l *stepLog) finishAttempt(duration time.Duration, err error) {
+	if err != nil {
+		l.note(fmt.Sprintf("--- failed after %s: %v ---", duration.Round(time.Millisecond), err))
+		return
+	}
+	l.note(fmt.Sprintf("--- succeeded in %s ---", duration.Round(time.Millisecond)))
+}
+
+func (l *stepLog) note(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		_, _ = fmt.Fprintln(l.file, line)
+	}
+}
+
+// output returns the captured output of the current attempt.
+func (l *stepLog) output() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return string(l.captured)
+}
+
+// tail returns the last n non-empty lines of the current attempt.
+func (l *stepLog) tail(n int) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(l.output(), func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+func (l *stepLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// withOutputTail appends the last lines of a failed step's output and the
+// path of its log to err.
+func withOutputTail(err error, log *stepLog, worktreePath string) error {
+	lines := log.tail(failureTailLines)
+	var b strings.Builder
+	if len(lines) > 0 {
+		fmt.Fprintf(&b, "\n\nLast %d lines of output:\n  %s", len(lines), strings.Join(lines, "\n  "))
+	}
+	if log.path != "" {
+		fmt.Fprintf(&b, "\n\nFull log: %s", filepath.Join(worktreePath, log.path))
+	}
+	if b.Len() == 0 {
+		return err
+	}
+	return fmt.Errorf("%w%s", err, b.String())
+}
+
+// RunLogInfo describes the logs of a past scaffold run.
+type RunLogInfo struct {
+	ID    string
+	Dir   string
+	Steps []string // Log file names in execution order
+}
+
+// StepLog returns the path of the log file of the step with the given key
+// (see the journal), and whether the run has one.
+func (r RunLogInfo) StepLog(key string) (string, bool) {
+	want := logFileName(key) + ".log"
+	for _, name := range r.Steps {
+		// Strip the position prefix
+		if _, rest, ok := strings.Cut(name, "-"); ok && rest == want {
+			return filepath.Join(r.Dir, name), true
+		}
+	}
+	return "", false
+}
+
+// ListRunLogs returns the logged runs of a worktree, most recent first.
+func ListRunLogs(worktreePath string) ([]RunLogInfo, error) {
+	base := filepath.Join(worktreePath, LogsDir)
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading log directory: %w", err)
+	}
+
+	var runs []RunLogInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(base, entry.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading log directory: %w", err)
+		}
+		run := RunLogInfo{ID: entry.Name(), Dir: dir}
+		for _, file := range files {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), ".log") {
+				run.Steps = append(run.Steps, file.Name())
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	// Run ids sort chronologically
+	sort.Slice(runs, func(i, j int) bool {
+		return runIDLess(runs[j].ID, runs[i].ID)
+	})
+	return runs, nil
+}
+
+// runIDLess orders run ids chronologically, including the numeric suffix
+// of runs started within the same second.
+func runIDLess(a, b string) bool {
+	if len(a) != len(b) {
+		aBase, bBase := a[:min(len(a), len(runIDLayout))], b[:min(len(b), len(runIDLayout))]
+		if aBase != bBase {
+			return aBase < bBase
+		}
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// pruneRunLogs removes the logs of all but the most recent keep runs.
+func pruneRunLogs(worktreePath string, keep int) error {
+	runs, err := ListRunLogs(worktreePath)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs[min(keep, len(runs)):] {
+		if err := os.RemoveAll(run.Dir); err != nil {
+			return fmt.Errorf("removing old logs: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	anvil_exec "github.com/naoray/anvil/internal/exec"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// outputStep writes output to the streamed writer and fails with err.
+type outputStep struct {
+	mockStep
+	output string
+}
+
+func (s *outputStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	s.runCalled = true
+	if w := anvil_exec.OutputWriter(opts.Context()); w != nil {
+		_, _ = io.WriteString(w, s.output)
+	}
+	return s.runError
+}
+
+func TestNewRunLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	started := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	first, err := NewRunLog(tmpDir, started)
+	require.NoError(t, err)
+	second, err := NewRunLog(tmpDir, started)
+	require.NoError(t, err)
+
+	assert.Equal(t, "20250102-150405", first.ID())
+	assert.Equal(t, "20250102-150405-2", second.ID())
+	assert.DirExists(t, second.Dir())
+
+	ignore, err := os.ReadFile(filepath.Join(tmpDir, ".anvil", "logs", ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(ignore))
+	assert.NoFileExists(t, filepath.Join(tmpDir, ".anvil", ".gitignore"))
+
+	runs, err := ListRunLogs(tmpDir)
+	require.NoError(t, err)
+	assert.Len(t, runs, 2)
+}
+
+func TestNewRunLog_PrunesOldRuns(t *testing.T) {
+	tmpDir := t.TempDir()
+	started := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	for i := 0; i < keepLogRuns+2; i++ {
+		_, err := NewRunLog(tmpDir, started.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	runs, err := ListRunLogs(tmpDir)
+	require.NoError(t, err)
+	require.Len(t, runs, keepLogRuns)
+	assert.Equal(t, "20250102-151505", runs[0].ID)
+	assert.Equal(t, "20250102-150605", runs[len(runs)-1].ID)
+}
+
+func TestListRunLogs_OrdersSameSecondRuns(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, id := range []string{"20250102-150405", "20250102-150405-10", "20250102-150405-2", "20250101-090000"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, LogsDir, id), 0755))
+	}
+
+	runs, err := ListRunLogs(tmpDir)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	assert.Equal(t, []string{"20250102-150405-10", "20250102-150405-2", "20250102-150405", "20250101-090000"}, ids)
+}
+
+func TestListRunLogs_NoLogs(t *testing.T) {
+	runs, err := ListRunLogs(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestRunLogInfo_StepLog(t *testing.T) {
+	run := RunLogInfo{Dir: "/logs", Steps: []string{"01-php.composer-install-1.log", "02-install-1.log"}}
+
+	path, ok := run.StepLog("install#1")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join("/logs", "02-install-1.log"), path)
+
+	_, ok = run.StepLog("composer#1")
+	assert.False(t, ok)
+}
+
+func TestStepLog_Tail(t *testing.T) {
+	log := &stepLog{}
+	log.startAttempt("first")
+	_, _ = log.Write([]byte("old\n"))
+	log.startAttempt("second")
+	_, _ = log.Write([]byte("one\r\ntwo\n\nthree\nfour"))
+
+	assert.Equal(t, []string{"three", "four"}, log.tail(2))
+	assert.Equal(t, []string{"one", "two", "three", "four"}, log.tail(10))
+}
+
+func TestStepExecutor_WritesStepLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	runLog, err := NewRunLog(tmpDir, time.Now())
+	require.NoError(t, err)
+
+	var output string
+	for i := 1; i <= 30; i++ {
+		output += fmt.Sprintf("line %d\n", i)
+	}
+	step := &outputStep{
+		mockStep: mockStep{name: "bash.run", conditionResult: true, runError: fmt.Errorf("bash.run failed: exit status 1")},
+		output:   output,
+	}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{WorktreePath: tmpDir}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	executor.SetRunLog(runLog)
+
+	err = executor.Execute()
+	require.Error(t, err)
+
+	logPath := filepath.Join(runLog.Dir(), "01-bash.run-1.log")
+	assert.Contains(t, err.Error(), "Last 20 lines of output:\n  line 11\n")
+	assert.Contains(t, err.Error(), "  line 30\n\nFull log: "+logPath)
+	assert.NotContains(t, err.Error(), "line 10\n")
+
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "=== bash.run (")
+	assert.Contains(t, string(content), "line 1\n")
+	assert.Contains(t, string(content), "--- failed after ")
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, runLog.ID(), state.ScaffoldJournal.RunID)
+	entry, ok := state.ScaffoldJournal.Entry("bash.run#1")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(LogsDir, runLog.ID(), "01-bash.run-1.log"), entry.Log)
+}
+
+func TestStepExecutor_Retry_MatchesStreamedOutput(t *testing.T) {
+	step := &outputStep{
+		mockStep: mockStep{name: "npm", conditionResult: true, runError: fmt.Errorf("npm failed: exit status 1")},
+		output:   "npm ERR! ETIMEDOUT\n",
+	}
+	configured := newConfiguredStep(step, config.StepConfig{
+		Name:       "npm",
+		Retries:    1,
+		RetryDelay: "1ms",
+		RetryOn:    &config.RetryOn{Output: "ETIMEDOUT"},
+	})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{configured}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed after 2 attempts")
+	assert.Contains(t, err.Error(), "Last 1 lines of output:\n  npm ERR! ETIMEDOUT")
+}
@@ -1,12 +1,15 @@
 package scaffold
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/scaffold/steps"
@@ -19,8 +22,24 @@ type ScaffoldManager struct {
 	presets     map[string]Preset
 	presetOrder []string
 	registry    StepRegistry
+	concurrency int
+	locator     ConfigLocator
+	// projectName and mainWorktree are exposed to templates as
+	// .ProjectName and .MainWorktree.
+	projectName  string
+	mainWorktree string
 }
 
+// ConfigLocator finds the anvil.yaml file and line a configuration value
+// was read from. *config.ResolvedConfig implements it.
+type ConfigLocator interface {
+	Locate(path string) (file string, line int, ok bool)
+}
+
+// maxParallelSteps bounds how many independent steps run at the same time
+// when parallel dependency execution is enabled.
+const maxParallelSteps = 4
+
 // StepRegistry defines the interface for step creation.
 // This abstraction allows for dependency injection and testing.
 type StepRegistry interface {
@@ -51,7 +70,46 @@ func NewScaffoldManagerWithRegistry(registry StepRegistry) *ScaffoldManager {
 		presets:     make(map[string]Preset),
 		presetOrder: make([]string, 0),
 		registry:    registry,
+		concurrency: 1,
+	}
+}
+
+// SetParallelDependencies enables or disables concurrent execution of
+// independent scaffold steps (see depends_on). Disabled by default.
+func (m *ScaffoldManager) SetParallelDependencies(enabled bool) {
+	if enabled {
+		m.concurrency = maxParallelSteps
+	} else {
+		m.concurrency = 1
+	}
+}
+
+// SetConfigLocator sets where invalid step and pre-flight conditions are
+// looked up, so errors point at the anvil.yaml line.
+func (m *ScaffoldManager) SetConfigLocator(locator ConfigLocator) {
+	m.locator = locator
+}
+
+// SetProject sets the project name and the path of the default branch
+// worktree that templates can refer to.
+func (m *ScaffoldManager) SetProject(name, mainWorktree string) {
+	m.projectName = name
+	m.mainWorktree = mainWorktree
+}
+
+// locateCondition prefixes the path of a condition error with the
+// configuration path of the condition and adds its anvil.yaml location.
+func (m *ScaffoldManager) locateCondition(condErr *types.ConditionError, path string) *types.ConditionError {
+	if condErr.Path != "" {
+		path += "." + condErr.Path
+	}
+	condErr.Path = path
+	if m.locator != nil {
+		if file, line, ok := m.locator.Locate(path); ok {
+			condErr.File, condErr.Line = file, line
+		}
 	}
+	return condErr
 }
 
 // globalStepRegistryAdapter adapts the global step functions to the StepRegistry interface.
@@ -85,75 +143,81 @@ func (m *ScaffoldManager) DetectPreset(path string) string {
 	return ""
 }
 
+// GetStepsForWorktree returns the scaffold steps of a worktree: the steps
+// of its preset with the configured steps merged in (see
+// mergeScaffoldSteps), or only the configured steps with scaffold.override,
+// narrowed down by the profile of cfg.
 func (m *ScaffoldManager) GetStepsForWorktree(cfg *config.Config, worktreePath, branch string) ([]types.ScaffoldStep, error) {
-	var stepsList []types.ScaffoldStep
-
-	presetName := cfg.Preset
-	if presetName == "" {
-		presetName = m.DetectPreset(worktreePath)
-	}
-
-	if preset, ok := m.GetPreset(presetName); ok {
-		for _, stepConfig := range preset.DefaultSteps() {
-			step, err := m.registry.Create(stepConfig.Name, stepConfig)
-			if err != nil {
-				return nil, fmt.Errorf("creating step %q: %w", stepConfig.Name, err)
-			}
-			stepsList = append(stepsList, step)
+	var presetSteps []config.StepConfig
+	if !cfg.Scaffold.Override {
+		if preset, ok := m.GetPreset(m.presetFor(cfg, worktreePath)); ok {
+			presetSteps = preset.DefaultSteps()
 		}
 	}
 
-	if cfg.Scaffold.Override {
-		overrideSteps, err := m.stepsFromConfig(cfg.Scaffold.Steps)
-		if err != nil {
-			return nil, err
+	stepConfigs, indexes, err := mergeScaffoldSteps(presetSteps, cfg.Scaffold)
+	if err != nil {
+		return nil, err
+	}
+	stepsList := make([]types.ScaffoldStep, 0, len(stepConfigs))
+	for i, stepConfig := range stepConfigs {
+		var step types.ScaffoldStep
+		if indexes[i] < 0 {
+			step, err = m.createStep(stepConfig)
+		} else {
+			step, err = m.createConfiguredStep(stepConfig, fmt.Sprintf("scaffold.steps[%d]", indexes[i]))
 		}
-		stepsList = overrideSteps
-	} else {
-		additionalSteps, err := m.stepsFromConfig(cfg.Scaffold.Steps)
 		if err != nil {
 			return nil, err
 		}
-		stepsList = append(stepsList, additionalSteps...)
+		stepsList = append(stepsList, step)
 	}
 
-	return stepsList, nil
+	return m.applyProfile(cfg, stepsList)
 }
 
+// GetCleanupSteps returns the cleanup steps of a worktree: the cleanup
+// steps of its preset with the configured cleanup steps merged in, or only
+// the configured cleanup steps with cleanup.override.
 func (m *ScaffoldManager) GetCleanupSteps(cfg *config.Config, worktreePath, branch string) ([]types.ScaffoldStep, error) {
-	var stepsList []types.ScaffoldStep
-
-	presetName := cfg.Preset
-	if presetName == "" {
-		presetName = m.DetectPreset(worktreePath)
-	}
-
-	if preset, ok := m.GetPreset(presetName); ok {
-		for _, cleanupConfig := range preset.CleanupSteps() {
-			stepConfig := m.cleanupConfigToStepConfig(cleanupConfig)
-			step, err := m.registry.Create(cleanupConfig.Name, stepConfig)
-			if err != nil {
-				return nil, fmt.Errorf("creating cleanup step %q: %w", cleanupConfig.Name, err)
-			}
-			stepsList = append(stepsList, step)
+	var presetSteps []config.CleanupStep
+	if !cfg.Cleanup.Override {
+		if preset, ok := m.GetPreset(m.presetFor(cfg, worktreePath)); ok {
+			presetSteps = preset.CleanupSteps()
 		}
 	}
 
-	for _, cleanupConfig := range cfg.Cleanup.Steps {
+	cleanupSteps, err := mergeCleanupSteps(presetSteps, cfg.Cleanup)
+	if err != nil {
+		return nil, err
+	}
+	stepsList := make([]types.ScaffoldStep, 0, len(cleanupSteps))
+	for _, cleanupConfig := range cleanupSteps {
 		stepConfig := m.cleanupConfigToStepConfig(cleanupConfig)
 		step, err := m.registry.Create(cleanupConfig.Name, stepConfig)
 		if err != nil {
 			return nil, fmt.Errorf("creating cleanup step %q: %w", cleanupConfig.Name, err)
 		}
-		stepsList = append(stepsList, step)
+		stepsList = append(stepsList, newConfiguredStep(step, stepConfig))
 	}
 
 	return stepsList, nil
 }
 
+// presetFor returns the configured preset, or the preset detected in the
+// worktree.
+func (m *ScaffoldManager) presetFor(cfg *config.Config, worktreePath string) string {
+	if cfg.Preset != "" {
+		return cfg.Preset
+	}
+	return m.DetectPreset(worktreePath)
+}
+
 func (m *ScaffoldManager) cleanupConfigToStepConfig(cleanupConfig config.CleanupStep) config.StepConfig {
 	stepConfig := config.StepConfig{
 		Name: cleanupConfig.Name,
+		ID:   cleanupConfig.ID,
+		Tags: cleanupConfig.Tags,
 		Args: nil,
 	}
 	if cleanupConfig.Name == "herd" {
@@ -169,13 +233,15 @@ func (m *ScaffoldManager) cleanupConfigToStepConfig(cleanupConfig config.Cleanup
 	return stepConfig
 }
 
-func (m *ScaffoldManager) stepsFromConfig(stepConfigs []config.StepConfig) ([]types.ScaffoldStep, error) {
+// stepsFromConfig creates the steps configured at path, e.g.
+// "scaffold.steps", which locates errors in anvil.yaml.
+func (m *ScaffoldManager) stepsFromConfig(stepConfigs []config.StepConfig, path string) ([]types.ScaffoldStep, error) {
 	stepsList := make([]types.ScaffoldStep, 0, len(stepConfigs))
 
-	for _, cfg := range stepConfigs {
-		step, err := m.registry.Create(cfg.Name, cfg)
+	for i, cfg := range stepConfigs {
+		step, err := m.createConfiguredStep(cfg, fmt.Sprintf("%s[%d]", path, i))
 		if err != nil {
-			return nil, fmt.Errorf("creating step %q: %w", cfg.Name, err)
+			return nil, err
 		}
 		stepsList = append(stepsList, step)
 	}
@@ -183,8 +249,80 @@ func (m *ScaffoldManager) stepsFromConfig(stepConfigs []config.StepConfig) ([]ty
 	return stepsList, nil
 }
 
+// createConfiguredStep creates a step configured in anvil.yaml at path,
+// e.g. "scaffold.steps[2]", and adds the location of invalid conditions.
+func (m *ScaffoldManager) createConfiguredStep(cfg config.StepConfig, path string) (types.ScaffoldStep, error) {
+	step, err := m.createStep(cfg)
+	if err != nil {
+		var condErr *types.ConditionError
+		if errors.As(err, &condErr) {
+			condErr = m.locateCondition(condErr, path+".condition")
+			return nil, fmt.Errorf("creating step %q: invalid condition: %w", cfg.Name, condErr)
+		}
+		return nil, err
+	}
+	return step, nil
+}
+
+// createStep creates a step from its configuration and validates the
+// options handled by the executor rather than by the step itself.
+func (m *ScaffoldManager) createStep(cfg config.StepConfig) (types.ScaffoldStep, error) {
+	step, err := m.registry.Create(cfg.Name, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating step %q: %w", cfg.Name, err)
+	}
+	if _, err := parseTimeout(cfg.Timeout); err != nil {
+		return nil, fmt.Errorf("creating step %q: %w", cfg.Name, err)
+	}
+	if _, err := newRetryPolicy(cfg); err != nil {
+		return nil, fmt.Errorf("creating step %q: %w", cfg.Name, err)
+	}
+	return newConfiguredStep(step, cfg), nil
+}
+
+// RunOptions controls a scaffold run.
+type RunOptions struct {
+	DryRun  bool
+	Verbose bool
+	Quiet   bool
+	Resume  ResumeOptions
+	// Selection restricts the run to some of its steps.
+	Selection StepSelection
+	// Timeout bounds the duration of the run and overrides scaffold.timeout
+	// when non-zero.
+	Timeout time.Duration
+	// Interactive lets prompt steps ask the user for input.
+	Interactive bool
+	// Vars holds the values passed with --var, by name.
+	Vars map[string]string
+}
+
 func (m *ScaffoldManager) RunScaffold(worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, dryRun, verbose, quiet bool) error {
+	return m.RunScaffoldWithOptions(context.Background(), worktreePath, branch, repoName, siteName, preset, cfg, RunOptions{
+		DryRun:  dryRun,
+		Verbose: verbose,
+		Quiet:   quiet,
+	})
+}
+
+// RunScaffoldWithOptions runs the scaffold steps for a worktree and records
+// their outcome in the worktree's scaffold journal. Cancelling runCtx stops
+// the running steps and kills the commands they started.
+func (m *ScaffoldManager) RunScaffoldWithOptions(runCtx context.Context, worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, runOpts RunOptions) error {
+	dryRun, verbose, quiet := runOpts.DryRun, runOpts.Verbose, runOpts.Quiet
 	ctx := m.newScaffoldContext(worktreePath, branch, repoName, siteName, preset)
+	ctx.DefaultBranch = cfg.DefaultBranch
+	ctx.MainSiteName = cfg.SiteName
+	ctx.Inputs = runOpts.Vars
+
+	timeout := runOpts.Timeout
+	if timeout == 0 {
+		var err error
+		timeout, err = parseTimeout(cfg.Scaffold.Timeout)
+		if err != nil {
+			return fmt.Errorf("scaffold.timeout: %w", err)
+		}
+	}
 
 	// Run pre-flight checks with spinner
 	if !quiet {
@@ -222,40 +360,122 @@ func (m *ScaffoldManager) RunScaffold(worktreePath, branch, repoName, siteName,
 	} else {
 		ctx.SetDbSuffix(localState.DbSuffix)
 	}
+	ctx.Ports = localState.Ports
 
 	stepsList, err := m.GetStepsForWorktree(cfg, worktreePath, branch)
 	if err != nil {
 		return fmt.Errorf("getting scaffold steps: %w", err)
 	}
 
+	if err := checkVarOverrides(runOpts.Vars, cfg.Vars, stepsList); err != nil {
+		return err
+	}
+	vars, err := applyVars(&ctx, cfg.Vars, runOpts.Vars, localState.Vars)
+	if err != nil {
+		return err
+	}
+	if !dryRun && len(vars) > 0 {
+		if err := config.WriteLocalState(worktreePath, config.LocalState{Vars: vars}); err != nil {
+			return fmt.Errorf("writing variables to local state: %w", err)
+		}
+	}
+	// Later runs of the worktree select the same steps unless told otherwise
+	if !dryRun {
+		if err := config.WriteLocalState(worktreePath, config.LocalState{Profile: &cfg.Profile}); err != nil {
+			return fmt.Errorf("writing profile to local state: %w", err)
+		}
+	}
+
 	opts := m.stepOptionsFromFlags(dryRun, verbose, quiet)
+	opts.Interactive = runOpts.Interactive
+
+	journal := NewJournal(worktreePath, localState.ScaffoldJournal, !dryRun)
+	if runOpts.Resume.Resume && journal.Previous() == nil && !quiet {
+		ui.PrintInfo("No previous scaffold run recorded, running all steps")
+	}
 
 	executor := NewStepExecutor(stepsList, &ctx, opts)
-	if err := executor.Execute(); err != nil {
-		return err
+	executor.SetConcurrency(m.concurrency)
+	executor.SetJournal(journal, runOpts.Resume)
+	executor.SetSelection(runOpts.Selection)
+	if !dryRun {
+		runLog, err := NewRunLog(worktreePath, time.Now())
+		switch {
+		case err == nil:
+			executor.SetRunLog(runLog)
+		case !quiet:
+			ui.PrintWarning(fmt.Sprintf("Step output will not be logged: %v", err))
+		}
 	}
+	executor.SetTimeout(timeout)
+	err = executor.ExecuteContext(runCtx)
 
-	return nil
+	// Keep ports allocated by templates for later runs, even if a step failed
+	if ports := ctx.SnapshotPorts(); len(ports) > 0 && !dryRun {
+		if writeErr := config.WriteLocalState(worktreePath, config.LocalState{Ports: ports}); writeErr != nil && err == nil {
+			return fmt.Errorf("writing ports to local state: %w", writeErr)
+		}
+	}
+	return err
 }
 
-func (m *ScaffoldManager) RunCleanup(worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, dryRun, verbose, quiet bool) error {
+// RunCleanup runs the cleanup steps for a worktree that is being removed.
+// Cancelling runCtx stops the running steps.
+func (m *ScaffoldManager) RunCleanup(runCtx context.Context, worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, dryRun, verbose, quiet bool) error {
+	return m.RunCleanupWithOptions(runCtx, worktreePath, branch, repoName, siteName, preset, cfg, RunOptions{
+		DryRun:  dryRun,
+		Verbose: verbose,
+		Quiet:   quiet,
+	})
+}
+
+// RunCleanupWithOptions runs the cleanup steps selected by runOpts. Resume
+// options and timeouts apply to scaffold runs only and are ignored.
+func (m *ScaffoldManager) RunCleanupWithOptions(runCtx context.Context, worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, runOpts RunOptions) error {
 	ctx := m.newScaffoldContext(worktreePath, branch, repoName, siteName, preset)
+	ctx.DefaultBranch = cfg.DefaultBranch
+	ctx.MainSiteName = cfg.SiteName
+	ctx.Inputs = runOpts.Vars
+	localState, err := config.ReadLocalState(worktreePath)
+	if err != nil {
+		localState = &config.LocalState{}
+	}
+	ctx.Ports = localState.Ports
+	// Cleanup sees the variables the worktree was scaffolded with
+	if _, err := applyVars(&ctx, cfg.Vars, runOpts.Vars, localState.Vars); err != nil {
+		return err
+	}
 
 	stepsList, err := m.GetCleanupSteps(cfg, worktreePath, branch)
 	if err != nil {
 		return fmt.Errorf("getting cleanup steps: %w", err)
 	}
 
-	opts := m.stepOptionsFromFlags(dryRun, verbose, quiet)
+	opts := m.stepOptionsFromFlags(runOpts.DryRun, runOpts.Verbose, runOpts.Quiet)
+	opts.Interactive = runOpts.Interactive
 
 	executor := NewStepExecutor(stepsList, &ctx, opts)
-	if err := executor.Execute(); err != nil {
+	executor.SetSelection(runOpts.Selection)
+	if err := executor.ExecuteContext(runCtx); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// applyVars resolves the project variables and stores them in ctx, where
+// templates and steps see them like variables stored by steps.
+func applyVars(ctx *types.ScaffoldContext, defs map[string]config.VarConfig, overrides, saved map[string]string) (map[string]string, error) {
+	vars, err := resolveVars(defs, overrides, saved, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range vars {
+		ctx.SetVar(name, value)
+	}
+	return vars, nil
+}
+
 func (m *ScaffoldManager) newScaffoldContext(worktreePath, branch, repoName, siteName, preset string) types.ScaffoldContext {
 	path := filepath.Base(worktreePath)
 	repoPath := filepath.Base(filepath.Dir(worktreePath))
@@ -269,6 +489,8 @@ func (m *ScaffoldManager) newScaffoldContext(worktreePath, branch, repoName, sit
 		Path:         path,
 		RepoPath:     repoPath,
 		Vars:         make(map[string]string),
+		ProjectName:  m.projectName,
+		MainWorktree: m.mainWorktree,
 	}
 }
 
@@ -288,8 +510,17 @@ func (m *ScaffoldManager) runPreFlightChecks(ctx *types.ScaffoldContext, cfg *co
 		return nil
 	}
 
+	condition, err := types.CompileCondition(cfg.PreFlight.Condition)
+	if err != nil {
+		var condErr *types.ConditionError
+		if errors.As(err, &condErr) {
+			err = m.locateCondition(condErr, "scaffold.pre_flight.condition")
+		}
+		return fmt.Errorf("invalid pre-flight condition: %w", err)
+	}
+
 	// Evaluate the condition
-	result, err := ctx.EvaluateCondition(cfg.PreFlight.Condition)
+	result, err := condition.Evaluate(ctx)
 	if err != nil {
 		return fmt.Errorf("pre-flight check error: %w", err)
 	}
@@ -399,6 +630,8 @@ func collectPreFlightValuesFromCondition(condition any, values *preFlightValues)
 				values.commands = append(values.commands, extractStringValues(value, "command")...)
 			case "file_exists":
 				values.files = append(values.files, extractStringValues(value, "file")...)
+			case "any", "all":
+				collectPreFlightValuesFromCondition(value, values)
 			}
 		}
 	case []any:
@@ -0,0 +1,289 @@
+package scaffold
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+// mergedStep is a step of the merged preset and configured steps.
+type mergedStep struct {
+	id       string
+	preset   int           // Index in the preset steps, or -1
+	config   int           // Index in the configured steps, or -1
+	replaced *mergedStep   // Step a configured step replaces, if any
+	before   []*mergedStep // Configured steps inserted before the step
+}
+
+// mergeSteps merges configured steps into preset steps. The preset steps
+// listed in disable are left out. Configured steps are inserted before or
+// after the step named by their patch, replace it, or are appended in
+// order. A replacing step without an id takes the id of the step it
+// replaces. section is the configuration section for errors, e.g.
+// "scaffold".
+func mergeSteps(presetIDs, configIDs []string, patches []config.StepPatch, disable []string, section string) ([]*mergedStep, error) {
+	disabled := make(map[string]bool, len(disable))
+	for _, id := range disable {
+		if id == "" || !slices.Contains(presetIDs, id) {
+			return nil, fmt.Errorf("%s.disable: unknown preset step id %q (available: %s)", section, id, availableIDs(presetIDs))
+		}
+		disabled[id] = true
+	}
+
+	merged := make([]*mergedStep, 0, len(presetIDs)+len(configIDs))
+	for i, id := range presetIDs {
+		if id == "" || !disabled[id] {
+			merged = append(merged, &mergedStep{id: id, preset: i, config: -1})
+		}
+	}
+
+	// Steps inserted after the same step keep their configured order
+	lastAfter := make(map[string]*mergedStep)
+	for i, patch := range patches {
+		step := &mergedStep{id: configIDs[i], preset: -1, config: i}
+		op, target, err := patchOp(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%s.steps[%d]: %w", section, i, err)
+		}
+		if op == "" {
+			merged = append(merged, step)
+			continue
+		}
+
+		pos := slices.IndexFunc(merged, func(s *mergedStep) bool { return s.id == target })
+		if pos < 0 {
+			ids := make([]string, len(merged))
+			for j, s := range merged {
+				ids[j] = s.id
+			}
+			return nil, fmt.Errorf("%s.steps[%d]: %s: unknown step id %q (available: %s)", section, i, op, target, availableIDs(ids))
+		}
+		switch op {
+		case "before":
+			merged[pos].before = append(merged[pos].before, step)
+			merged = slices.Insert(merged, pos, step)
+		case "after":
+			if last := slices.Index(merged, lastAfter[target]); last >= 0 {
+				pos = last
+			}
+			merged = slices.Insert(merged, pos+1, step)
+			lastAfter[target] = step
+		case "replace":
+			if step.id == "" {
+				step.id = target
+			}
+			step.replaced = merged[pos]
+			step.before = merged[pos].before
+			merged[pos] = step
+		}
+	}
+	return merged, nil
+}
+
+// patchOp returns the operation of a patch and the id it names, or "" if
+// the step is not patched in.
+func patchOp(patch config.StepPatch) (op, target string, err error) {
+	set := 0
+	for _, candidate := range []struct{ op, target string }{
+		{"before", patch.Before},
+		{"after", patch.After},
+		{"replace", patch.Replace},
+	} {
+		if candidate.target != "" {
+			op, target = candidate.op, candidate.target
+			set++
+		}
+	}
+	if set > 1 {
+		return "", "", fmt.Errorf("only one of before, after and replace may be set")
+	}
+	return op, target, nil
+}
+
+// availableIDs lists the non-empty ids for error messages.
+func availableIDs(ids []string) string {
+	var available []string
+	for _, id := range ids {
+		if id != "" {
+			available = append(available, id)
+		}
+	}
+	if len(available) == 0 {
+		return "none"
+	}
+	return strings.Join(available, ", ")
+}
+
+// mergeScaffoldSteps merges the configured scaffold steps into the preset
+// steps, see mergeSteps. A replacing step also takes over the dependencies
+// of the step it replaces unless it declares its own, and steps that
+// depended on the replaced step depend on its replacement. Steps that
+// depended on a disabled step wait for its dependencies instead. A step
+// with depends_on also waits for the steps inserted before it, which get
+// an id if they have none. The second result holds the index of each step
+// in scaffold.Steps, or -1 for preset steps.
+func mergeScaffoldSteps(presetSteps []config.StepConfig, scaffold config.ScaffoldConfig) ([]config.StepConfig, []int, error) {
+	presetIDs := make([]string, len(presetSteps))
+	for i, step := range presetSteps {
+		presetIDs[i] = step.ID
+	}
+	configIDs := make([]string, len(scaffold.Steps))
+	patches := make([]config.StepPatch, len(scaffold.Steps))
+	for i, step := range scaffold.Steps {
+		configIDs[i], patches[i] = step.ID, step.StepPatch
+	}
+
+	merged, err := mergeSteps(presetIDs, configIDs, patches, scaffold.Disable, "scaffold")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stepConfig := func(step *mergedStep) config.StepConfig {
+		if step.preset >= 0 {
+			return presetSteps[step.preset]
+		}
+		return scaffold.Steps[step.config]
+	}
+	renamed := make(map[string]string)
+	for _, step := range merged {
+		if step.replaced != nil && step.replaced.id != "" && step.replaced.id != step.id {
+			renamed[step.replaced.id] = step.id
+		}
+	}
+	disabled := make(map[string]bool, len(scaffold.Disable))
+	for _, id := range scaffold.Disable {
+		disabled[id] = true
+	}
+	presetIndex := make(map[string]int, len(presetSteps))
+	for i, step := range presetSteps {
+		if step.ID != "" {
+			presetIndex[step.ID] = i
+		}
+	}
+
+	// resolveDep returns the ids a dependency stands for in the merged steps
+	var resolveDep func(dep string, visiting map[string]bool) []string
+	resolveDep = func(dep string, visiting map[string]bool) []string {
+		if newID, ok := renamed[dep]; ok {
+			return []string{newID}
+		}
+		if !disabled[dep] {
+			return []string{dep}
+		}
+		if visiting[dep] {
+			return nil // A cycle, reported when the graph is built
+		}
+		visiting[dep] = true
+		defer delete(visiting, dep)
+
+		i := presetIndex[dep]
+		deps := presetSteps[i].DependsOn
+		if deps == nil {
+			// Without depends_on the disabled step waited for every step before it
+			for _, step := range presetSteps[:i] {
+				if step.ID != "" {
+					deps = append(deps, step.ID)
+				}
+			}
+		}
+		var resolved []string
+		for _, d := range deps {
+			resolved = append(resolved, resolveDep(d, visiting)...)
+		}
+		return resolved
+	}
+
+	taken := make(map[string]bool, len(merged))
+	for _, step := range merged {
+		taken[step.id] = true
+	}
+	for _, step := range merged {
+		for _, inserted := range step.before {
+			if inserted.id != "" {
+				continue
+			}
+			id := "before-" + step.id
+			for n := 2; taken[id]; n++ {
+				id = fmt.Sprintf("before-%s-%d", step.id, n)
+			}
+			inserted.id, taken[id] = id, true
+		}
+	}
+
+	configs := make([]config.StepConfig, len(merged))
+	indexes := make([]int, len(merged))
+	for i, step := range merged {
+		cfg := stepConfig(step)
+		cfg.ID = step.id
+		cfg.StepPatch = config.StepPatch{}
+		if step.replaced != nil && cfg.DependsOn == nil {
+			cfg.DependsOn = stepConfig(step.replaced).DependsOn
+		}
+		if cfg.DependsOn != nil {
+			// Copy, as the slice may be shared with the preset
+			deps := make([]string, 0, len(cfg.DependsOn)+len(step.before))
+			for _, dep := range cfg.DependsOn {
+				for _, id := range resolveDep(dep, map[string]bool{}) {
+					if !slices.Contains(deps, id) {
+						deps = append(deps, id)
+					}
+				}
+			}
+			for _, inserted := range step.before {
+				if !slices.Contains(deps, inserted.id) {
+					deps = append(deps, inserted.id)
+				}
+			}
+			cfg.DependsOn = deps
+		}
+		configs[i], indexes[i] = cfg, step.config
+	}
+	return configs, indexes, nil
+}
+
+// mergeCleanupSteps merges the configured cleanup steps into the preset
+// cleanup steps, see mergeSteps.
+func mergeCleanupSteps(presetSteps []config.CleanupStep, cleanup config.CleanupConfig) ([]config.CleanupStep, error) {
+	presetIDs := make([]string, len(presetSteps))
+	for i, step := range presetSteps {
+		presetIDs[i] = step.ID
+	}
+	configIDs := make([]string, len(cleanup.Steps))
+	patches := make([]config.StepPatch, len(cleanup.Steps))
+	for i, step := range cleanup.Steps {
+		configIDs[i], patches[i] = step.ID, step.StepPatch
+	}
+
+	merged, err := mergeSteps(presetIDs, configIDs, patches, cleanup.Disable, "cleanup")
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]config.CleanupStep, len(merged))
+	for i, step := range merged {
+		if step.preset >= 0 {
+			steps[i] = presetSteps[step.preset]
+		} else {
+			steps[i] = cleanup.Steps[step.config]
+		}
+		steps[i].ID = step.id
+		steps[i].StepPatch = config.StepPatch{}
+	}
+	return steps, nil
+}
+
+// ApplyStepPatches merges the steps of patch into base steps like the
+// configured scaffold steps are merged into the preset steps. Presets that
+// extend another preset use it.
+func ApplyStepPatches(base []config.StepConfig, patch config.ScaffoldConfig) ([]config.StepConfig, error) {
+	steps, _, err := mergeScaffoldSteps(base, patch)
+	return steps, err
+}
+
+// ApplyCleanupPatches merges the cleanup steps of patch into base cleanup
+// steps, see ApplyStepPatches.
+func ApplyCleanupPatches(base []config.CleanupStep, patch config.CleanupConfig) ([]config.CleanupStep, error) {
+	return mergeCleanupSteps(base, patch)
+}
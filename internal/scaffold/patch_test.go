@@ -0,0 +1,233 @@
+package scaffold
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// testPreset is a preset with fixed steps.
+type testPreset struct {
+	defaultSteps []config.StepConfig
+	cleanupSteps []config.CleanupStep
+}
+
+func (p *testPreset) Name() string                       { return "test" }
+func (p *testPreset) Detect(path string) bool            { return true }
+func (p *testPreset) DefaultSteps() []config.StepConfig  { return p.defaultSteps }
+func (p *testPreset) CleanupSteps() []config.CleanupStep { return p.cleanupSteps }
+
+func TestMergeScaffoldSteps(t *testing.T) {
+	presetSteps := []config.StepConfig{
+		{Name: "php.composer", ID: "composer", DependsOn: []string{}, Args: []string{"install"}},
+		{Name: "php.laravel", ID: "migrate", DependsOn: []string{"composer"}, Args: []string{"migrate:fresh", "--seed"}},
+		{Name: "node.npm", ID: "npm-ci", DependsOn: []string{}, Args: []string{"ci"}},
+		{Name: "node.npm", ID: "npm-build", DependsOn: []string{"npm-ci"}, Args: []string{"run", "build"}},
+		{Name: "php.laravel", ID: "storage-link", DependsOn: []string{"migrate"}, Args: []string{"storage:link"}},
+	}
+	ids := func(steps []config.StepConfig) []string {
+		ids := make([]string, len(steps))
+		for i, step := range steps {
+			ids[i] = step.ID
+		}
+		return ids
+	}
+
+	t.Run("inserts, replaces and disables steps", func(t *testing.T) {
+		steps, indexes, err := mergeScaffoldSteps(presetSteps, config.ScaffoldConfig{
+			Disable: []string{"npm-ci"},
+			Steps: []config.StepConfig{
+				{Name: "bash.run", ID: "first", StepPatch: config.StepPatch{Before: "composer"}},
+				{Name: "php.laravel", Args: []string{"migrate"}, StepPatch: config.StepPatch{Replace: "migrate"}},
+				{Name: "bash.run", ID: "after-1", StepPatch: config.StepPatch{After: "composer"}},
+				{Name: "bash.run", ID: "after-2", StepPatch: config.StepPatch{After: "composer"}},
+				{Name: "bash.run", ID: "last"},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"first", "composer", "after-1", "after-2", "migrate", "npm-build", "storage-link", "last"}, ids(steps))
+		assert.Equal(t, []int{0, -1, 2, 3, 1, -1, -1, 4}, indexes)
+
+		// The replacement takes over the id and dependencies of migrate
+		assert.Equal(t, []string{"migrate"}, steps[4].Args)
+		assert.Equal(t, []string{"composer"}, steps[4].DependsOn)
+		// Steps no longer wait for disabled steps
+		assert.Equal(t, []string{}, steps[5].DependsOn)
+		assert.Nil(t, steps[0].DependsOn)
+		assert.Equal(t, []string{"npm-ci"}, presetSteps[3].DependsOn, "preset steps are not modified")
+	})
+
+	t.Run("dependents follow a replacement with its own id", func(t *testing.T) {
+		steps, _, err := mergeScaffoldSteps(presetSteps, config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "php.laravel", ID: "migrate-only", DependsOn: []string{}, Args: []string{"migrate"}, StepPatch: config.StepPatch{Replace: "migrate"}},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"composer", "migrate-only", "npm-ci", "npm-build", "storage-link"}, ids(steps))
+		assert.Equal(t, []string{}, steps[1].DependsOn)
+		assert.Equal(t, []string{"migrate-only"}, steps[4].DependsOn)
+	})
+
+	t.Run("dependents of a disabled step wait for its dependencies", func(t *testing.T) {
+		steps, _, err := mergeScaffoldSteps([]config.StepConfig{
+			{Name: "php.composer", ID: "composer", DependsOn: []string{}},
+			{Name: "php.laravel", ID: "key-generate", DependsOn: []string{"composer"}},
+			{Name: "php.laravel", ID: "migrate", DependsOn: []string{"key-generate"}},
+			{Name: "bash.run", ID: "warm"},
+			{Name: "bash.run", ID: "serve", DependsOn: []string{"warm", "migrate"}},
+		}, config.ScaffoldConfig{Disable: []string{"key-generate", "warm"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"composer", "migrate", "serve"}, ids(steps))
+		assert.Equal(t, []string{"composer"}, steps[1].DependsOn)
+		// warm declared no depends_on, so it waited for every step before it
+		assert.Equal(t, []string{"composer", "migrate"}, steps[2].DependsOn)
+	})
+
+	t.Run("steps inserted before a step are its dependencies", func(t *testing.T) {
+		steps, _, err := mergeScaffoldSteps(presetSteps, config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", Command: "./check-db", StepPatch: config.StepPatch{Before: "migrate"}},
+				{Name: "bash.run", ID: "backup", StepPatch: config.StepPatch{Before: "migrate"}},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"composer", "before-migrate", "backup", "migrate", "npm-ci", "npm-build", "storage-link"}, ids(steps))
+		assert.Equal(t, []string{"composer", "before-migrate", "backup"}, steps[3].DependsOn)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			scaffold config.ScaffoldConfig
+			want     string
+		}{
+			{
+				name:     "unknown disabled step",
+				scaffold: config.ScaffoldConfig{Disable: []string{"npm"}},
+				want:     `scaffold.disable: unknown preset step id "npm" (available: composer, migrate, npm-ci, npm-build, storage-link)`,
+			},
+			{
+				name:     "unknown target",
+				scaffold: config.ScaffoldConfig{Steps: []config.StepConfig{{Name: "bash.run"}, {Name: "bash.run", StepPatch: config.StepPatch{After: "seed"}}}},
+				want:     `scaffold.steps[1]: after: unknown step id "seed" (available: composer, migrate, npm-ci, npm-build, storage-link)`,
+			},
+			{
+				name:     "target was disabled",
+				scaffold: config.ScaffoldConfig{Disable: []string{"migrate"}, Steps: []config.StepConfig{{Name: "bash.run", StepPatch: config.StepPatch{Replace: "migrate"}}}},
+				want:     `scaffold.steps[0]: replace: unknown step id "migrate"`,
+			},
+			{
+				name:     "several operations",
+				scaffold: config.ScaffoldConfig{Steps: []config.StepConfig{{Name: "bash.run", StepPatch: config.StepPatch{Before: "composer", After: "migrate"}}}},
+				want:     "scaffold.steps[0]: only one of before, after and replace may be set",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, _, err := mergeScaffoldSteps(presetSteps, tt.scaffold)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.want)
+			})
+		}
+	})
+}
+
+func TestScaffoldManager_PatchPresetSteps(t *testing.T) {
+	manager := NewScaffoldManager()
+	manager.RegisterPreset(&testPreset{
+		defaultSteps: []config.StepConfig{
+			{Name: "bash.run", ID: "install", DependsOn: []string{}, Command: "make install"},
+			{Name: "bash.run", ID: "build", DependsOn: []string{"install"}, Command: "make build"},
+		},
+		cleanupSteps: []config.CleanupStep{
+			{Name: "herd", ID: "herd-unlink"},
+			{Name: config.StepDbDestroy, ID: "db-destroy"},
+		},
+	})
+	cfg := &config.Config{
+		Preset: "test",
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", Command: "make assets", StepPatch: config.StepPatch{Replace: "build"}},
+			},
+		},
+		Cleanup: config.CleanupConfig{
+			Disable: []string{"db-destroy"},
+			Steps: []config.CleanupStep{
+				{Name: "bash.run", ID: "stop", StepPatch: config.StepPatch{Before: "herd-unlink"}, ConditionHolder: config.ConditionHolder{Condition: map[string]any{"command": "make stop"}}},
+			},
+		},
+	}
+
+	steps, err := manager.GetStepsForWorktree(cfg, t.TempDir(), "main")
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, "build", stepKey(steps[1], 1))
+	deps, _ := stepDependsOn(steps[1])
+	assert.Equal(t, []string{"install"}, deps)
+
+	cleanup, err := manager.GetCleanupSteps(cfg, t.TempDir(), "main")
+	require.NoError(t, err)
+	require.Len(t, cleanup, 2)
+	assert.Equal(t, "stop", stepKey(cleanup[0], 0))
+	assert.Equal(t, "herd-unlink", stepKey(cleanup[1], 1))
+
+	cfg.Cleanup.Disable = []string{"herd"}
+	_, err = manager.GetCleanupSteps(cfg, t.TempDir(), "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `cleanup.disable: unknown preset step id "herd" (available: herd-unlink, db-destroy)`)
+
+	// cleanup.override leaves out the preset cleanup steps
+	cfg.Cleanup = config.CleanupConfig{Override: true, Steps: []config.CleanupStep{
+		{Name: "bash.run", ID: "stop", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"command": "make stop"}}},
+	}}
+	cleanup, err = manager.GetCleanupSteps(cfg, t.TempDir(), "main")
+	require.NoError(t, err)
+	require.Len(t, cleanup, 1)
+	assert.Equal(t, "stop", stepKey(cleanup[0], 0))
+}
+
+func TestMergeScaffoldSteps_BeforeInParallel(t *testing.T) {
+	merged, _, err := mergeScaffoldSteps([]config.StepConfig{
+		{Name: "php.composer", ID: "composer", DependsOn: []string{}},
+		{Name: "php.laravel", ID: "migrate", DependsOn: []string{"composer"}},
+	}, config.ScaffoldConfig{
+		Steps: []config.StepConfig{
+			{Name: "bash.run", Command: "./check-db", StepPatch: config.StepPatch{Before: "migrate"}},
+		},
+	})
+	require.NoError(t, err)
+
+	log := &eventLog{}
+	release := make(chan struct{})
+	steps := make([]types.ScaffoldStep, len(merged))
+	for i, cfg := range merged {
+		step := &recordingStep{name: cfg.ID, id: cfg.ID, deps: cfg.DependsOn, enabled: true, log: log}
+		if cfg.Command == "./check-db" {
+			step.name, step.release = "check-db", release
+		}
+		steps[i] = step
+	}
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetConcurrency(4)
+
+	// Hold the inserted step long enough for migrate to start if it could
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	require.NoError(t, executor.Execute())
+
+	events := log.snapshot()
+	assert.Less(t, indexOf(events, "finish check-db"), indexOf(events, "start migrate"))
+}
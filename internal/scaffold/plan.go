@@ -0,0 +1,174 @@
+package scaffold
+
+import (
+	"fmt"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// Actions of a planned step.
+const (
+	PlanRun   = "run"   // The step would run
+	PlanSkip  = "skip"  // The step would be skipped
+	PlanError = "error" // The step would fail before running, e.g. on an invalid condition
+)
+
+// placeholderDbSuffix stands in for the database suffix of a worktree that
+// was not scaffolded yet, since planning does not generate one.
+const placeholderDbSuffix = "<db_suffix>"
+
+// Plan describes what a scaffold run would do without running any step.
+type Plan struct {
+	Worktree string `json:"worktree"`
+	Branch   string `json:"branch"`
+	Preset   string `json:"preset,omitempty"`
+	Profile  string `json:"profile,omitempty"`
+	// PreFlight is the error of the pre-flight checks, or "" if they pass.
+	PreFlight string        `json:"preFlightError,omitempty"`
+	Steps     []PlannedStep `json:"steps"`
+}
+
+// PlannedStep describes what a scaffold run would do with a step.
+type PlannedStep struct {
+	ID          string   `json:"id"` // Key to select the step by, see stepKey
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Action      string   `json:"action"` // PlanRun, PlanSkip or PlanError
+	Reason      string   `json:"reason"`
+	Command     string   `json:"command,omitempty"` // Command line the step would run
+	Files       []string `json:"files,omitempty"`   // Files the step would write, relative to the worktree
+	DependsOn   []string `json:"dependsOn,omitempty"`
+	Error       string   `json:"error,omitempty"` // Why the step would fail, e.g. an unknown template variable
+}
+
+// PlanScaffold resolves the scaffold steps of a worktree and reports for
+// each of them whether it would run and what it would do. Conditions are
+// evaluated against the worktree as it is now, so files created by earlier
+// steps are not taken into account. Variables that steps would store with
+// store_as are rendered as placeholders, e.g. "<app_key>", unless their
+// value is passed with --var in vars.
+//
+// Nothing is run or written, and the returned error is reserved for
+// configurations that could not run at all (unknown steps, dependency
+// cycles); problems of single steps are reported in the plan.
+func (m *ScaffoldManager) PlanScaffold(worktreePath, branch, repoName, siteName, preset string, cfg *config.Config, selection StepSelection, vars map[string]string) (*Plan, error) {
+	ctx := m.newScaffoldContext(worktreePath, branch, repoName, siteName, preset)
+	ctx.DefaultBranch = cfg.DefaultBranch
+	ctx.MainSiteName = cfg.SiteName
+	ctx.Inputs = vars
+
+	stepsList, err := m.GetStepsForWorktree(cfg, worktreePath, branch)
+	if err != nil {
+		return nil, fmt.Errorf("getting scaffold steps: %w", err)
+	}
+	if _, err := buildStepGraph(stepsList); err != nil {
+		return nil, err
+	}
+	excluded, err := selection.excluded(stepsList)
+	if err != nil {
+		return nil, err
+	}
+
+	localState, err := config.ReadLocalState(worktreePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading local state: %w", err)
+	}
+	if localState.DbSuffix != "" {
+		ctx.SetDbSuffix(localState.DbSuffix)
+	} else {
+		ctx.SetDbSuffix(placeholderDbSuffix)
+	}
+	ctx.Ports = localState.Ports
+	if err := checkVarOverrides(vars, cfg.Vars, stepsList); err != nil {
+		return nil, err
+	}
+	if _, err := applyVars(&ctx, cfg.Vars, vars, localState.Vars); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Worktree: worktreePath, Branch: branch, Preset: preset, Profile: cfg.Profile, Steps: make([]PlannedStep, 0, len(stepsList))}
+	if err := m.runPreFlightChecks(&ctx, &cfg.Scaffold); err != nil {
+		plan.PreFlight = err.Error()
+	}
+
+	for i, step := range stepsList {
+		planned := planStep(step, i, &ctx, excluded[i])
+		if planned.Action == PlanRun {
+			if configured, ok := step.(*configuredStep); ok {
+				if name := configured.storedVar(); name != "" {
+					if value, ok := vars[name]; ok && promptVar(step) != "" {
+						ctx.SetVar(name, value)
+					} else {
+						ctx.SetVar(name, "<"+name+">")
+					}
+				}
+			}
+		}
+		plan.Steps = append(plan.Steps, planned)
+	}
+	return plan, nil
+}
+
+// planStep reports what a run would do with the step at index.
+func planStep(step types.ScaffoldStep, index int, ctx *types.ScaffoldContext, excluded bool) PlannedStep {
+	planned := PlannedStep{
+		ID:          stepKey(step, index),
+		Name:        step.Name(),
+		Description: getStepDescription(step),
+	}
+	planned.DependsOn, _ = stepDependsOn(step)
+
+	// The command and files are reported for skipped steps too, so a
+	// reviewer sees what a step does once its condition holds
+	stepPlan, planErr := stepPlanOf(step, ctx)
+	planned.Command, planned.Files = stepPlan.Command, stepPlan.Files
+
+	enabled := true
+	if stepConfig, ok := step.(interface{ IsEnabled() bool }); ok {
+		enabled = stepConfig.IsEnabled()
+	}
+
+	switch met, err := stepCondition(step, ctx); {
+	case excluded:
+		planned.Action, planned.Reason = PlanSkip, "not selected"
+	case !enabled:
+		planned.Action, planned.Reason = PlanSkip, "disabled"
+	case err != nil:
+		planned.Action, planned.Reason = PlanError, "condition could not be evaluated"
+		planned.Error = err.Error()
+	case !met:
+		planned.Action, planned.Reason = PlanSkip, stepSkipReason(step, ctx)
+	default:
+		planned.Action, planned.Reason = PlanRun, "no condition"
+		if configured, ok := step.(*configuredStep); ok && len(configured.cfg.Condition) > 0 {
+			planned.Reason = "condition met"
+		}
+		if planErr != nil {
+			planned.Action = PlanError
+			planned.Error = planErr.Error()
+		}
+	}
+	return planned
+}
+
+// stepPlanOf returns what a step would do, or an empty plan if the step
+// does not report it.
+func stepPlanOf(step types.ScaffoldStep, ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	if planner, ok := step.(interface {
+		Plan(*types.ScaffoldContext) (types.StepPlan, error)
+	}); ok {
+		return planner.Plan(ctx)
+	}
+	return types.StepPlan{}, nil
+}
+
+// stepSkipReason explains why the condition of a step is not met.
+func stepSkipReason(step types.ScaffoldStep, ctx *types.ScaffoldContext) string {
+	if explainer, ok := step.(interface {
+		SkipReason(*types.ScaffoldContext) string
+	}); ok {
+		return explainer.SkipReason(ctx)
+	}
+	return "condition not met"
+}
@@ -0,0 +1,96 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+func TestScaffoldManager_PlanScaffold(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".env.example"), []byte("APP_NAME=app\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.tmpl"), []byte("site={{ .SiteName }}\n"), 0644))
+
+	disabled := false
+	cfg := &config.Config{
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "file.copy", From: ".env.example", To: ".env"},
+				{Name: "file.copy", From: ".env.testing.example", To: ".env.testing"},
+				{Name: "bash.run", ID: "key", Command: "echo {{ .SiteName }}-key", StoreAs: "app_key"},
+				{Name: "env.write", Key: "APP_KEY", Value: "{{ .app_key }}"},
+				{Name: "file.template", From: "config.tmpl", To: "config/app.conf", DependsOn: []string{"key"}},
+				{Name: "bash.run", ID: "seed", Command: "touch seeded", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "seeded"}}},
+				{Name: "bash.run", ID: "off", Command: "touch off", Enabled: &disabled},
+				{Name: "bash.run", ID: "broken", Command: "echo {{ .missing }}"},
+				{Name: "bash.run", ID: "build", Command: "touch built"},
+			},
+		},
+	}
+
+	manager := NewScaffoldManager()
+	plan, err := manager.PlanScaffold(tmpDir, "feature/auth", "myrepo", "myapp", "", cfg, StepSelection{Skip: []string{"build"}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, tmpDir, plan.Worktree)
+	assert.Equal(t, "feature/auth", plan.Branch)
+	assert.Empty(t, plan.PreFlight)
+	require.Len(t, plan.Steps, 9)
+
+	assert.Equal(t, PlannedStep{
+		ID: "file.copy#1", Name: "file.copy", Description: "Copying files (file.copy)",
+		Action: PlanRun, Reason: "no condition", Files: []string{".env"},
+	}, plan.Steps[0])
+
+	assert.Equal(t, PlanSkip, plan.Steps[1].Action)
+	assert.Equal(t, ".env.testing.example does not exist", plan.Steps[1].Reason)
+
+	assert.Equal(t, PlanRun, plan.Steps[2].Action)
+	assert.Equal(t, "bash -c 'echo myapp-key'", plan.Steps[2].Command)
+
+	// env.write renders the variable stored by the step before it
+	assert.Equal(t, PlanRun, plan.Steps[3].Action)
+	assert.Empty(t, plan.Steps[3].Error)
+	assert.Equal(t, []string{".env"}, plan.Steps[3].Files)
+
+	assert.Equal(t, PlanRun, plan.Steps[4].Action)
+	assert.Equal(t, []string{filepath.Join("config", "app.conf")}, plan.Steps[4].Files)
+	assert.Equal(t, []string{"key"}, plan.Steps[4].DependsOn)
+
+	assert.Equal(t, PlanSkip, plan.Steps[5].Action)
+	assert.Equal(t, "condition not met", plan.Steps[5].Reason)
+	assert.Equal(t, "bash -c 'touch seeded'", plan.Steps[5].Command, "skipped steps show their command")
+
+	assert.Equal(t, PlanSkip, plan.Steps[6].Action)
+	assert.Equal(t, "disabled", plan.Steps[6].Reason)
+
+	assert.Equal(t, PlanError, plan.Steps[7].Action)
+	assert.Contains(t, plan.Steps[7].Error, `map has no entry for key "missing"`)
+
+	assert.Equal(t, PlanSkip, plan.Steps[8].Action)
+	assert.Equal(t, "not selected", plan.Steps[8].Reason)
+
+	// Nothing was run or written
+	assert.NoFileExists(t, filepath.Join(tmpDir, ".env"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, config.LocalStateFile))
+}
+
+func TestScaffoldManager_PlanScaffoldErrors(t *testing.T) {
+	cfg := &config.Config{
+		Scaffold: config.ScaffoldConfig{
+			Steps: []config.StepConfig{
+				{Name: "bash.run", ID: "a", Command: "true", DependsOn: []string{"b"}},
+				{Name: "bash.run", ID: "b", Command: "true", DependsOn: []string{"a"}},
+			},
+		},
+	}
+
+	_, err := NewScaffoldManager().PlanScaffold(t.TempDir(), "main", "myrepo", "myapp", "", cfg, StepSelection{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cycle")
+}
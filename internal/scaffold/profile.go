@@ -0,0 +1,86 @@
+package scaffold
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// applyProfile narrows steps down to the steps selected by cfg.Profile and
+// appends the steps the profile adds. Steps are returned unchanged when no
+// profile is set.
+func (m *ScaffoldManager) applyProfile(cfg *config.Config, steps []types.ScaffoldStep) ([]types.ScaffoldStep, error) {
+	if cfg.Profile == "" {
+		return steps, nil
+	}
+	if err := CheckProfile(cfg); err != nil {
+		return nil, err
+	}
+	profile := cfg.Profiles[cfg.Profile]
+
+	selection := StepSelection{Only: profile.Only, Skip: profile.Skip, Tags: profile.Tags}
+	excluded, err := selection.excluded(steps)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", cfg.Profile, err)
+	}
+	selected := make([]types.ScaffoldStep, 0, len(steps)+len(profile.Steps))
+	leftOut := make(map[string]bool)
+	for i, step := range steps {
+		if !excluded[i] {
+			selected = append(selected, step)
+		} else if id := stepID(step); id != "" {
+			leftOut[id] = true
+		}
+	}
+
+	for i, step := range profile.Steps {
+		if step.StepPatch != (config.StepPatch{}) {
+			return nil, fmt.Errorf("profiles.%s.steps[%d]: before, after and replace are not supported in profiles", cfg.Profile, i)
+		}
+	}
+	added, err := m.stepsFromConfig(profile.Steps, "profiles."+cfg.Profile+".steps")
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", cfg.Profile, err)
+	}
+	selected = append(selected, added...)
+
+	// Report dependencies on left out steps here, as the dependency graph
+	// can only tell that the id is unknown
+	for i, step := range selected {
+		deps, _ := stepDependsOn(step)
+		for _, dep := range deps {
+			if leftOut[dep] {
+				return nil, fmt.Errorf("profile %s leaves out step %q, which %s depends on", cfg.Profile, dep, stepKey(step, i))
+			}
+		}
+	}
+	return selected, nil
+}
+
+// CheckProfile reports an error if cfg.Profile is not defined, so commands
+// can check it before creating a worktree.
+func CheckProfile(cfg *config.Config) error {
+	if cfg.Profile == "" {
+		return nil
+	}
+	if _, ok := cfg.Profiles[cfg.Profile]; !ok {
+		return unknownProfileError(cfg.Profile, cfg.Profiles)
+	}
+	return nil
+}
+
+// unknownProfileError reports a profile that is not defined in anvil.yaml.
+func unknownProfileError(name string, profiles map[string]config.ProfileConfig) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("unknown profile %q (no profiles defined in %s)", name, config.ProjectConfigFile)
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+}
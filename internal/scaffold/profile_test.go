@@ -0,0 +1,74 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+func TestScaffoldManager_GetStepsForWorktree_Profile(t *testing.T) {
+	newConfig := func(profile string) *config.Config {
+		return &config.Config{
+			Profile: profile,
+			Profiles: map[string]config.ProfileConfig{
+				"minimal": {Skip: []string{"build", "herd"}},
+				"ci":      {Tags: []string{"ci"}, Steps: []config.StepConfig{{Name: "bash.run", ID: "test", Command: "make test", DependsOn: []string{"install"}}}},
+				"broken":  {Skip: []string{"install"}},
+			},
+			Scaffold: config.ScaffoldConfig{
+				Steps: []config.StepConfig{
+					{Name: "bash.run", ID: "install", Command: "make install", Tags: []string{"ci"}},
+					{Name: "bash.run", ID: "build", Command: "make build", DependsOn: []string{"install"}},
+					{Name: "herd", Args: []string{"link"}},
+				},
+			},
+		}
+	}
+	keys := func(steps []types.ScaffoldStep) []string {
+		keys := make([]string, len(steps))
+		for i, step := range steps {
+			keys[i] = stepKey(step, i)
+		}
+		return keys
+	}
+	manager := NewScaffoldManager()
+
+	steps, err := manager.GetStepsForWorktree(newConfig(""), t.TempDir(), "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"install", "build", "herd:link#3"}, keys(steps))
+
+	steps, err = manager.GetStepsForWorktree(newConfig("minimal"), t.TempDir(), "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"install"}, keys(steps))
+
+	steps, err = manager.GetStepsForWorktree(newConfig("ci"), t.TempDir(), "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"install", "test"}, keys(steps))
+
+	_, err = manager.GetStepsForWorktree(newConfig("broken"), t.TempDir(), "main")
+	require.Error(t, err)
+	assert.Equal(t, `profile broken leaves out step "install", which build depends on`, err.Error())
+
+	_, err = manager.GetStepsForWorktree(newConfig("full"), t.TempDir(), "main")
+	require.Error(t, err)
+	assert.Equal(t, `unknown profile "full" (available: broken, ci, minimal)`, err.Error())
+
+	cfg := newConfig("minimal")
+	cfg.Profiles["minimal"] = config.ProfileConfig{Skip: []string{"npm"}}
+	_, err = manager.GetStepsForWorktree(cfg, t.TempDir(), "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `profile minimal: unknown step "npm"`)
+}
+
+func TestCheckProfile(t *testing.T) {
+	assert.NoError(t, CheckProfile(&config.Config{}))
+	assert.NoError(t, CheckProfile(&config.Config{Profile: "ci", Profiles: map[string]config.ProfileConfig{"ci": {}}}))
+
+	err := CheckProfile(&config.Config{Profile: "ci"})
+	require.Error(t, err)
+	assert.Equal(t, `unknown profile "ci" (no profiles defined in anvil.yaml)`, err.Error())
+}
@@ -0,0 +1,109 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+const (
+	// defaultRetryDelay is the delay before the first retry when a step
+	// sets retries without retry_delay.
+	defaultRetryDelay = 2 * time.Second
+
+	// maxRetryDelay caps the exponential backoff between retries, unless
+	// retry_delay itself is longer.
+	maxRetryDelay = time.Minute
+)
+
+// retryPolicy decides whether a failed step is run again and how long to
+// wait before the next attempt.
+type retryPolicy struct {
+	retries   int
+	delay     time.Duration
+	exitCodes []int
+	output    *regexp.Regexp
+}
+
+// newRetryPolicy builds the retry policy of a step from its retries,
+// retry_delay and retry_on options.
+func newRetryPolicy(cfg config.StepConfig) (retryPolicy, error) {
+	policy := retryPolicy{retries: cfg.Retries, delay: defaultRetryDelay}
+	if cfg.Retries < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid retries %d: must not be negative", cfg.Retries)
+	}
+
+	if cfg.RetryDelay != "" {
+		delay, err := time.ParseDuration(cfg.RetryDelay)
+		if err != nil {
+			return retryPolicy{}, fmt.Errorf("invalid retry_delay %q: %w", cfg.RetryDelay, err)
+		}
+		if delay < 0 {
+			return retryPolicy{}, fmt.Errorf("invalid retry_delay %q: must not be negative", cfg.RetryDelay)
+		}
+		policy.delay = delay
+	}
+
+	if cfg.RetryOn != nil {
+		policy.exitCodes = cfg.RetryOn.ExitCodes
+		if cfg.RetryOn.Output != "" {
+			output, err := regexp.Compile(cfg.RetryOn.Output)
+			if err != nil {
+				return retryPolicy{}, fmt.Errorf("invalid retry_on.output: %w", err)
+			}
+			policy.output = output
+		}
+	}
+
+	return policy, nil
+}
+
+// attempts returns how many times the step is run at most.
+func (p retryPolicy) attempts() int {
+	return p.retries + 1
+}
+
+// retryable reports whether a failure with the given command output matches
+// retry_on. Without retry_on, every failure is retried.
+func (p retryPolicy) retryable(err error, output string) bool {
+	if len(p.exitCodes) == 0 && p.output == nil {
+		return true
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && slices.Contains(p.exitCodes, exitErr.ExitCode()) {
+		return true
+	}
+
+	if p.output == nil {
+		return false
+	}
+	return p.output.MatchString(output) || p.output.MatchString(err.Error())
+}
+
+// backoff returns the delay before the given retry (1 for the first retry).
+// The delay doubles with every retry.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.delay
+	for i := 1; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay && p.delay <= maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// stepRetryPolicy returns the retry policy of a step. Steps that do not
+// expose one are run once.
+func stepRetryPolicy(step types.ScaffoldStep) retryPolicy {
+	if retrying, ok := step.(interface{ retryPolicy() retryPolicy }); ok {
+		return retrying.retryPolicy()
+	}
+	return retryPolicy{}
+}
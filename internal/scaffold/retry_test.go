@@ -0,0 +1,187 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// flakyStep fails until it has been run succeedAfter times.
+type flakyStep struct {
+	mockStep
+	succeedAfter int
+	runs         int
+	err          error
+}
+
+func (s *flakyStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	s.runCalled = true
+	s.runs++
+	if s.runs < s.succeedAfter {
+		return s.err
+	}
+	return nil
+}
+
+func newFlakyStep(succeedAfter int, cfg config.StepConfig) (*flakyStep, types.ScaffoldStep) {
+	flaky := &flakyStep{
+		mockStep:     mockStep{name: "php.composer", conditionResult: true},
+		succeedAfter: succeedAfter,
+		err:          fmt.Errorf("php.composer failed: exit status 1\nConnection reset by peer"),
+	}
+	cfg.Name = "php.composer"
+	if cfg.RetryDelay == "" {
+		cfg.RetryDelay = "1ms"
+	}
+	return flaky, newConfiguredStep(flaky, cfg)
+}
+
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	require.Error(t, err)
+	return fmt.Errorf("bash.run failed: %w\n", err)
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		policy, err := newRetryPolicy(config.StepConfig{Retries: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, policy.attempts())
+		assert.Equal(t, defaultRetryDelay, policy.backoff(1))
+	})
+
+	t.Run("no retries", func(t *testing.T) {
+		policy, err := newRetryPolicy(config.StepConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, policy.attempts())
+	})
+
+	invalid := []struct {
+		name string
+		cfg  config.StepConfig
+		want string
+	}{
+		{"negative retries", config.StepConfig{Retries: -1}, "invalid retries -1"},
+		{"invalid delay", config.StepConfig{RetryDelay: "later"}, `invalid retry_delay "later"`},
+		{"negative delay", config.StepConfig{RetryDelay: "-1s"}, `invalid retry_delay "-1s"`},
+		{"invalid output pattern", config.StepConfig{RetryOn: &config.RetryOn{Output: "("}}, "invalid retry_on.output"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRetryPolicy(tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := retryPolicy{delay: 10 * time.Second}
+	assert.Equal(t, 10*time.Second, policy.backoff(1))
+	assert.Equal(t, 20*time.Second, policy.backoff(2))
+	assert.Equal(t, 40*time.Second, policy.backoff(3))
+	assert.Equal(t, maxRetryDelay, policy.backoff(4))
+	assert.Equal(t, maxRetryDelay, policy.backoff(10))
+
+	long := retryPolicy{delay: 2 * time.Minute}
+	assert.Equal(t, 2*time.Minute, long.backoff(3), "a retry_delay above the cap is kept")
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	exitOne := exitError(t, 1)
+	exitTwo := exitError(t, 2)
+
+	assert.True(t, retryPolicy{}.retryable(exitOne, ""), "without retry_on every failure is retried")
+
+	byCode := retryPolicy{exitCodes: []int{2}}
+	assert.True(t, byCode.retryable(exitTwo, ""))
+	assert.False(t, byCode.retryable(exitOne, ""))
+	assert.False(t, byCode.retryable(fmt.Errorf("no exit code"), ""))
+
+	policy, err := newRetryPolicy(config.StepConfig{RetryOn: &config.RetryOn{Output: "(?i)connection reset"}})
+	require.NoError(t, err)
+	assert.True(t, policy.retryable(fmt.Errorf("npm failed: exit status 1"), "ECONNRESET: Connection reset by peer\n"))
+	assert.True(t, policy.retryable(fmt.Errorf("npm failed: exit status 1\nECONNRESET: Connection reset by peer"), ""))
+	assert.False(t, policy.retryable(fmt.Errorf("npm failed: exit status 1"), "missing script: build\n"))
+}
+
+func TestStepExecutor_Retry_SucceedsAfterRetries(t *testing.T) {
+	tmpDir := t.TempDir()
+	flaky, step := newFlakyStep(3, config.StepConfig{Retries: 2})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+
+	require.NoError(t, executor.Execute())
+	assert.Equal(t, 3, flaky.runs)
+	assert.Equal(t, 3, executor.Results()[0].Attempts)
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	entry, ok := state.ScaffoldJournal.Entry("php.composer#1")
+	require.True(t, ok)
+	assert.Equal(t, config.JournalSucceeded, entry.Status)
+	assert.Equal(t, 3, entry.Attempts)
+}
+
+func TestStepExecutor_Retry_GivesUp(t *testing.T) {
+	flaky, step := newFlakyStep(10, config.StepConfig{Retries: 2})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Equal(t, 3, flaky.runs)
+	assert.Contains(t, err.Error(), "step php.composer failed after 3 attempts: php.composer failed: exit status 1")
+}
+
+func TestStepExecutor_Retry_OnlyMatchingFailures(t *testing.T) {
+	flaky, step := newFlakyStep(3, config.StepConfig{
+		Retries: 2,
+		RetryOn: &config.RetryOn{Output: "ETIMEDOUT"},
+	})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	err := executor.Execute()
+
+	require.Error(t, err)
+	assert.Equal(t, 1, flaky.runs)
+	assert.Equal(t, "step php.composer failed: php.composer failed: exit status 1\nConnection reset by peer", err.Error())
+}
+
+func TestStepExecutor_Retry_StopsWhenInterrupted(t *testing.T) {
+	flaky, step := newFlakyStep(10, config.StepConfig{Retries: 5, RetryDelay: "1m"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	started := time.Now()
+	err := executor.ExecuteContext(ctx)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, 1, flaky.runs)
+	assert.Less(t, time.Since(started), 10*time.Second)
+}
+
+func TestStepExecutor_Retry_Summary(t *testing.T) {
+	_, step := newFlakyStep(2, config.StepConfig{Retries: 1})
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+
+	require.NoError(t, executor.Execute())
+	assert.Equal(t, 1, executor.retriedCnt)
+}
@@ -0,0 +1,162 @@
+package scaffold
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// StepSelection restricts a run to some of its steps. Steps are referenced
+// by their key (see stepKey), by their key without the position (e.g.
+// "node.npm:run-build"), or by name, which selects every step of that name.
+type StepSelection struct {
+	Only []string // Run only these steps
+	Skip []string // Do not run these steps
+	Tags []string // Run only the steps with one of these tags
+}
+
+// IsZero reports whether the selection includes every step.
+func (s StepSelection) IsZero() bool {
+	return len(s.Only) == 0 && len(s.Skip) == 0 && len(s.Tags) == 0
+}
+
+// excluded returns the indexes of the steps left out by the selection.
+// A step is selected if it is listed in Only or has one of Tags (or if
+// neither is set), and it is not listed in Skip. Steps and tags that match
+// no step are an error, so a typo does not silently run everything.
+func (s StepSelection) excluded(steps []types.ScaffoldStep) (map[int]bool, error) {
+	excluded := make(map[int]bool)
+	if s.IsZero() {
+		return excluded, nil
+	}
+
+	only, err := matchSteps(steps, s.Only)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := matchSteps(steps, s.Skip)
+	if err != nil {
+		return nil, err
+	}
+	tagged, err := matchTags(steps, s.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	restricted := len(s.Only) > 0 || len(s.Tags) > 0
+	for i := range steps {
+		if skip[i] || restricted && !only[i] && !tagged[i] {
+			excluded[i] = true
+		}
+	}
+	return excluded, nil
+}
+
+// matchSteps returns the indexes of the steps referenced by selectors.
+func matchSteps(steps []types.ScaffoldStep, selectors []string) (map[int]bool, error) {
+	matched := make(map[int]bool)
+	for _, selector := range selectors {
+		found := false
+		for i, step := range steps {
+			if stepMatches(step, i, selector) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			keys := make([]string, len(steps))
+			for i, step := range steps {
+				keys[i] = stepKey(step, i)
+			}
+			return nil, fmt.Errorf("unknown step %q (available: %s)", selector, strings.Join(keys, ", "))
+		}
+	}
+	return matched, nil
+}
+
+// stepMatches reports whether selector references the step at index.
+func stepMatches(step types.ScaffoldStep, index int, selector string) bool {
+	if selector == stepKey(step, index) || selector == step.Name() {
+		return true
+	}
+	return stepID(step) == "" && selector == stepLabel(step)
+}
+
+// matchTags returns the indexes of the steps with one of tags.
+func matchTags(steps []types.ScaffoldStep, tags []string) (map[int]bool, error) {
+	matched := make(map[int]bool)
+	for _, tag := range tags {
+		found := false
+		for i, step := range steps {
+			for _, stepTag := range stepTags(step) {
+				if stepTag == tag {
+					matched[i] = true
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown tag %q (available: %s)", tag, availableTags(steps))
+		}
+	}
+	return matched, nil
+}
+
+// availableTags lists the tags used by steps for error messages.
+func availableTags(steps []types.ScaffoldStep) string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, step := range steps {
+		for _, tag := range stepTags(step) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return "none"
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ", ")
+}
+
+// stepTags returns the tags of a step, or nil if it does not expose any.
+func stepTags(step types.ScaffoldStep) []string {
+	if tagged, ok := step.(interface{ Tags() []string }); ok {
+		return tagged.Tags()
+	}
+	return nil
+}
+
+// StepInfo describes a step for listings.
+type StepInfo struct {
+	Key         string // Key to select the step by, see stepKey
+	Name        string
+	Description string
+	Tags        []string
+}
+
+// DescribeSteps describes the steps selected by selection, in the order
+// they are provided.
+func DescribeSteps(steps []types.ScaffoldStep, selection StepSelection) ([]StepInfo, error) {
+	excluded, err := selection.excluded(steps)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]StepInfo, 0, len(steps))
+	for i, step := range steps {
+		if excluded[i] {
+			continue
+		}
+		infos = append(infos, StepInfo{
+			Key:         stepKey(step, i),
+			Name:        step.Name(),
+			Description: getStepDescription(step),
+			Tags:        stepTags(step),
+		})
+	}
+	return infos, nil
+}
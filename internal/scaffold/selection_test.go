@@ -0,0 +1,151 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// argsStep is a mock step that exposes its arguments.
+type argsStep struct {
+	mockStep
+	args []string
+}
+
+func (s *argsStep) GetArgs() []string {
+	return s.args
+}
+
+func selectionTestSteps() []types.ScaffoldStep {
+	return []types.ScaffoldStep{
+		newConfiguredStep(&mockStep{name: "php.composer", conditionResult: true}, config.StepConfig{Name: "php.composer", ID: "install"}),
+		&argsStep{mockStep: mockStep{name: "node.npm", conditionResult: true}, args: []string{"run", "build"}},
+		newConfiguredStep(&argsStep{mockStep: mockStep{name: "php.laravel", conditionResult: true}, args: []string{"migrate", "--seed"}}, config.StepConfig{Name: "php.laravel", Tags: []string{"db"}}),
+		newConfiguredStep(&mockStep{name: "php.laravel", conditionResult: true}, config.StepConfig{Name: "php.laravel", Tags: []string{"db", "cache"}}),
+	}
+}
+
+func TestStepKey(t *testing.T) {
+	steps := selectionTestSteps()
+
+	keys := make([]string, len(steps))
+	for i, step := range steps {
+		keys[i] = stepKey(step, i)
+	}
+	assert.Equal(t, []string{"install", "node.npm:run-build#2", "php.laravel:migrate-seed#3", "php.laravel#4"}, keys)
+}
+
+func TestStepSelection_Excluded(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection StepSelection
+		want      []int
+	}{
+		{"empty", StepSelection{}, nil},
+		{"only by id", StepSelection{Only: []string{"install"}}, []int{1, 2, 3}},
+		{"only by key", StepSelection{Only: []string{"node.npm:run-build#2"}}, []int{0, 2, 3}},
+		{"only by name and args", StepSelection{Only: []string{"node.npm:run-build"}}, []int{0, 2, 3}},
+		{"only by name", StepSelection{Only: []string{"php.laravel"}}, []int{0, 1}},
+		{"skip", StepSelection{Skip: []string{"install", "php.laravel:migrate-seed"}}, []int{0, 2}},
+		{"tag", StepSelection{Tags: []string{"cache"}}, []int{0, 1, 2}},
+		{"only and tag", StepSelection{Only: []string{"install"}, Tags: []string{"cache"}}, []int{1, 2}},
+		{"tag and skip", StepSelection{Tags: []string{"db"}, Skip: []string{"php.laravel#4"}}, []int{0, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excluded, err := tt.selection.excluded(selectionTestSteps())
+			require.NoError(t, err)
+
+			want := make(map[int]bool)
+			for _, i := range tt.want {
+				want[i] = true
+			}
+			assert.Equal(t, want, excluded)
+		})
+	}
+}
+
+func TestStepSelection_Errors(t *testing.T) {
+	_, err := StepSelection{Only: []string{"migrate"}}.excluded(selectionTestSteps())
+	require.Error(t, err)
+	assert.Equal(t, `unknown step "migrate" (available: install, node.npm:run-build#2, php.laravel:migrate-seed#3, php.laravel#4)`, err.Error())
+
+	_, err = StepSelection{Skip: []string{"install#1"}}.excluded(selectionTestSteps())
+	require.Error(t, err, "steps with an id are only selected by id")
+
+	_, err = StepSelection{Tags: []string{"assets"}}.excluded(selectionTestSteps())
+	require.Error(t, err)
+	assert.Equal(t, `unknown tag "assets" (available: cache, db)`, err.Error())
+}
+
+func TestStepExecutor_Selection(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	install := &mockStep{name: "php.composer", conditionResult: true}
+	migrate := &mockStep{name: "php.laravel", conditionResult: true}
+	steps := []types.ScaffoldStep{
+		newConfiguredStep(install, config.StepConfig{Name: "php.composer", ID: "install"}),
+		newConfiguredStep(migrate, config.StepConfig{Name: "php.laravel", ID: "migrate", DependsOn: []string{"install"}}),
+	}
+
+	// A first run records both steps
+	executor := NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, nil, true), ResumeOptions{})
+	require.NoError(t, executor.Execute())
+
+	state, err := config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	install.runCalled, migrate.runCalled = false, false
+
+	// --only migrate runs the step even though its dependency is left out
+	executor = NewStepExecutor(steps, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetJournal(NewJournal(tmpDir, state.ScaffoldJournal, true), ResumeOptions{})
+	executor.SetSelection(StepSelection{Only: []string{"migrate"}})
+	require.NoError(t, executor.Execute())
+
+	assert.False(t, install.runCalled)
+	assert.True(t, migrate.runCalled)
+	results := executor.Results()
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Excluded)
+	assert.True(t, results[0].Skipped)
+	assert.False(t, results[1].Skipped)
+
+	state, err = config.ReadLocalState(tmpDir)
+	require.NoError(t, err)
+	entry, ok := state.ScaffoldJournal.Entry("install")
+	require.True(t, ok, "steps left out keep their journal entry")
+	assert.Equal(t, config.JournalSucceeded, entry.Status)
+}
+
+func TestStepExecutor_SelectionUnknownStep(t *testing.T) {
+	step := &mockStep{name: "php.composer", conditionResult: true}
+
+	executor := NewStepExecutor([]types.ScaffoldStep{step}, &types.ScaffoldContext{}, types.StepOptions{Quiet: true})
+	executor.SetSelection(StepSelection{Skip: []string{"npm"}})
+
+	err := executor.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown step "npm"`)
+	assert.False(t, step.runCalled)
+}
+
+func TestDescribeSteps(t *testing.T) {
+	infos, err := DescribeSteps(selectionTestSteps(), StepSelection{Tags: []string{"db"}})
+	require.NoError(t, err)
+
+	require.Len(t, infos, 2)
+	assert.Equal(t, StepInfo{
+		Key:         "php.laravel:migrate-seed#3",
+		Name:        "php.laravel",
+		Description: "Running artisan migrate (php.laravel)",
+		Tags:        []string{"db"},
+	}, infos[0])
+	assert.Equal(t, "php.laravel#4", infos[1].Key)
+	assert.Equal(t, []string{"db", "cache"}, infos[1].Tags)
+}
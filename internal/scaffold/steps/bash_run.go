@@ -1,10 +1,10 @@
 package steps
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
+	"github.com/naoray/anvil/internal/config"
 	anvil_exec "github.com/naoray/anvil/internal/exec"
 	"github.com/naoray/anvil/internal/scaffold/template"
 	"github.com/naoray/anvil/internal/scaffold/types"
@@ -13,6 +13,7 @@ import (
 type BashRunStep struct {
 	command  string
 	storeAs  string
+	options  commandOptions
 	executor *anvil_exec.CommandExecutor
 }
 
@@ -21,6 +22,14 @@ func NewBashRunStep(command string, storeAs string) *BashRunStep {
 	return NewBashRunStepWithExecutor(command, storeAs, nil)
 }
 
+// NewBashRunStepFromConfig creates a bash step from its configuration.
+// This is the factory function used by the registry.
+func NewBashRunStepFromConfig(cfg config.StepConfig) *BashRunStep {
+	step := NewBashRunStep(cfg.Command, cfg.StoreAs)
+	step.options = newCommandOptions(cfg)
+	return step
+}
+
 // NewBashRunStepWithExecutor creates a bash step with a custom command executor.
 // This is useful for testing with mock executors.
 func NewBashRunStepWithExecutor(command string, storeAs string, executor *anvil_exec.CommandExecutor) *BashRunStep {
@@ -44,10 +53,15 @@ func (s *BashRunStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) er
 		return fmt.Errorf("template replacement failed: %w", err)
 	}
 
+	runCtx, dir, err := s.options.prepare(ctx, opts)
+	if err != nil {
+		return err
+	}
+
 	// Use the command executor for testability
-	output, err := s.executor.RunBash(context.Background(), ctx.WorktreePath, command)
+	output, err := s.executor.RunInShell(runCtx, dir, s.options.shellOr("bash"), command)
 	if err != nil {
-		return fmt.Errorf("bash.run failed: %w\n%s", err, string(output))
+		return commandError(opts.Context(), "bash.run", err, output)
 	}
 
 	if s.storeAs != "" {
@@ -60,6 +74,19 @@ func (s *BashRunStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) er
 	return nil
 }
 
+// Plan returns the command line the step would run.
+func (s *BashRunStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	command, err := template.ReplaceTemplateVars(s.command, ctx)
+	if err != nil {
+		return types.StepPlan{}, fmt.Errorf("template replacement failed: %w", err)
+	}
+	planned, err := s.options.planCommand(ctx, []string{s.options.shellOr("bash"), "-c", command})
+	if err != nil {
+		return types.StepPlan{}, err
+	}
+	return types.StepPlan{Command: planned}, nil
+}
+
 func (s *BashRunStep) Condition(ctx *types.ScaffoldContext) bool {
 	return true
 }
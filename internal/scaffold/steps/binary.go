@@ -1,7 +1,6 @@
 package steps
 
 import (
-	"context"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -17,12 +16,12 @@ import (
 var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
 
 type BinaryStep struct {
-	name      string
-	binary    string
-	args      []string
-	condition map[string]any
-	storeAs   string
-	executor  *anvil_exec.CommandExecutor
+	name     string
+	binary   string
+	args     []string
+	storeAs  string
+	options  commandOptions
+	executor *anvil_exec.CommandExecutor
 }
 
 // NewBinaryStep creates a binary step with the default command executor.
@@ -37,25 +36,25 @@ func NewBinaryStepWithExecutor(name, binary string, args []string, storeAs strin
 		executor = anvil_exec.NewCommandExecutor(nil)
 	}
 	return &BinaryStep{
-		name:      name,
-		binary:    binary,
-		args:      args,
-		condition: nil,
-		storeAs:   storeAs,
-		executor:  executor,
+		name:     name,
+		binary:   binary,
+		args:     args,
+		storeAs:  storeAs,
+		executor: executor,
 	}
 }
 
-// NewBinaryStepWithCondition creates a binary step with condition evaluation.
-// This is the factory function used by the registry.
+// NewBinaryStepWithCondition creates a binary step from its configuration.
+// This is the factory function used by the registry, which also applies the
+// configured condition in place of the check for the binary.
 func NewBinaryStepWithCondition(name string, cfg config.StepConfig, binary string) *BinaryStep {
 	return &BinaryStep{
-		name:      name,
-		binary:    binary,
-		args:      cfg.Args,
-		condition: cfg.Condition,
-		storeAs:   cfg.StoreAs,
-		executor:  anvil_exec.NewCommandExecutor(nil),
+		name:     name,
+		binary:   binary,
+		args:     cfg.Args,
+		storeAs:  cfg.StoreAs,
+		options:  newCommandOptions(cfg),
+		executor: anvil_exec.NewCommandExecutor(nil),
 	}
 }
 
@@ -67,15 +66,8 @@ func (s *BinaryStep) GetArgs() []string {
 	return s.args
 }
 
+// Condition reports whether the binary is installed.
 func (s *BinaryStep) Condition(ctx *types.ScaffoldContext) bool {
-	if len(s.condition) > 0 {
-		result, err := ctx.EvaluateCondition(s.condition)
-		if err != nil {
-			return false
-		}
-		return result
-	}
-
 	binaries := strings.Fields(s.binary)
 	if len(binaries) == 0 {
 		return false
@@ -84,6 +76,32 @@ func (s *BinaryStep) Condition(ctx *types.ScaffoldContext) bool {
 	return err == nil
 }
 
+// SkipReason explains why Condition is false.
+func (s *BinaryStep) SkipReason(ctx *types.ScaffoldContext) string {
+	binaries := strings.Fields(s.binary)
+	if len(binaries) == 0 {
+		return "no binary configured"
+	}
+	return fmt.Sprintf("%s not found in PATH", binaries[0])
+}
+
+// Plan returns the command line the step would run.
+func (s *BinaryStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	args := make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		replaced, err := template.ReplaceTemplateVars(arg, ctx)
+		if err != nil {
+			return types.StepPlan{}, fmt.Errorf("template replacement failed: %w", err)
+		}
+		args = append(args, replaced)
+	}
+	planned, err := s.options.planCommand(ctx, append(strings.Fields(s.binary), args...))
+	if err != nil {
+		return types.StepPlan{}, err
+	}
+	return types.StepPlan{Command: planned}, nil
+}
+
 func (s *BinaryStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
 	allArgs := append(s.args, opts.Args...)
 	allArgs = s.replaceTemplate(allArgs, ctx)
@@ -93,10 +111,15 @@ func (s *BinaryStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) err
 		fmt.Printf("  Running: %s\n", strings.Join(fullCmd, " "))
 	}
 
+	runCtx, dir, err := s.options.prepare(ctx, opts)
+	if err != nil {
+		return err
+	}
+
 	// Use the command executor for testability
-	output, err := s.executor.RunBinary(context.Background(), ctx.WorktreePath, s.binary, allArgs)
+	output, err := s.executor.RunBinary(runCtx, dir, s.binary, allArgs)
 	if err != nil {
-		return fmt.Errorf("%s failed: %w\n%s", s.name, err, string(output))
+		return commandError(opts.Context(), s.name, err, output)
 	}
 
 	if s.storeAs != "" {
@@ -589,14 +589,14 @@ func TestConditionEvaluator_fileHasScript(t *testing.T) {
 		assert.False(t, result)
 	})
 
-	t.Run("file_has_script - empty script name", func(t *testing.T) {
+	t.Run("file_has_script - empty script name is rejected", func(t *testing.T) {
 		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"scripts": {}}`), 0644))
 
-		result, err := ctx.EvaluateCondition(map[string]any{
+		_, err := ctx.EvaluateCondition(map[string]any{
 			"file_has_script": "",
 		})
-		assert.NoError(t, err)
-		assert.False(t, result)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file_has_script: must not be empty")
 	})
 }
 
@@ -695,3 +695,25 @@ func TestBinaryStep_OutputCapture(t *testing.T) {
 		assert.Equal(t, "PhpOutput", binaryStep.storeAs)
 	})
 }
+
+func TestBinaryStep_Plan(t *testing.T) {
+	step, err := Create("php.laravel", config.StepConfig{
+		Args: []string{"db:seed", "--class={{ .SiteName }}Seeder", "it's"},
+	})
+	require.NoError(t, err)
+
+	planner, ok := step.(interface {
+		Plan(*types.ScaffoldContext) (types.StepPlan, error)
+	})
+	require.True(t, ok)
+
+	plan, err := planner.Plan(&types.ScaffoldContext{SiteName: "my app"})
+	require.NoError(t, err)
+	assert.Equal(t, `php artisan db:seed '--class=my appSeeder' 'it'\''s'`, plan.Command)
+	assert.Empty(t, plan.Files)
+
+	step, err = Create("php.laravel", config.StepConfig{Args: []string{"{{ .Missing }}"}})
+	require.NoError(t, err)
+	_, err = step.(*BinaryStep).Plan(&types.ScaffoldContext{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,18 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+
+	anvil_exec "github.com/naoray/anvil/internal/exec"
+)
+
+// commandError wraps the error of a command run by a step. The command
+// output is only appended when it was not already streamed to the step log,
+// so a failing step does not dump its whole output into the error message.
+func commandError(ctx context.Context, prefix string, err error, output []byte) error {
+	if anvil_exec.OutputWriter(ctx) != nil {
+		return fmt.Errorf("%s failed: %w", prefix, err)
+	}
+	return fmt.Errorf("%s failed: %w\n%s", prefix, err, string(output))
+}
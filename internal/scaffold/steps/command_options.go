@@ -0,0 +1,91 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+	anvil_exec "github.com/naoray/anvil/internal/exec"
+	"github.com/naoray/anvil/internal/scaffold/template"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// commandOptions holds the env, cwd and shell options of the steps that
+// run commands.
+type commandOptions struct {
+	env   map[string]string // Values are templates
+	cwd   string            // Relative to the worktree
+	shell string            // Only used by bash.run and command.run
+}
+
+func newCommandOptions(cfg config.StepConfig) commandOptions {
+	return commandOptions{env: cfg.Env, cwd: cfg.Cwd, shell: cfg.Shell}
+}
+
+// shellOr returns the configured shell, or fallback if none is set.
+func (o commandOptions) shellOr(fallback string) string {
+	if o.shell != "" {
+		return o.shell
+	}
+	return fallback
+}
+
+// dir returns the directory to run the command in.
+func (o commandOptions) dir(ctx *types.ScaffoldContext) string {
+	if o.cwd == "" {
+		return ctx.WorktreePath
+	}
+	return filepath.Join(ctx.WorktreePath, o.cwd)
+}
+
+// renderEnv renders the env values and returns them as sorted "KEY=value"
+// entries.
+func (o commandOptions) renderEnv(ctx *types.ScaffoldContext) ([]string, error) {
+	names := make([]string, 0, len(o.env))
+	for name := range o.env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		value, err := template.ReplaceTemplateVars(o.env[name], ctx)
+		if err != nil {
+			return nil, fmt.Errorf("template replacement failed for env %s: %w", name, err)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// prepare returns the context and directory to run the command with.
+func (o commandOptions) prepare(ctx *types.ScaffoldContext, opts types.StepOptions) (context.Context, string, error) {
+	env, err := o.renderEnv(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return anvil_exec.WithEnv(opts.Context(), env...), o.dir(ctx), nil
+}
+
+// planCommand prefixes a command line for display with the cwd and env
+// the command would run with.
+func (o commandOptions) planCommand(ctx *types.ScaffoldContext, args []string) (string, error) {
+	env, err := o.renderEnv(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if o.cwd != "" {
+		fmt.Fprintf(&b, "cd %s && ", shellQuote(o.cwd))
+	}
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		fmt.Fprintf(&b, "%s=%s ", name, shellQuote(value))
+	}
+	b.WriteString(shellJoin(args))
+	return b.String(), nil
+}
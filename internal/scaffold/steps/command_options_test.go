@@ -0,0 +1,92 @@
+package steps
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	anvil_exec "github.com/naoray/anvil/internal/exec"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+func TestCommandOptions_Run(t *testing.T) {
+	cfg := config.StepConfig{
+		Command: "make build",
+		Env:     map[string]string{"APP_URL": "https://{{ .SiteName }}.test", "APP_ENV": "testing"},
+		Cwd:     "frontend",
+		Shell:   "zsh",
+	}
+	ctx := &types.ScaffoldContext{WorktreePath: "/worktrees/feature", SiteName: "myapp"}
+	runCtx := anvil_exec.WithEnv(context.Background(), "ANVIL_BRANCH=feature")
+
+	t.Run("bash.run uses the shell, cwd and env", func(t *testing.T) {
+		mock := anvil_exec.NewMockCommander()
+		step := NewBashRunStepWithExecutor(cfg.Command, "", anvil_exec.NewCommandExecutor(mock))
+		step.options = newCommandOptions(cfg)
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Ctx: runCtx}))
+
+		call := mock.LastCall()
+		require.NotNil(t, call)
+		assert.Equal(t, "zsh", call.Command)
+		assert.Equal(t, []string{"-c", "make build"}, call.Args)
+		assert.Equal(t, filepath.Join("/worktrees/feature", "frontend"), call.Dir)
+		assert.Equal(t, []string{"ANVIL_BRANCH=feature", "APP_ENV=testing", "APP_URL=https://myapp.test"}, call.Env)
+	})
+
+	t.Run("command.run defaults to sh", func(t *testing.T) {
+		mock := anvil_exec.NewMockCommander()
+		step := NewCommandRunStepWithExecutor("make", "", anvil_exec.NewCommandExecutor(mock))
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+
+		call := mock.LastCall()
+		require.NotNil(t, call)
+		assert.Equal(t, "sh", call.Command)
+		assert.Equal(t, "/worktrees/feature", call.Dir)
+		assert.Empty(t, call.Env)
+	})
+
+	t.Run("binary steps use the cwd and env", func(t *testing.T) {
+		mock := anvil_exec.NewMockCommander()
+		step := NewBinaryStepWithCondition("node.npm", config.StepConfig{Args: []string{"install"}, Env: cfg.Env, Cwd: cfg.Cwd}, "npm")
+		step.executor = anvil_exec.NewCommandExecutor(mock)
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+
+		call := mock.LastCall()
+		require.NotNil(t, call)
+		assert.Equal(t, "npm", call.Command)
+		assert.Equal(t, filepath.Join("/worktrees/feature", "frontend"), call.Dir)
+		assert.Equal(t, []string{"APP_ENV=testing", "APP_URL=https://myapp.test"}, call.Env)
+	})
+
+	t.Run("invalid env template fails before running", func(t *testing.T) {
+		mock := anvil_exec.NewMockCommander()
+		step := NewBashRunStepWithExecutor("true", "", anvil_exec.NewCommandExecutor(mock))
+		step.options = commandOptions{env: map[string]string{"KEY": "{{ .missing }}"}}
+
+		err := step.Run(ctx, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "env KEY")
+		assert.Zero(t, mock.CallCount())
+	})
+}
+
+func TestCommandOptions_Plan(t *testing.T) {
+	step, err := Create(config.StepBashRun, config.StepConfig{
+		Command: "make build",
+		Env:     map[string]string{"APP_NAME": "{{ .SiteName }}"},
+		Cwd:     "frontend",
+		Shell:   "sh",
+	})
+	require.NoError(t, err)
+
+	plan, err := step.(*BashRunStep).Plan(&types.ScaffoldContext{SiteName: "my app"})
+	require.NoError(t, err)
+	assert.Equal(t, "cd frontend && APP_NAME='my app' sh -c 'make build'", plan.Command)
+}
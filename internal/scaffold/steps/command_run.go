@@ -1,10 +1,10 @@
 package steps
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
+	"github.com/naoray/anvil/internal/config"
 	anvil_exec "github.com/naoray/anvil/internal/exec"
 	"github.com/naoray/anvil/internal/scaffold/types"
 )
@@ -12,6 +12,7 @@ import (
 type CommandRunStep struct {
 	command  string
 	storeAs  string
+	options  commandOptions
 	executor *anvil_exec.CommandExecutor
 }
 
@@ -20,6 +21,14 @@ func NewCommandRunStep(command string, storeAs string) *CommandRunStep {
 	return NewCommandRunStepWithExecutor(command, storeAs, nil)
 }
 
+// NewCommandRunStepFromConfig creates a command step from its configuration.
+// This is the factory function used by the registry.
+func NewCommandRunStepFromConfig(cfg config.StepConfig) *CommandRunStep {
+	step := NewCommandRunStep(cfg.Command, cfg.StoreAs)
+	step.options = newCommandOptions(cfg)
+	return step
+}
+
 // NewCommandRunStepWithExecutor creates a command step with a custom command executor.
 // This is useful for testing with mock executors.
 func NewCommandRunStepWithExecutor(command string, storeAs string, executor *anvil_exec.CommandExecutor) *CommandRunStep {
@@ -38,10 +47,15 @@ func (s *CommandRunStep) Name() string {
 }
 
 func (s *CommandRunStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	runCtx, dir, err := s.options.prepare(ctx, opts)
+	if err != nil {
+		return err
+	}
+
 	// Use the command executor for testability
-	output, err := s.executor.RunShell(context.Background(), ctx.WorktreePath, s.command)
+	output, err := s.executor.RunInShell(runCtx, dir, s.options.shellOr("sh"), s.command)
 	if err != nil {
-		return fmt.Errorf("command.run failed: %w\n%s", err, string(output))
+		return commandError(opts.Context(), "command.run", err, output)
 	}
 
 	if s.storeAs != "" {
@@ -54,6 +68,15 @@ func (s *CommandRunStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions)
 	return nil
 }
 
+// Plan returns the command line the step would run.
+func (s *CommandRunStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	planned, err := s.options.planCommand(ctx, []string{s.options.shellOr("sh"), "-c", s.command})
+	if err != nil {
+		return types.StepPlan{}, err
+	}
+	return types.StepPlan{Command: planned}, nil
+}
+
 func (s *CommandRunStep) Condition(ctx *types.ScaffoldContext) bool {
 	return true
 }
@@ -0,0 +1,75 @@
+package steps
+
+import (
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// conditionalStep guards a step with the condition from its configuration.
+// The step's own condition (e.g. "the source file exists") still applies
+// when the configured condition holds, except for binary steps, whose
+// configured condition replaces the check for the binary.
+type conditionalStep struct {
+	types.ScaffoldStep
+	condition *types.Condition
+}
+
+func newConditionalStep(step types.ScaffoldStep, condition *types.Condition) *conditionalStep {
+	return &conditionalStep{ScaffoldStep: step, condition: condition}
+}
+
+// CheckCondition reports whether the step should run. Unlike Condition it
+// returns evaluation errors, which the executor reports as step failures.
+func (s *conditionalStep) CheckCondition(ctx *types.ScaffoldContext) (bool, error) {
+	ok, err := s.condition.Evaluate(ctx)
+	if err != nil || !ok {
+		return false, err
+	}
+	if _, isBinary := s.ScaffoldStep.(*BinaryStep); isBinary {
+		return true, nil
+	}
+	return s.ScaffoldStep.Condition(ctx), nil
+}
+
+// Condition reports whether the step should run, treating evaluation
+// errors as false.
+func (s *conditionalStep) Condition(ctx *types.ScaffoldContext) bool {
+	ok, err := s.CheckCondition(ctx)
+	return ok && err == nil
+}
+
+// GetArgs forwards to the wrapped step so step descriptions keep working.
+func (s *conditionalStep) GetArgs() []string {
+	if argGetter, ok := s.ScaffoldStep.(interface{ GetArgs() []string }); ok {
+		return argGetter.GetArgs()
+	}
+	return nil
+}
+
+// Plan forwards to the wrapped step.
+func (s *conditionalStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	if planner, ok := s.ScaffoldStep.(interface {
+		Plan(*types.ScaffoldContext) (types.StepPlan, error)
+	}); ok {
+		return planner.Plan(ctx)
+	}
+	return types.StepPlan{}, nil
+}
+
+// Interactive forwards to the wrapped step.
+func (s *conditionalStep) Interactive() bool {
+	interactive, ok := s.ScaffoldStep.(interface{ Interactive() bool })
+	return ok && interactive.Interactive()
+}
+
+// SkipReason explains why the condition is not met.
+func (s *conditionalStep) SkipReason(ctx *types.ScaffoldContext) string {
+	if met, err := s.condition.Evaluate(ctx); err == nil && !met {
+		return "condition not met"
+	}
+	if explainer, ok := s.ScaffoldStep.(interface {
+		SkipReason(*types.ScaffoldContext) string
+	}); ok {
+		return explainer.SkipReason(ctx)
+	}
+	return "condition not met"
+}
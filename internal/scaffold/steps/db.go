@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/naoray/anvil/internal/config"
 	"github.com/naoray/anvil/internal/scaffold/types"
@@ -11,9 +12,10 @@ import (
 	"github.com/naoray/anvil/internal/utils"
 )
 
-// detectDatabaseEngine determines the database engine from explicit type or .env file.
-// This is shared between DbCreateStep and DbDestroyStep.
-func detectDatabaseEngine(dbType string, ctx *types.ScaffoldContext) (config.DatabaseEngine, error) {
+// DetectDatabaseEngine determines the database engine from explicit type or
+// the .env file of the worktree at worktreePath. This is shared between the
+// database steps and the anvil db commands.
+func DetectDatabaseEngine(dbType string, worktreePath string) (config.DatabaseEngine, error) {
 	if dbType != "" {
 		switch dbType {
 		case string(config.DBEngineMySQL), string(config.DBEnginePgSQL), string(config.DBEngineSQLite):
@@ -23,7 +25,7 @@ func detectDatabaseEngine(dbType string, ctx *types.ScaffoldContext) (config.Dat
 		}
 	}
 
-	env := utils.ReadEnvFile(ctx.WorktreePath, ".env")
+	env := utils.ReadEnvFile(worktreePath, ".env")
 	if conn := env["DB_CONNECTION"]; conn != "" {
 		switch conn {
 		case "mysql", "mariadb":
@@ -72,7 +74,7 @@ func (s *DbCreateStep) Condition(ctx *types.ScaffoldContext) bool {
 }
 
 func (s *DbCreateStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
-	engine, err := detectDatabaseEngine(s.dbType, ctx)
+	engine, err := DetectDatabaseEngine(s.dbType, ctx.WorktreePath)
 	if err != nil {
 		if opts.Verbose {
 			fmt.Printf("  %v\n", err)
@@ -85,23 +87,32 @@ func (s *DbCreateStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) e
 	}
 
 	if engine == config.DBEngineSQLite {
-		dbName := ""
-		for i, arg := range s.args {
-			if arg == "--database" && i+1 < len(s.args) {
-				dbName = s.args[i+1]
-			}
-		}
-		if dbName == "" {
-			env := utils.ReadEnvFile(ctx.WorktreePath, ".env")
-			dbName = env["DB_DATABASE"]
-		}
-		if dbName == "" {
-			dbName = "database/database.sqlite"
-		}
-		return s.createSqlite(ctx, dbName, opts)
+		return s.createSqlite(ctx, sqliteDatabase(s.args, ctx.WorktreePath), opts)
 	}
 
-	return s.createWithRetry(ctx, engine, opts)
+	return s.createWithRetry(ctx, engine, opts, func(client DatabaseClient, dbName string) error {
+		return client.CreateDatabase(dbName)
+	})
+}
+
+// sqliteDatabase returns the SQLite database file of the worktree at
+// worktreePath: the --database argument, DB_DATABASE of its .env, or
+// database/database.sqlite.
+func sqliteDatabase(args []string, worktreePath string) string {
+	dbName := ""
+	for i, arg := range args {
+		if arg == "--database" && i+1 < len(args) {
+			dbName = args[i+1]
+		}
+	}
+	if dbName == "" {
+		env := utils.ReadEnvFile(worktreePath, ".env")
+		dbName = env["DB_DATABASE"]
+	}
+	if dbName == "" {
+		dbName = "database/database.sqlite"
+	}
+	return dbName
 }
 
 func (s *DbCreateStep) getPrefixOrSiteName(ctx *types.ScaffoldContext) string {
@@ -122,35 +133,20 @@ func (s *DbCreateStep) getPrefixOrSiteName(ctx *types.ScaffoldContext) string {
 	return siteName
 }
 
-func (s *DbCreateStep) parseConnectionOptions() DatabaseOptions {
-	opts := DatabaseOptions{
-		Host:     "127.0.0.1",
-		Username: "root",
-	}
-
-	for i, arg := range s.args {
-		if arg == "--username" && i+1 < len(s.args) {
-			opts.Username = s.args[i+1]
-		}
-		if arg == "--password" && i+1 < len(s.args) {
-			opts.Password = s.args[i+1]
-		}
-		if arg == "--host" && i+1 < len(s.args) {
-			opts.Host = s.args[i+1]
-		}
-		if arg == "--port" && i+1 < len(s.args) {
-			opts.Port = s.args[i+1]
-		}
-	}
-
-	return opts
-}
-
 const maxDbCreateRetries = 5
 
-func (s *DbCreateStep) createWithRetry(ctx *types.ScaffoldContext, engine config.DatabaseEngine, opts types.StepOptions) error {
+// createWithRetry creates a database named after the site and the suffix
+// of the worktree with create, generating a new suffix while the name is
+// taken. db.clone creates its database through it as well.
+func (s *DbCreateStep) createWithRetry(ctx *types.ScaffoldContext, engine config.DatabaseEngine, opts types.StepOptions, create func(client DatabaseClient, dbName string) error) error {
 	siteName := s.getPrefixOrSiteName(ctx)
-	dbOpts := s.parseConnectionOptions()
+	dbOpts, err := ResolveConnectionOptions(engine, s.args, ctx.WorktreePath)
+	if err != nil {
+		return fmt.Errorf("database connection: %w", err)
+	}
+	if opts.Verbose {
+		fmt.Printf("  Connecting to %s (%s)...\n", dbOpts, engine)
+	}
 
 	client, err := s.clientFactory(string(engine), dbOpts)
 	if err != nil {
@@ -184,7 +180,7 @@ func (s *DbCreateStep) createWithRetry(ctx *types.ScaffoldContext, engine config
 			fmt.Printf("  Generated database name: %s (attempt %d/%d)\n", dbName, attempt+1, maxDbCreateRetries)
 		}
 
-		err := client.CreateDatabase(dbName)
+		err := create(client, dbName)
 		if err == nil {
 			if opts.Verbose {
 				fmt.Printf("  Database '%s' created successfully.\n", dbName)
@@ -194,6 +190,11 @@ func (s *DbCreateStep) createWithRetry(ctx *types.ScaffoldContext, engine config
 					fmt.Printf("  warning: failed to persist db_suffix: %v\n", err)
 				}
 			}
+			if err := registerDatabase(ctx, engine, dbOpts, dbName); err != nil {
+				if opts.Verbose {
+					fmt.Printf("  warning: failed to register database: %v\n", err)
+				}
+			}
 			return nil
 		}
 
@@ -221,6 +222,18 @@ func (s *DbCreateStep) persistDbSuffix(ctx *types.ScaffoldContext) error {
 	return config.WriteLocalState(ctx.WorktreePath, config.LocalState{DbSuffix: suffix})
 }
 
+// registerDatabase records a database created for the worktree of ctx, so
+// db.destroy drops it.
+func registerDatabase(ctx *types.ScaffoldContext, engine config.DatabaseEngine, dbOpts DatabaseOptions, dbName string) error {
+	return config.RegisterDatabase(config.DatabaseRecord{
+		Engine:   engine,
+		Host:     dbOpts.Address(),
+		Name:     dbName,
+		Project:  ctx.ProjectName,
+		Worktree: ctx.WorktreePath,
+	})
+}
+
 func (s *DbCreateStep) createSqlite(ctx *types.ScaffoldContext, dbName string, opts types.StepOptions) error {
 	dbPath := filepath.Join(ctx.WorktreePath, dbName)
 
@@ -304,7 +317,7 @@ func (s *DbDestroyStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions)
 
 	ctx.SetDbSuffix(suffix)
 
-	engine, err := detectDatabaseEngine(s.dbType, ctx)
+	engine, err := DetectDatabaseEngine(s.dbType, ctx.WorktreePath)
 	if err != nil {
 		if opts.Verbose {
 			fmt.Printf("  %v\n", err)
@@ -313,49 +326,27 @@ func (s *DbDestroyStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions)
 	}
 
 	if opts.Verbose {
-		fmt.Printf("  Cleaning up databases matching suffix: %s\n", suffix)
+		fmt.Printf("  Cleaning up databases registered with suffix: %s\n", suffix)
 	}
 
 	if engine == config.DBEngineSQLite {
 		return nil
 	}
 
-	return s.destroyDatabases(engine, suffix, opts)
+	return s.destroyDatabases(ctx, engine, suffix, opts)
 }
 
-func (s *DbDestroyStep) parseConnectionOptions(engine config.DatabaseEngine) DatabaseOptions {
-	opts := DatabaseOptions{
-		Host: "127.0.0.1",
-	}
-
-	if engine == config.DBEnginePgSQL {
-		opts.Username = "postgres"
-		opts.Port = "5432"
-	} else {
-		opts.Username = "root"
-		opts.Port = "3306"
-	}
-
-	for i, arg := range s.args {
-		if arg == "--username" && i+1 < len(s.args) {
-			opts.Username = s.args[i+1]
-		}
-		if arg == "--password" && i+1 < len(s.args) {
-			opts.Password = s.args[i+1]
-		}
-		if arg == "--host" && i+1 < len(s.args) {
-			opts.Host = s.args[i+1]
-		}
-		if arg == "--port" && i+1 < len(s.args) {
-			opts.Port = s.args[i+1]
+func (s *DbDestroyStep) destroyDatabases(ctx *types.ScaffoldContext, engine config.DatabaseEngine, suffix string, opts types.StepOptions) error {
+	dbOpts, err := ResolveConnectionOptions(engine, s.args, ctx.WorktreePath)
+	if err != nil {
+		if opts.Verbose {
+			fmt.Printf("  Could not resolve database connection: %v\n", err)
 		}
+		return nil
+	}
+	if opts.Verbose {
+		fmt.Printf("  Connecting to %s (%s)...\n", dbOpts, engine)
 	}
-
-	return opts
-}
-
-func (s *DbDestroyStep) destroyDatabases(engine config.DatabaseEngine, suffix string, opts types.StepOptions) error {
-	dbOpts := s.parseConnectionOptions(engine)
 
 	client, err := s.clientFactory(string(engine), dbOpts)
 	if err != nil {
@@ -373,41 +364,71 @@ func (s *DbDestroyStep) destroyDatabases(engine config.DatabaseEngine, suffix st
 		return nil
 	}
 
-	pattern := fmt.Sprintf("%%_%s", suffix)
-	databases, err := client.ListDatabases(pattern)
+	records, err := config.ReadDatabaseRegistry()
 	if err != nil {
 		if opts.Verbose {
-			fmt.Printf("  Failed to list databases: %v\n", err)
+			fmt.Printf("  Could not read database registry: %v\n", err)
 		}
 		return nil
 	}
+	var owned []config.DatabaseRecord
+	for _, record := range records {
+		if record.OnServer(engine, dbOpts.Address()) && record.OwnedBy(ctx.WorktreePath) {
+			owned = append(owned, record)
+		}
+	}
+
+	// Suffixes come from a small word list, so databases of other projects
+	// may carry the same one. They are reported, never dropped. Databases
+	// created before the registry existed are not registered either.
+	pattern := fmt.Sprintf("%%_%s", suffix)
+	if databases, err := client.ListDatabases(pattern); err == nil {
+		for _, dbName := range databases {
+			i := slices.IndexFunc(records, func(r config.DatabaseRecord) bool {
+				return r.OnServer(engine, dbOpts.Address()) && r.Name == dbName
+			})
+			switch {
+			case i < 0:
+				if opts.Verbose {
+					fmt.Printf("  warning: kept database %s: it is not registered and may belong to another project (drop it with 'anvil db gc' if it is left over)\n", dbName)
+				}
+			case !records[i].OwnedBy(ctx.WorktreePath) && opts.Verbose:
+				fmt.Printf("  Skipping database %s: registered for %s\n", dbName, records[i].Worktree)
+			}
+		}
+	}
 
-	if len(databases) == 0 {
+	if len(owned) == 0 {
 		if opts.Verbose {
-			fmt.Printf("  No databases matching pattern found.\n")
+			fmt.Printf("  No databases registered for this worktree.\n")
 		}
 		return nil
 	}
 
-	for _, dbName := range databases {
+	var dropped []config.DatabaseRecord
+	for _, record := range owned {
 		if opts.DryRun {
 			if opts.Verbose {
-				fmt.Printf("  Would drop database: %s\n", dbName)
+				fmt.Printf("  Would drop database: %s\n", record.Name)
 			}
 			continue
 		}
 
-		if err := client.DropDatabase(dbName); err != nil {
+		if err := client.DropDatabase(record.Name); err != nil {
 			if opts.Verbose {
-				fmt.Printf("  Failed to drop database %s: %v\n", dbName, err)
+				fmt.Printf("  Failed to drop database %s: %v\n", record.Name, err)
 			}
 			continue
 		}
+		dropped = append(dropped, record)
 
 		if opts.Verbose {
-			fmt.Printf("  Dropped database: %s\n", dbName)
+			fmt.Printf("  Dropped database: %s\n", record.Name)
 		}
 	}
 
+	if err := config.UnregisterDatabases(dropped...); err != nil && opts.Verbose {
+		fmt.Printf("  warning: failed to update database registry: %v\n", err)
+	}
 	return nil
 }
@@ -0,0 +1,178 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+	"github.com/naoray/anvil/internal/scaffold/words"
+	"github.com/naoray/anvil/internal/utils"
+)
+
+// DbCloneStep creates the database of a worktree like db.create, but as a
+// copy of the database of another worktree, by default the worktree of the
+// default branch. The copy is made on the database server rather than
+// through a dump; for MySQL that copies tables, rows and views, but not
+// triggers and stored routines. Cancelling the run stops the copy and
+// drops the partly copied database.
+type DbCloneStep struct {
+	name   string
+	args   []string
+	source string
+	create *DbCreateStep
+}
+
+func NewDbCloneStep(cfg config.StepConfig) *DbCloneStep {
+	return NewDbCloneStepWithFactory(cfg, DefaultDatabaseClientFactory)
+}
+
+func NewDbCloneStepWithFactory(cfg config.StepConfig, factory DatabaseClientFactory) *DbCloneStep {
+	return &DbCloneStep{
+		name:   config.StepDbClone,
+		args:   cfg.Args,
+		source: cfg.Source,
+		create: NewDbCreateStepWithFactory(cfg, factory),
+	}
+}
+
+func (s *DbCloneStep) Name() string {
+	return s.name
+}
+
+func (s *DbCloneStep) Condition(ctx *types.ScaffoldContext) bool {
+	return true
+}
+
+func (s *DbCloneStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	engine, err := DetectDatabaseEngine(s.create.dbType, ctx.WorktreePath)
+	if err != nil {
+		if opts.Verbose {
+			fmt.Printf("  %v\n", err)
+		}
+		return nil
+	}
+
+	source := s.sourceWorktree(ctx)
+	if source == "" {
+		return fmt.Errorf("no worktree to clone the database from, set 'source'")
+	}
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("source worktree: %w", err)
+	}
+	// The default branch worktree has no other database to start from
+	if sameDir(ctx.WorktreePath, source) {
+		if opts.Verbose {
+			fmt.Printf("  %s is the source worktree, creating an empty database instead.\n", ctx.WorktreePath)
+		}
+		return s.create.Run(ctx, opts)
+	}
+
+	if engine == config.DBEngineSQLite {
+		return s.cloneSqlite(ctx, source, opts)
+	}
+
+	sourceDB, err := s.sourceDatabase(ctx, source)
+	if err != nil {
+		return err
+	}
+	if opts.Verbose {
+		fmt.Printf("  Cloning database %s (%s)...\n", sourceDB, engine)
+	}
+	return s.create.createWithRetry(ctx, engine, opts, func(client DatabaseClient, dbName string) error {
+		return client.CloneDatabase(opts.Context(), sourceDB, dbName)
+	})
+}
+
+// sourceWorktree returns the path of the worktree to clone the database
+// of. A relative source is relative to the worktree.
+func (s *DbCloneStep) sourceWorktree(ctx *types.ScaffoldContext) string {
+	if s.source == "" {
+		return ctx.MainWorktree
+	}
+	if filepath.IsAbs(s.source) {
+		return s.source
+	}
+	return filepath.Join(ctx.WorktreePath, s.source)
+}
+
+// sourceDatabase returns the name of the database of the source worktree.
+// The site name of the worktree does not apply to the source. An explicit
+// --prefix names the databases of every worktree alike; otherwise the
+// default branch worktree's database is named after the project's site
+// name.
+func (s *DbCloneStep) sourceDatabase(ctx *types.ScaffoldContext, source string) (string, error) {
+	prefix := argValue(s.args, "--prefix")
+	if prefix == "" && sameDir(source, ctx.MainWorktree) {
+		prefix = ctx.MainSiteName
+	}
+	return WorktreeDatabase(source, prefix)
+}
+
+// WorktreeDatabase returns the name of the database of the worktree at
+// worktreePath: the database db.create named after siteName and the
+// db_suffix in its .anvil.local if siteName is set, or else DB_DATABASE
+// of its .env.
+func WorktreeDatabase(worktreePath, siteName string) (string, error) {
+	state, err := config.ReadLocalState(worktreePath)
+	if err != nil {
+		return "", err
+	}
+	if state.DbSuffix != "" && siteName != "" {
+		return words.BuildDatabaseName(siteName, state.DbSuffix, 0), nil
+	}
+	if name := utils.ReadEnvFile(worktreePath, ".env")["DB_DATABASE"]; name != "" {
+		return name, nil
+	}
+	if state.DbSuffix != "" {
+		return "", fmt.Errorf("no database found in %s: DB_DATABASE in .env is not set and the site name its db_suffix belongs to is unknown (set site_name or pass --prefix)", worktreePath)
+	}
+	return "", fmt.Errorf("no database found in %s: neither db_suffix in %s nor DB_DATABASE in .env is set", worktreePath, config.LocalStateFile)
+}
+
+// sameDir reports whether a and b are the same existing directory.
+func sameDir(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	return err == nil && os.SameFile(aInfo, bInfo)
+}
+
+// WorktreeSqliteFile returns the path of the SQLite database file of the
+// worktree at worktreePath, see sqliteDatabase.
+func WorktreeSqliteFile(worktreePath string, args []string) string {
+	return sqlitePath(worktreePath, sqliteDatabase(args, worktreePath))
+}
+
+// cloneSqlite copies the SQLite database file of the source worktree over
+// the database file of the worktree.
+func (s *DbCloneStep) cloneSqlite(ctx *types.ScaffoldContext, source string, opts types.StepOptions) error {
+	from := WorktreeSqliteFile(source, s.args)
+	to := WorktreeSqliteFile(ctx.WorktreePath, s.args)
+	if from == to {
+		return fmt.Errorf("the worktree already uses the SQLite database %s of %s", from, source)
+	}
+
+	if opts.Verbose {
+		fmt.Printf("  Copying SQLite database %s to %s\n", from, to)
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	return utils.CopyFile(from, to)
+}
+
+// sqlitePath returns the path of the SQLite database dbName of a worktree.
+func sqlitePath(worktreePath, dbName string) string {
+	if filepath.IsAbs(dbName) {
+		return dbName
+	}
+	return filepath.Join(worktreePath, dbName)
+}
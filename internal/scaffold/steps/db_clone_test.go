@@ -0,0 +1,272 @@
+package steps
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+	"github.com/naoray/anvil/internal/scaffold/words"
+)
+
+// cloneWorktrees creates a default branch worktree and a new worktree,
+// both with the given .env.
+func cloneWorktrees(t *testing.T, env string) (main, worktree string) {
+	t.Helper()
+	main, worktree = t.TempDir(), t.TempDir()
+	for _, dir := range []string{main, worktree} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0644))
+	}
+	return main, worktree
+}
+
+func TestDbCloneStep(t *testing.T) {
+	t.Run("name returns db.clone", func(t *testing.T) {
+		step := NewDbCloneStep(config.StepConfig{})
+		assert.Equal(t, "db.clone", step.Name())
+	})
+
+	t.Run("clones the database named by the prefix and suffix of the default branch", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+		require.NoError(t, config.WriteLocalState(main, config.LocalState{DbSuffix: "swift_runner"}))
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("testapp_swift_runner")
+		step := NewDbCloneStepWithFactory(config.StepConfig{Args: []string{"--prefix", "testapp"}}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "testapp"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+
+		suffix := ctx.GetDbSuffix()
+		require.NotEmpty(t, suffix)
+		assert.NotEqual(t, "swift_runner", suffix)
+		assert.Equal(t, [][2]string{{"testapp_swift_runner", "testapp_" + suffix}}, mockClient.GetCloneCalls())
+
+		state, err := config.ReadLocalState(worktree)
+		require.NoError(t, err)
+		assert.Equal(t, suffix, state.DbSuffix, "the suffix is saved for db.destroy")
+	})
+
+	t.Run("finds the source database through DB_DATABASE without a prefix", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=pgsql\n")
+		require.NoError(t, os.WriteFile(filepath.Join(main, ".env"), []byte("DB_CONNECTION=pgsql\nDB_DATABASE=shop_swift_runner\n"), 0644))
+		require.NoError(t, config.WriteLocalState(main, config.LocalState{DbSuffix: "swift_runner"}))
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("shop_swift_runner")
+		step := NewDbCloneStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "feature-auth"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		calls := mockClient.GetCloneCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "shop_swift_runner", calls[0][0])
+		assert.Equal(t, words.BuildDatabaseName("feature-auth", ctx.GetDbSuffix(), 0), calls[0][1])
+	})
+
+	t.Run("source option", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+		other := filepath.Join(filepath.Dir(worktree), "staging")
+		require.NoError(t, os.Mkdir(other, 0755))
+		t.Cleanup(func() { _ = os.RemoveAll(other) })
+		require.NoError(t, config.WriteLocalState(other, config.LocalState{DbSuffix: "calm_river"}))
+		require.NoError(t, os.WriteFile(filepath.Join(other, ".env"), []byte("DB_CONNECTION=mysql\nDB_DATABASE=staging_calm_river\n"), 0644))
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("staging_calm_river")
+		step := NewDbCloneStepWithFactory(config.StepConfig{Source: "../staging"}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "app", MainSiteName: "shop"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		calls := mockClient.GetCloneCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "staging_calm_river", calls[0][0], "the project's site name only names the default branch database")
+	})
+
+	t.Run("names the default branch database after the project's site name", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+		require.NoError(t, config.WriteLocalState(main, config.LocalState{DbSuffix: "swift_runner"}))
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("shop_swift_runner")
+		step := NewDbCloneStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "feature-auth", MainSiteName: "shop"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		calls := mockClient.GetCloneCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "shop_swift_runner", calls[0][0])
+	})
+
+	t.Run("stops when the run is cancelled", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+		require.NoError(t, config.WriteLocalState(main, config.LocalState{DbSuffix: "swift_runner"}))
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("testapp_swift_runner")
+		step := NewDbCloneStepWithFactory(config.StepConfig{Args: []string{"--prefix", "testapp"}}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "testapp"}
+		runCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := step.Run(ctx, types.StepOptions{Ctx: runCtx})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.False(t, mockClient.HasDatabase("testapp_"+ctx.GetDbSuffix()))
+	})
+
+	t.Run("creates an empty database in the default branch worktree", func(t *testing.T) {
+		main, _ := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+
+		mockClient := NewMockDatabaseClient()
+		step := NewDbCloneStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: main, MainWorktree: main, SiteName: "app"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		assert.Empty(t, mockClient.GetCloneCalls())
+		assert.Equal(t, []string{"app_" + ctx.GetDbSuffix()}, mockClient.GetCreateCalls())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=mysql\n")
+		mockClient := NewMockDatabaseClient()
+		step := NewDbCloneStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+
+		err := step.Run(&types.ScaffoldContext{WorktreePath: worktree}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no worktree to clone the database from")
+
+		err = step.Run(&types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no database found in")
+
+		require.NoError(t, config.WriteLocalState(main, config.LocalState{DbSuffix: "swift_runner"}))
+		err = step.Run(&types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "app"}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the site name its db_suffix belongs to is unknown", "the folder name is not guessed")
+
+		err = step.Run(&types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main, SiteName: "app", MainSiteName: "shop"}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "source database shop_swift_runner does not exist")
+	})
+
+	t.Run("copies the SQLite database file", func(t *testing.T) {
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=sqlite\n")
+		require.NoError(t, os.MkdirAll(filepath.Join(main, "database"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(main, "database", "database.sqlite"), []byte("SQLite format 3"), 0644))
+
+		step := NewDbCloneStep(config.StepConfig{})
+		ctx := &types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		content, err := os.ReadFile(filepath.Join(worktree, "database", "database.sqlite"))
+		require.NoError(t, err)
+		assert.Equal(t, "SQLite format 3", string(content))
+	})
+
+	t.Run("refuses to copy a shared SQLite database onto itself", func(t *testing.T) {
+		shared := filepath.Join(t.TempDir(), "app.sqlite")
+		require.NoError(t, os.WriteFile(shared, []byte("SQLite format 3"), 0644))
+		main, worktree := cloneWorktrees(t, "DB_CONNECTION=sqlite\nDB_DATABASE="+shared+"\n")
+
+		step := NewDbCloneStep(config.StepConfig{})
+		err := step.Run(&types.ScaffoldContext{WorktreePath: worktree, MainWorktree: main}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already uses the SQLite database")
+	})
+}
+
+// TestDatabaseClient_CloneDatabase clones a database on the local MySQL and
+// PostgreSQL servers the clients connect to by default. Servers that are
+// not running are skipped.
+func TestDatabaseClient_CloneDatabase(t *testing.T) {
+	tests := []struct {
+		engine string
+		dsn    func(database string) string
+		schema []string
+	}{
+		{
+			engine: "mysql",
+			dsn:    func(database string) string { return "root:@tcp(127.0.0.1:3306)/" + database },
+			schema: []string{
+				"CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50), upper_name VARCHAR(50) AS (UPPER(name)))",
+				"CREATE TABLE posts (id INT PRIMARY KEY, user_id INT, FOREIGN KEY (user_id) REFERENCES users (id))",
+				"INSERT INTO users (id, name) VALUES (1, 'ada'), (2, 'linus')",
+				"INSERT INTO posts VALUES (1, 1)",
+				"CREATE VIEW user_names AS SELECT name FROM users",
+			},
+		},
+		{
+			engine: "pgsql",
+			dsn: func(database string) string {
+				return "host=127.0.0.1 port=5432 user=postgres dbname=" + database + " sslmode=disable"
+			},
+			schema: []string{
+				"CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50))",
+				"CREATE TABLE posts (id INT PRIMARY KEY, user_id INT REFERENCES users (id))",
+				"INSERT INTO users VALUES (1, 'ada'), (2, 'linus')",
+				"INSERT INTO posts VALUES (1, 1)",
+				"CREATE VIEW user_names AS SELECT name FROM users",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			client, err := DefaultDatabaseClientFactory(tt.engine, DatabaseOptions{})
+			require.NoError(t, err)
+			defer func() { _ = client.Close() }()
+			if err := client.Ping(); err != nil {
+				t.Skipf("no local %s server: %v", tt.engine, err)
+			}
+
+			suffix := words.GenerateSuffix()
+			source, target := "anvil_test_"+suffix, "anvil_test_clone_"+suffix
+			require.NoError(t, client.CreateDatabase(source))
+			t.Cleanup(func() {
+				_ = client.DropDatabase(target)
+				_ = client.DropDatabase(source)
+			})
+
+			driver := map[string]string{"mysql": "mysql", "pgsql": "pgx"}[tt.engine]
+			db, err := sql.Open(driver, tt.dsn(source))
+			require.NoError(t, err)
+			for _, statement := range tt.schema {
+				_, err := db.Exec(statement)
+				require.NoError(t, err, statement)
+			}
+			// PostgreSQL only clones databases nobody is connected to
+			require.NoError(t, db.Close())
+
+			cancelled, cancel := context.WithCancel(context.Background())
+			cancel()
+			require.ErrorIs(t, client.CloneDatabase(cancelled, source, target), context.Canceled)
+			databases, err := client.ListDatabases(target)
+			require.NoError(t, err)
+			assert.Empty(t, databases, "a cancelled clone leaves no database behind")
+
+			require.NoError(t, client.CloneDatabase(context.Background(), source, target))
+			assert.True(t, IsDatabaseExistsError(client.CloneDatabase(context.Background(), source, target)))
+
+			cloned, err := sql.Open(driver, tt.dsn(target))
+			require.NoError(t, err)
+			defer func() { _ = cloned.Close() }()
+			for query, want := range map[string]int{
+				"SELECT COUNT(*) FROM users":      2,
+				"SELECT COUNT(*) FROM posts":      1,
+				"SELECT COUNT(*) FROM user_names": 2,
+			} {
+				var got int
+				require.NoError(t, cloned.QueryRow(query).Scan(&got), query)
+				assert.Equal(t, want, got, query)
+			}
+			_, err = cloned.Exec("INSERT INTO posts VALUES (2, 99)")
+			assert.Error(t, err, "the foreign key is cloned")
+		})
+	}
+}
@@ -181,6 +181,39 @@ func TestDbCreateStep(t *testing.T) {
 		assert.Equal(t, suffix, localState.DbSuffix, "DbSuffix should be persisted to .anvil.local")
 	})
 
+	t.Run("registers the database for db.destroy", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		envFile := filepath.Join(tmpDir, ".env")
+		if err := os.WriteFile(envFile, []byte("DB_CONNECTION=pgsql\n"), 0644); err != nil {
+			t.Fatalf("writing env file: %v", err)
+		}
+
+		mockClient := NewMockDatabaseClient()
+		step := NewDbCreateStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{
+			WorktreePath: tmpDir,
+			SiteName:     "testapp",
+			ProjectName:  "testapp",
+		}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Verbose: false}))
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		var owned []config.DatabaseRecord
+		for _, record := range records {
+			if record.OwnedBy(tmpDir) {
+				owned = append(owned, record)
+			}
+		}
+		require.Len(t, owned, 1)
+		assert.Equal(t, config.DBEnginePgSQL, owned[0].Engine)
+		assert.Equal(t, "127.0.0.1:5432", owned[0].Host)
+		assert.Equal(t, "testapp_"+ctx.GetDbSuffix(), owned[0].Name)
+		assert.Equal(t, "testapp", owned[0].Project)
+	})
+
 	t.Run("reads APP_NAME from .env if SiteName is empty", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
@@ -510,7 +543,7 @@ func TestDbDestroyStep(t *testing.T) {
 		assert.Equal(t, "%_swift_runner", listCalls[0])
 	})
 
-	t.Run("drops databases matching suffix", func(t *testing.T) {
+	t.Run("drops the registered databases of the worktree", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
 		envFile := filepath.Join(tmpDir, ".env")
@@ -519,8 +552,13 @@ func TestDbDestroyStep(t *testing.T) {
 		}
 
 		mockClient := NewMockDatabaseClient()
-		mockClient.AddDatabase("app1_test_suffix")
-		mockClient.AddDatabase("app2_test_suffix")
+		for _, name := range []string{"app1_test_suffix", "app2_test_suffix", "other_test_suffix", "shop_test_suffix"} {
+			mockClient.AddDatabase(name)
+		}
+		registerTestDatabase(t, tmpDir, "app1_test_suffix")
+		registerTestDatabase(t, tmpDir, "app2_test_suffix")
+		// Another project that drew the same suffix
+		registerTestDatabase(t, t.TempDir(), "shop_test_suffix")
 
 		step := NewDbDestroyStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
 		ctx := &types.ScaffoldContext{
@@ -531,9 +569,55 @@ func TestDbDestroyStep(t *testing.T) {
 		err := step.Run(ctx, types.StepOptions{Verbose: false})
 		assert.NoError(t, err)
 
-		dropCalls := mockClient.GetDropCalls()
-		assert.Len(t, dropCalls, 2, "Should have dropped 2 databases")
-		assert.Equal(t, 0, mockClient.DatabaseCount(), "All databases should be dropped")
+		assert.ElementsMatch(t, []string{"app1_test_suffix", "app2_test_suffix"}, mockClient.GetDropCalls())
+		assert.True(t, mockClient.HasDatabase("other_test_suffix"), "unregistered databases are kept")
+		assert.True(t, mockClient.HasDatabase("shop_test_suffix"), "databases of other worktrees are kept")
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		for _, record := range records {
+			assert.False(t, record.OwnedBy(tmpDir), "dropped databases are unregistered")
+		}
+	})
+
+	t.Run("keeps databases registered on another server", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		envFile := filepath.Join(tmpDir, ".env")
+		if err := os.WriteFile(envFile, []byte("DB_CONNECTION=mysql\n"), 0644); err != nil {
+			t.Fatalf("writing env file: %v", err)
+		}
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("app_test_suffix")
+		require.NoError(t, config.RegisterDatabase(config.DatabaseRecord{
+			Engine: config.DBEngineMySQL, Host: "db.internal:3306", Name: "app_test_suffix", Worktree: tmpDir,
+		}))
+
+		step := NewDbDestroyStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: tmpDir}
+		ctx.SetDbSuffix("test_suffix")
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		assert.Empty(t, mockClient.GetDropCalls())
+	})
+
+	t.Run("matches the server however its host is written", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		writeEnv(t, tmpDir, "DB_CONNECTION=mysql\nDB_HOST=localhost\n")
+
+		mockClient := NewMockDatabaseClient()
+		mockClient.AddDatabase("app_test_suffix")
+		require.NoError(t, config.RegisterDatabase(config.DatabaseRecord{
+			Engine: config.DBEngineMySQL, Host: "127.0.0.1", Name: "app_test_suffix", Worktree: tmpDir,
+		}))
+
+		step := NewDbDestroyStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
+		ctx := &types.ScaffoldContext{WorktreePath: tmpDir}
+		ctx.SetDbSuffix("test_suffix")
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+		assert.Equal(t, []string{"app_test_suffix"}, mockClient.GetDropCalls())
 	})
 
 	t.Run("auto-detects mysql engine from DB_CONNECTION env", func(t *testing.T) {
@@ -681,6 +765,7 @@ func TestDbDestroyStep(t *testing.T) {
 
 		mockClient := NewMockDatabaseClient()
 		mockClient.AddDatabase("app_test_suffix")
+		registerTestDatabase(t, tmpDir, "app_test_suffix")
 
 		step := NewDbDestroyStepWithFactory(config.StepConfig{}, MockClientFactory(mockClient))
 		ctx := &types.ScaffoldContext{
@@ -697,6 +782,18 @@ func TestDbDestroyStep(t *testing.T) {
 	})
 }
 
+// registerTestDatabase registers a MySQL database on the default server
+// for the worktree at worktreePath.
+func registerTestDatabase(t *testing.T, worktreePath, name string) {
+	t.Helper()
+	require.NoError(t, config.RegisterDatabase(config.DatabaseRecord{
+		Engine:   config.DBEngineMySQL,
+		Host:     "127.0.0.1:3306",
+		Name:     name,
+		Worktree: worktreePath,
+	}))
+}
+
 func TestIsDatabaseExistsError(t *testing.T) {
 	t.Run("returns true for DatabaseExistsError", func(t *testing.T) {
 		err := &DatabaseExistsError{Name: "test"}
@@ -1,11 +1,15 @@
 package steps
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/naoray/anvil/internal/config"
@@ -14,6 +18,10 @@ import (
 // DatabaseClient abstracts database operations for testability
 type DatabaseClient interface {
 	CreateDatabase(name string) error
+	// CloneDatabase creates the database target with the schema and data
+	// of source. It returns a *DatabaseExistsError if target exists.
+	// Cancelling ctx stops the copy and drops the partly copied target.
+	CloneDatabase(ctx context.Context, source, target string) error
 	DropDatabase(name string) error
 	ListDatabases(pattern string) ([]string, error)
 	Ping() error
@@ -61,8 +69,13 @@ func NewMySQLClient(opts DatabaseOptions) (*MySQLClient, error) {
 		opts.Username = "root"
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/", opts.Username, opts.Password, opts.Host, opts.Port)
-	db, err := sql.Open("mysql", dsn)
+	// FormatDSN escapes passwords with characters such as @ and /
+	cfg := mysql.NewConfig()
+	cfg.User = opts.Username
+	cfg.Passwd = opts.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(opts.Host, opts.Port)
+	db, err := sql.Open("mysql", cfg.FormatDSN())
 	if err != nil {
 		return nil, fmt.Errorf("opening mysql connection: %w", err)
 	}
@@ -87,6 +100,157 @@ func (c *MySQLClient) CreateDatabase(name string) error {
 	return nil
 }
 
+// CloneDatabase creates target with the character set of source and copies
+// the tables, their rows and the views of source into it. The rows are
+// streamed from table to table on the server. Triggers and stored routines
+// are not copied. A partly copied target is dropped again.
+func (c *MySQLClient) CloneDatabase(ctx context.Context, source, target string) error {
+	var charset, collation string
+	err := c.db.QueryRowContext(ctx, "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", source).
+		Scan(&charset, &collation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("source database %s does not exist", source)
+	}
+	if err != nil {
+		return fmt.Errorf("reading database %s: %w", source, err)
+	}
+
+	query := fmt.Sprintf("CREATE DATABASE `%s` CHARACTER SET %s COLLATE %s", target, charset, collation)
+	if _, err := c.db.ExecContext(ctx, query); err != nil {
+		if IsDatabaseExistsError(err) {
+			return &DatabaseExistsError{Name: target}
+		}
+		return fmt.Errorf("creating database %s: %w", target, err)
+	}
+
+	if err := c.copyDatabase(ctx, source, target); err != nil {
+		_ = c.DropDatabase(target) // best-effort cleanup
+		return fmt.Errorf("cloning database %s into %s: %w", source, target, err)
+	}
+	return nil
+}
+
+// copyDatabase copies the tables and views of source into the empty
+// database target.
+func (c *MySQLClient) copyDatabase(ctx context.Context, source, target string) error {
+	// USE and FOREIGN_KEY_CHECKS apply to a single connection
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }() // best-effort cleanup
+
+	tables, views, err := mysqlTables(ctx, conn, source)
+	if err != nil {
+		return err
+	}
+
+	// Read the definitions from within source, so that they refer to its
+	// tables without the database name and can be run in target
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", source)); err != nil {
+		return err
+	}
+	tableDDL := make([]string, len(tables))
+	for i, table := range tables {
+		var name string
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&name, &tableDDL[i]); err != nil {
+			return fmt.Errorf("reading table %s: %w", table, err)
+		}
+	}
+	viewDDL := make([]string, len(views))
+	for i, view := range views {
+		var name, charset, collation string
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE VIEW `%s`", view)).Scan(&name, &viewDDL[i], &charset, &collation); err != nil {
+			return fmt.Errorf("reading view %s: %w", view, err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", target)); err != nil {
+		return err
+	}
+	// Tables are created and filled in name order, not in the order their
+	// foreign keys need
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1") }() // best-effort reset
+
+	for i, table := range tables {
+		if _, err := conn.ExecContext(ctx, tableDDL[i]); err != nil {
+			return fmt.Errorf("creating table %s: %w", table, err)
+		}
+		columns, err := mysqlCopyColumns(ctx, conn, source, table)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf("INSERT INTO `%s`.`%s` (%s) SELECT %s FROM `%s`.`%s`",
+			target, table, columns, columns, source, table)
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("copying rows of table %s: %w", table, err)
+		}
+	}
+
+	// Views may select from other views; create them in as many passes as
+	// it takes
+	for pending := viewDDL; len(pending) > 0; {
+		var failed []string
+		var lastErr error
+		for _, ddl := range pending {
+			if _, err := conn.ExecContext(ctx, ddl); err != nil {
+				failed, lastErr = append(failed, ddl), err
+			}
+		}
+		if len(failed) == len(pending) {
+			return fmt.Errorf("creating views: %w", lastErr)
+		}
+		pending = failed
+	}
+	return nil
+}
+
+// mysqlTables returns the base tables and the views of database, sorted by
+// name.
+func mysqlTables(ctx context.Context, conn *sql.Conn, database string) (tables, views []string, err error) {
+	rows, err := conn.QueryContext(ctx, "SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }() // best-effort cleanup
+
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			return nil, nil, fmt.Errorf("scanning table name: %w", err)
+		}
+		if tableType == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+	return tables, views, rows.Err()
+}
+
+// mysqlCopyColumns returns the quoted, comma-separated columns of a table
+// that can be inserted into, which leaves out generated columns.
+func mysqlCopyColumns(ctx context.Context, conn *sql.Conn, database, table string) (string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND EXTRA NOT LIKE '%GENERATED%' ORDER BY ORDINAL_POSITION", database, table)
+	if err != nil {
+		return "", fmt.Errorf("listing columns of table %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }() // best-effort cleanup
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", fmt.Errorf("scanning column name: %w", err)
+		}
+		columns = append(columns, "`"+name+"`")
+	}
+	return strings.Join(columns, ", "), rows.Err()
+}
+
 func (c *MySQLClient) DropDatabase(name string) error {
 	query := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name)
 	_, err := c.db.Exec(query)
@@ -133,9 +297,14 @@ func NewPostgreSQLClient(opts DatabaseOptions) (*PostgreSQLClient, error) {
 		opts.Username = "postgres"
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-		opts.Host, opts.Port, opts.Username, opts.Password)
-	db, err := sql.Open("pgx", dsn)
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(opts.Username, opts.Password),
+		Host:     net.JoinHostPort(opts.Host, opts.Port),
+		Path:     "/postgres",
+		RawQuery: "sslmode=disable",
+	}
+	db, err := sql.Open("pgx", dsn.String())
 	if err != nil {
 		return nil, fmt.Errorf("opening postgres connection: %w", err)
 	}
@@ -172,6 +341,36 @@ func (c *PostgreSQLClient) CreateDatabase(name string) error {
 	return nil
 }
 
+// CloneDatabase creates target with source as its template, which copies
+// the whole database on the server. PostgreSQL refuses while other
+// sessions are connected to source.
+func (c *PostgreSQLClient) CloneDatabase(ctx context.Context, source, target string) error {
+	var exists bool
+	err := c.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", source).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking database existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("source database %s does not exist", source)
+	}
+
+	query := fmt.Sprintf("CREATE DATABASE \"%s\" TEMPLATE \"%s\"", target, source)
+	if _, err := c.db.ExecContext(ctx, query); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "already exists"):
+			return &DatabaseExistsError{Name: target}
+		case strings.Contains(err.Error(), "being accessed by other users"):
+			return fmt.Errorf("cloning database %s: close the connections to it first, e.g. stop the queue workers of the default branch: %w", source, err)
+		case ctx.Err() != nil:
+			// The server rolls back a cancelled CREATE DATABASE, unless the
+			// cancellation arrived just after it finished
+			_ = c.DropDatabase(target) // best-effort cleanup
+		}
+		return fmt.Errorf("cloning database %s into %s: %w", source, target, err)
+	}
+	return nil
+}
+
 func (c *PostgreSQLClient) DropDatabase(name string) error {
 	query := fmt.Sprintf("DROP DATABASE IF EXISTS \"%s\"", name)
 	_, err := c.db.Exec(query)
@@ -1,6 +1,8 @@
 package steps
 
 import (
+	"context"
+	"fmt"
 	"sync"
 )
 
@@ -9,6 +11,7 @@ type MockDatabaseClient struct {
 	mu           sync.Mutex
 	databases    map[string]bool
 	createCalls  []string
+	cloneCalls   [][2]string
 	dropCalls    []string
 	listCalls    []string
 	pingError    error
@@ -60,6 +63,23 @@ func (m *MockDatabaseClient) CreateDatabase(name string) error {
 	return nil
 }
 
+// CloneDatabase records the call and creates target like CreateDatabase,
+// if source exists and ctx is not cancelled.
+func (m *MockDatabaseClient) CloneDatabase(ctx context.Context, source, target string) error {
+	m.mu.Lock()
+	m.cloneCalls = append(m.cloneCalls, [2]string{source, target})
+	exists := m.databases[source]
+	m.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cloning database %s into %s: %w", source, target, err)
+	}
+	if !exists {
+		return fmt.Errorf("source database %s does not exist", source)
+	}
+	return m.CreateDatabase(target)
+}
+
 func (m *MockDatabaseClient) DropDatabase(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -125,6 +145,15 @@ func (m *MockDatabaseClient) GetCreateCalls() []string {
 	return result
 }
 
+// GetCloneCalls returns the source and target of each CloneDatabase call.
+func (m *MockDatabaseClient) GetCloneCalls() [][2]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([][2]string, len(m.cloneCalls))
+	copy(result, m.cloneCalls)
+	return result
+}
+
 func (m *MockDatabaseClient) GetDropCalls() []string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -42,6 +42,15 @@ func (s *EnvCopyStep) Condition(ctx *types.ScaffoldContext) bool {
 	return true
 }
 
+// Plan returns the file the step would write.
+func (s *EnvCopyStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	file := s.file
+	if file == "" {
+		file = ".env"
+	}
+	return types.StepPlan{Files: []string{filepath.Clean(file)}}, nil
+}
+
 func (s *EnvCopyStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
 	sourceFile := s.sourceFile
 	if sourceFile == "" {
@@ -70,6 +70,18 @@ func (s *EnvWriteStep) Condition(ctx *types.ScaffoldContext) bool {
 	return true
 }
 
+// Plan renders the value and returns the file the step would write.
+func (s *EnvWriteStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	if _, err := template.ReplaceTemplateVars(s.value, ctx); err != nil {
+		return types.StepPlan{}, fmt.Errorf("template replacement failed: %w", err)
+	}
+	file := s.file
+	if file == "" {
+		file = ".env"
+	}
+	return types.StepPlan{Files: []string{filepath.Clean(file)}}, nil
+}
+
 func (s *EnvWriteStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
 	file := s.file
 	if file == "" {
@@ -53,6 +53,16 @@ func (s *FileCopyStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) e
 	return nil
 }
 
+// Plan returns the file the step would write.
+func (s *FileCopyStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	return types.StepPlan{Files: []string{filepath.Clean(s.to)}}, nil
+}
+
+// SkipReason explains why Condition is false.
+func (s *FileCopyStep) SkipReason(ctx *types.ScaffoldContext) string {
+	return fmt.Sprintf("%s does not exist", s.from)
+}
+
 func (s *FileCopyStep) Condition(ctx *types.ScaffoldContext) bool {
 	fromPath := filepath.Join(ctx.WorktreePath, s.from)
 	_, err := s.fs.Stat(fromPath)
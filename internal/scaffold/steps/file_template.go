@@ -0,0 +1,195 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/fs"
+	"github.com/naoray/anvil/internal/scaffold/template"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// templateExt is removed from the names of files rendered from a glob.
+const templateExt = ".tmpl"
+
+// FileTemplateStep renders one file, or every file matching a glob, with the
+// scaffold template variables.
+type FileTemplateStep struct {
+	from      string
+	to        string
+	overwrite bool
+	fs        fs.FS
+}
+
+// NewFileTemplateStep creates a file.template step with the default file system.
+func NewFileTemplateStep(cfg config.StepConfig) *FileTemplateStep {
+	return NewFileTemplateStepWithFS(cfg, nil)
+}
+
+// NewFileTemplateStepWithFS creates a file.template step with a custom file system.
+// Globs are always expanded on the real file system.
+func NewFileTemplateStepWithFS(cfg config.StepConfig, filesystem fs.FS) *FileTemplateStep {
+	if filesystem == nil {
+		filesystem = fs.Default
+	}
+	overwrite := true
+	if cfg.Overwrite != nil {
+		overwrite = *cfg.Overwrite
+	}
+	return &FileTemplateStep{from: cfg.From, to: cfg.To, overwrite: overwrite, fs: filesystem}
+}
+
+func (s *FileTemplateStep) Name() string {
+	return config.StepFileTemplate
+}
+
+func (s *FileTemplateStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	sources, err := s.sources(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		if s.isGlob() {
+			return fmt.Errorf("no files match %s", s.from)
+		}
+		return fmt.Errorf("template %s does not exist", s.from)
+	}
+
+	for _, source := range sources {
+		if err := s.render(ctx, source, s.destination(ctx, source), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Condition skips the step when no template file exists, like file.copy.
+func (s *FileTemplateStep) Condition(ctx *types.ScaffoldContext) bool {
+	sources, err := s.sources(ctx)
+	return err == nil && len(sources) > 0
+}
+
+// SkipReason explains why Condition is false.
+func (s *FileTemplateStep) SkipReason(ctx *types.ScaffoldContext) string {
+	if _, err := s.sources(ctx); err != nil {
+		return err.Error()
+	}
+	if s.isGlob() {
+		return fmt.Sprintf("no files match %s", s.from)
+	}
+	return fmt.Sprintf("template %s does not exist", s.from)
+}
+
+// Plan renders the templates and returns the files the step would write.
+// Existing files are left out unless they are overwritten.
+func (s *FileTemplateStep) Plan(ctx *types.ScaffoldContext) (types.StepPlan, error) {
+	sources, err := s.sources(ctx)
+	if err != nil {
+		return types.StepPlan{}, err
+	}
+	var plan types.StepPlan
+	for _, source := range sources {
+		dest := s.destination(ctx, source)
+		if !s.overwrite && s.fs.Exists(dest) {
+			continue
+		}
+		relSource, _ := filepath.Rel(ctx.WorktreePath, source)
+		content, err := s.fs.ReadFile(source)
+		if err != nil {
+			return types.StepPlan{}, fmt.Errorf("reading template %s: %w", relSource, err)
+		}
+		if _, err := template.RenderFile(relSource, string(content), ctx); err != nil {
+			return types.StepPlan{}, fmt.Errorf("rendering template: %w", err)
+		}
+		relDest, _ := filepath.Rel(ctx.WorktreePath, dest)
+		plan.Files = append(plan.Files, relDest)
+	}
+	return plan, nil
+}
+
+// isGlob reports whether from matches several files. to is a directory then.
+func (s *FileTemplateStep) isGlob() bool {
+	return strings.ContainsAny(s.from, "*?[")
+}
+
+// sources returns the absolute paths of the template files.
+func (s *FileTemplateStep) sources(ctx *types.ScaffoldContext) ([]string, error) {
+	pattern := filepath.Join(ctx.WorktreePath, s.from)
+	if !s.isGlob() {
+		if _, err := s.fs.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", s.from, err)
+	}
+	files := matches[:0]
+	for _, match := range matches {
+		if info, err := s.fs.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// destination returns the absolute path a template file is rendered to.
+func (s *FileTemplateStep) destination(ctx *types.ScaffoldContext, source string) string {
+	to := filepath.Join(ctx.WorktreePath, s.to)
+	if !s.isGlob() {
+		return to
+	}
+	return filepath.Join(to, strings.TrimSuffix(filepath.Base(source), templateExt))
+}
+
+func (s *FileTemplateStep) render(ctx *types.ScaffoldContext, source, dest string, opts types.StepOptions) error {
+	relSource, _ := filepath.Rel(ctx.WorktreePath, source)
+	relDest, _ := filepath.Rel(ctx.WorktreePath, dest)
+
+	// Lock the destination so parallel steps do not interleave writes
+	lock := getFileLock(dest)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !s.overwrite && s.fs.Exists(dest) {
+		if opts.Verbose {
+			fmt.Printf("  Skipping %s (already exists)\n", relDest)
+		}
+		return nil
+	}
+
+	info, err := s.fs.Stat(source)
+	if err != nil {
+		return fmt.Errorf("reading template %s: %w", relSource, err)
+	}
+	content, err := s.fs.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("reading template %s: %w", relSource, err)
+	}
+
+	rendered, err := template.RenderFile(relSource, string(content), ctx)
+	if err != nil {
+		return fmt.Errorf("rendering template: %w", err)
+	}
+
+	if opts.Verbose {
+		fmt.Printf("  Rendering %s to %s\n", relSource, relDest)
+	}
+
+	if err := s.fs.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating parent directory: %w", err)
+	}
+	mode := info.Mode().Perm()
+	if err := s.fs.WriteFile(dest, []byte(rendered), mode); err != nil {
+		return fmt.Errorf("writing %s: %w", relDest, err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := s.fs.Chmod(dest, mode); err != nil {
+		return fmt.Errorf("setting mode of %s: %w", relDest, err)
+	}
+	return nil
+}
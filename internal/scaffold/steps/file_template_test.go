@@ -0,0 +1,98 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+func TestFileTemplateStep(t *testing.T) {
+	newContext := func(dir string) *types.ScaffoldContext {
+		return &types.ScaffoldContext{WorktreePath: dir, SiteName: "myapp", DbSuffix: "sunset"}
+	}
+
+	t.Run("renders a single file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "phpunit.xml.tmpl"), []byte(`<env name="DB_DATABASE" value="{{ .DatabaseName }}"/>`), 0644))
+
+		step := NewFileTemplateStep(config.StepConfig{From: "phpunit.xml.tmpl", To: "phpunit.xml"})
+		require.NoError(t, step.Run(newContext(tmpDir), types.StepOptions{}))
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "phpunit.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, `<env name="DB_DATABASE" value="myapp_sunset"/>`, string(content))
+	})
+
+	t.Run("renders every file matching a glob into a directory", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "stubs"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "stubs", "app.xml.tmpl"), []byte("{{ .SiteName }}"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "stubs", "tests.xml"), []byte("{{ .SiteName }} tests"), 0644))
+
+		step := NewFileTemplateStep(config.StepConfig{From: "stubs/*", To: ".idea/runConfigurations"})
+		require.NoError(t, step.Run(newContext(tmpDir), types.StepOptions{}))
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, ".idea", "runConfigurations", "app.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "myapp", string(content))
+		content, err = os.ReadFile(filepath.Join(tmpDir, ".idea", "runConfigurations", "tests.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "myapp tests", string(content))
+	})
+
+	t.Run("preserves the file mode", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not supported on Windows")
+		}
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "serve.sh.tmpl"), []byte("#!/bin/sh\necho {{ .SiteName }}\n"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "serve.sh"), []byte("old"), 0644))
+
+		step := NewFileTemplateStep(config.StepConfig{From: "serve.sh.tmpl", To: "serve.sh"})
+		require.NoError(t, step.Run(newContext(tmpDir), types.StepOptions{}))
+
+		info, err := os.Stat(filepath.Join(tmpDir, "serve.sh"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	})
+
+	t.Run("keeps existing files when overwrite is false", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "compose.tmpl"), []byte("{{ .SiteName }}"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "docker-compose.override.yml"), []byte("custom"), 0644))
+
+		overwrite := false
+		step := NewFileTemplateStep(config.StepConfig{From: "compose.tmpl", To: "docker-compose.override.yml", Overwrite: &overwrite})
+		require.NoError(t, step.Run(newContext(tmpDir), types.StepOptions{}))
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "docker-compose.override.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "custom", string(content))
+	})
+
+	t.Run("names the file, line and key of a missing variable", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "compose.tmpl"), []byte("services:\n  app: {{ .AppPort }}\n"), 0644))
+
+		step := NewFileTemplateStep(config.StepConfig{From: "compose.tmpl", To: "compose.yml"})
+		err := step.Run(newContext(tmpDir), types.StepOptions{})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `compose.tmpl:2:10: missing key "AppPort" (available: Branch, DatabaseName,`)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "compose.yml"))
+	})
+
+	t.Run("condition is false without matching files", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		assert.False(t, NewFileTemplateStep(config.StepConfig{From: "missing.tmpl", To: "out"}).Condition(newContext(tmpDir)))
+		assert.False(t, NewFileTemplateStep(config.StepConfig{From: "stubs/*.tmpl", To: "out"}).Condition(newContext(tmpDir)))
+	})
+}
@@ -0,0 +1,26 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain points the global config, which holds the database registry,
+// at a temporary directory so the tests never touch the registry of the
+// user.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "anvil-steps-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	for _, key := range []string{"ANVIL_DB_URL", "ANVIL_DB_HOST", "ANVIL_DB_PORT", "ANVIL_DB_USERNAME", "ANVIL_DB_PASSWORD"} {
+		os.Unsetenv(key)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,28 @@
+package steps
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shellSafe matches arguments a shell passes through unchanged.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin joins a command line for display, quoting the arguments a
+// shell would split or expand.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes an argument for display if a shell would split or
+// expand it.
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
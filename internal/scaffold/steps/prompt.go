@@ -0,0 +1,170 @@
+package steps
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/template"
+	"github.com/naoray/anvil/internal/scaffold/types"
+	"github.com/naoray/anvil/internal/ui"
+)
+
+// Prompter asks the user for the answers of prompt steps.
+type Prompter interface {
+	Input(message, defaultValue string) (string, error)
+	Password(message, defaultValue string) (string, error)
+	Select(message string, options []string, defaultValue string) (string, error)
+	Confirm(message string, defaultValue bool) (bool, error)
+}
+
+// formPrompter asks with the forms of the ui package.
+type formPrompter struct{}
+
+func (formPrompter) Input(message, defaultValue string) (string, error) {
+	return ui.PromptInput(message, defaultValue)
+}
+
+func (formPrompter) Password(message, defaultValue string) (string, error) {
+	return ui.PromptPassword(message, defaultValue)
+}
+
+func (formPrompter) Select(message string, options []string, defaultValue string) (string, error) {
+	return ui.PromptSelect(message, options, defaultValue)
+}
+
+func (formPrompter) Confirm(message string, defaultValue bool) (bool, error) {
+	return ui.PromptConfirm(message, defaultValue)
+}
+
+// PromptStep asks the user for a value and stores the answer as a
+// template variable. The prompt.input, prompt.select and prompt.confirm
+// steps only differ in the question they ask.
+//
+// A value passed with --var for the store_as name answers the prompt
+// without asking. When nobody can be asked, the default is used, and the
+// step fails if there is none. Secret answers are masked while typed and
+// kept out of the scaffold journal, so resumed runs ask again.
+type PromptStep struct {
+	name         string
+	message      string
+	options      []string
+	defaultValue *string
+	storeAs      string
+	secret       bool
+	prompter     Prompter
+}
+
+// NewPromptStep creates a prompt step that asks with the forms of the ui
+// package.
+func NewPromptStep(name string, cfg config.StepConfig) *PromptStep {
+	return NewPromptStepWithPrompter(name, cfg, nil)
+}
+
+// NewPromptStepWithPrompter creates a prompt step with a custom prompter.
+// This is useful for testing without a terminal.
+func NewPromptStepWithPrompter(name string, cfg config.StepConfig, prompter Prompter) *PromptStep {
+	if prompter == nil {
+		prompter = formPrompter{}
+	}
+	return &PromptStep{
+		name:         name,
+		message:      cfg.Message,
+		options:      cfg.Options,
+		defaultValue: cfg.Default,
+		storeAs:      cfg.StoreAs,
+		secret:       cfg.Secret,
+		prompter:     prompter,
+	}
+}
+
+func (s *PromptStep) Name() string {
+	return s.name
+}
+
+func (s *PromptStep) Condition(ctx *types.ScaffoldContext) bool {
+	return true
+}
+
+// Interactive reports that the step may ask the user for input, so the
+// executor does not draw progress over the prompt.
+func (s *PromptStep) Interactive() bool {
+	return true
+}
+
+func (s *PromptStep) Run(ctx *types.ScaffoldContext, opts types.StepOptions) error {
+	answer, err := s.answer(ctx, opts)
+	if err != nil {
+		return err
+	}
+	ctx.SetVar(s.storeAs, answer)
+	return nil
+}
+
+// answer returns the value passed with --var, the answer of the user or
+// the default, in that order.
+func (s *PromptStep) answer(ctx *types.ScaffoldContext, opts types.StepOptions) (string, error) {
+	if value, ok := ctx.Inputs[s.storeAs]; ok {
+		return s.normalize(value, "--var "+s.storeAs)
+	}
+
+	defaultValue, hasDefault := "", s.defaultValue != nil
+	if hasDefault {
+		var err error
+		defaultValue, err = template.ReplaceTemplateVars(*s.defaultValue, ctx)
+		if err != nil {
+			return "", fmt.Errorf("template replacement failed for default: %w", err)
+		}
+	}
+
+	if !opts.Interactive {
+		if !hasDefault {
+			return "", fmt.Errorf("%s: no value for %s in non-interactive mode; pass --var %s=<value> or set a default", s.name, s.storeAs, s.storeAs)
+		}
+		return s.normalize(defaultValue, "default")
+	}
+
+	message, err := template.ReplaceTemplateVars(s.message, ctx)
+	if err != nil {
+		return "", fmt.Errorf("template replacement failed for message: %w", err)
+	}
+
+	switch s.name {
+	case config.StepPromptSelect:
+		return s.prompter.Select(message, s.options, defaultValue)
+	case config.StepPromptConfirm:
+		confirmed := false
+		if hasDefault {
+			if confirmed, err = config.ParseConfirmAnswer(defaultValue); err != nil {
+				return "", fmt.Errorf("%s: invalid default: %w", s.name, err)
+			}
+		}
+		confirmed, err = s.prompter.Confirm(message, confirmed)
+		return strconv.FormatBool(confirmed), err
+	default:
+		if s.secret {
+			return s.prompter.Password(message, defaultValue)
+		}
+		return s.prompter.Input(message, defaultValue)
+	}
+}
+
+// normalize checks an answer that was not given through the prompt:
+// prompt.select answers must be one of the options, and prompt.confirm
+// answers are stored as "true" or "false".
+func (s *PromptStep) normalize(value, source string) (string, error) {
+	switch s.name {
+	case config.StepPromptSelect:
+		if !slices.Contains(s.options, value) {
+			return "", fmt.Errorf("%s: %s %q is not one of the options %v", s.name, source, value, s.options)
+		}
+	case config.StepPromptConfirm:
+		confirmed, err := config.ParseConfirmAnswer(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid %s: %w", s.name, source, err)
+		}
+		return strconv.FormatBool(confirmed), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,167 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// mockPrompter answers every prompt with its answer and records the
+// questions it was asked.
+type mockPrompter struct {
+	answer    string
+	messages  []string
+	defaults  []string
+	passwords int
+}
+
+func (p *mockPrompter) Input(message, defaultValue string) (string, error) {
+	p.messages = append(p.messages, message)
+	p.defaults = append(p.defaults, defaultValue)
+	return p.answer, nil
+}
+
+func (p *mockPrompter) Password(message, defaultValue string) (string, error) {
+	p.passwords++
+	return p.Input(message, defaultValue)
+}
+
+func (p *mockPrompter) Select(message string, options []string, defaultValue string) (string, error) {
+	p.messages = append(p.messages, message)
+	p.defaults = append(p.defaults, defaultValue)
+	return p.answer, nil
+}
+
+func (p *mockPrompter) Confirm(message string, defaultValue bool) (bool, error) {
+	p.messages = append(p.messages, message)
+	if defaultValue {
+		p.defaults = append(p.defaults, "true")
+	} else {
+		p.defaults = append(p.defaults, "false")
+	}
+	return p.answer == "yes", nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestPromptStep(t *testing.T) {
+	t.Run("asks when interactive", func(t *testing.T) {
+		prompter := &mockPrompter{answer: "secret"}
+		step := NewPromptStepWithPrompter(config.StepPromptInput, config.StepConfig{
+			Message: "API token for {{ .SiteName }}",
+			Default: stringPtr("{{ .Branch }}"),
+			StoreAs: "api_token",
+		}, prompter)
+		ctx := &types.ScaffoldContext{SiteName: "myapp", Branch: "main"}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Interactive: true}))
+
+		assert.Equal(t, []string{"API token for myapp"}, prompter.messages)
+		assert.Equal(t, []string{"main"}, prompter.defaults)
+		assert.Equal(t, "secret", ctx.GetVar("api_token"))
+	})
+
+	t.Run("masks secret input", func(t *testing.T) {
+		prompter := &mockPrompter{answer: "s3cret"}
+		step := NewPromptStepWithPrompter(config.StepPromptInput, config.StepConfig{
+			Message: "API token",
+			StoreAs: "api_token",
+			Secret:  true,
+		}, prompter)
+		ctx := &types.ScaffoldContext{}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Interactive: true}))
+
+		assert.Equal(t, 1, prompter.passwords)
+		assert.Equal(t, "s3cret", ctx.GetVar("api_token"))
+	})
+
+	t.Run("--var answers without asking", func(t *testing.T) {
+		prompter := &mockPrompter{answer: "asked"}
+		step := NewPromptStepWithPrompter(config.StepPromptSelect, config.StepConfig{
+			Message: "Seed dataset",
+			Options: []string{"none", "demo", "full"},
+			StoreAs: "seed",
+		}, prompter)
+		ctx := &types.ScaffoldContext{Inputs: map[string]string{"seed": "demo"}}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Interactive: true}))
+
+		assert.Empty(t, prompter.messages)
+		assert.Equal(t, "demo", ctx.GetVar("seed"))
+	})
+
+	t.Run("uses the default when not interactive", func(t *testing.T) {
+		prompter := &mockPrompter{}
+		step := NewPromptStepWithPrompter(config.StepPromptConfirm, config.StepConfig{
+			Message: "Load fixtures?",
+			Default: stringPtr("yes"),
+			StoreAs: "fixtures",
+		}, prompter)
+		ctx := &types.ScaffoldContext{}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{}))
+
+		assert.Empty(t, prompter.messages)
+		assert.Equal(t, "true", ctx.GetVar("fixtures"))
+	})
+
+	t.Run("confirm stores the answer as true or false", func(t *testing.T) {
+		prompter := &mockPrompter{answer: "no"}
+		step := NewPromptStepWithPrompter(config.StepPromptConfirm, config.StepConfig{
+			Message: "Load fixtures?",
+			Default: stringPtr("y"),
+			StoreAs: "fixtures",
+		}, prompter)
+		ctx := &types.ScaffoldContext{}
+
+		require.NoError(t, step.Run(ctx, types.StepOptions{Interactive: true}))
+
+		assert.Equal(t, []string{"true"}, prompter.defaults)
+		assert.Equal(t, "false", ctx.GetVar("fixtures"))
+	})
+
+	t.Run("fails when not interactive without default or --var", func(t *testing.T) {
+		step := NewPromptStepWithPrompter(config.StepPromptInput, config.StepConfig{
+			Message: "API token",
+			StoreAs: "api_token",
+		}, &mockPrompter{})
+
+		err := step.Run(&types.ScaffoldContext{}, types.StepOptions{})
+		require.Error(t, err)
+		assert.Equal(t, "prompt.input: no value for api_token in non-interactive mode; pass --var api_token=<value> or set a default", err.Error())
+	})
+
+	t.Run("rejects --var values that are not an option", func(t *testing.T) {
+		step := NewPromptStepWithPrompter(config.StepPromptSelect, config.StepConfig{
+			Message: "Seed dataset",
+			Options: []string{"none", "demo"},
+			StoreAs: "seed",
+		}, &mockPrompter{})
+		ctx := &types.ScaffoldContext{Inputs: map[string]string{"seed": "huge"}}
+
+		err := step.Run(ctx, types.StepOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `--var seed "huge" is not one of the options`)
+		assert.Empty(t, ctx.GetVar("seed"))
+	})
+
+	t.Run("is interactive", func(t *testing.T) {
+		step, err := Create(config.StepPromptInput, config.StepConfig{
+			Message:         "API token",
+			StoreAs:         "api_token",
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": ".env"}},
+		})
+		require.NoError(t, err)
+
+		interactive, ok := step.(interface{ Interactive() bool })
+		require.True(t, ok, "conditional steps forward Interactive")
+		assert.True(t, interactive.Interactive())
+	})
+}
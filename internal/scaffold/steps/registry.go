@@ -52,6 +52,8 @@ func (r *Registry) RegisterWithValidator(name string, factory StepFactory, valid
 // Create instantiates a step by name with the given configuration.
 // Validates the configuration before creating the step using registered validators.
 // Falls back to built-in validation if no validator is registered.
+// The step condition is compiled here, so unknown condition keys and
+// malformed values are rejected before anything runs.
 // Returns an error if the step is not registered or config is invalid.
 func (r *Registry) Create(name string, cfg config.StepConfig) (types.ScaffoldStep, error) {
 	// Use registered validator if available
@@ -59,6 +61,9 @@ func (r *Registry) Create(name string, cfg config.StepConfig) (types.ScaffoldSte
 		if err := validator.Validate(cfg); err != nil {
 			return nil, err
 		}
+		if err := config.ValidateCommandOptions(name, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config for step %q: %w", name, err)
+		}
 	} else {
 		// Fall back to built-in validation
 		if err := config.ValidateStepConfig(name, cfg); err != nil {
@@ -66,10 +71,19 @@ func (r *Registry) Create(name string, cfg config.StepConfig) (types.ScaffoldSte
 		}
 	}
 
-	if factory, ok := r.factories[name]; ok {
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown step %q (available: %v)", name, r.ListRegistered())
+	}
+
+	if len(cfg.Condition) == 0 {
 		return factory(cfg), nil
 	}
-	return nil, fmt.Errorf("unknown step %q (available: %v)", name, r.ListRegistered())
+	condition, err := types.CompileCondition(cfg.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	return newConditionalStep(factory(cfg), condition), nil
 }
 
 // ListRegistered returns a sorted list of all registered step names.
@@ -96,12 +110,16 @@ func (r *Registry) RegisterDefaults() {
 		return NewFileCopyStep(cfg.From, cfg.To)
 	}, validation.NewFileCopyValidator())
 
+	r.RegisterWithValidator(config.StepFileTemplate, func(cfg config.StepConfig) types.ScaffoldStep {
+		return NewFileTemplateStep(cfg)
+	}, validation.NewFileTemplateValidator())
+
 	r.RegisterWithValidator(config.StepBashRun, func(cfg config.StepConfig) types.ScaffoldStep {
-		return NewBashRunStep(cfg.Command, cfg.StoreAs)
+		return NewBashRunStepFromConfig(cfg)
 	}, validation.NewBashRunValidator())
 
 	r.RegisterWithValidator(config.StepCommandRun, func(cfg config.StepConfig) types.ScaffoldStep {
-		return NewCommandRunStep(cfg.Command, cfg.StoreAs)
+		return NewCommandRunStepFromConfig(cfg)
 	}, validation.NewCommandRunValidator())
 
 	r.RegisterWithValidator(config.StepEnvRead, func(cfg config.StepConfig) types.ScaffoldStep {
@@ -120,9 +138,18 @@ func (r *Registry) RegisterDefaults() {
 	r.Register(config.StepDbCreate, func(cfg config.StepConfig) types.ScaffoldStep {
 		return NewDbCreateStep(cfg)
 	})
+	r.Register(config.StepDbClone, func(cfg config.StepConfig) types.ScaffoldStep {
+		return NewDbCloneStep(cfg)
+	})
 	r.Register(config.StepDbDestroy, func(cfg config.StepConfig) types.ScaffoldStep {
 		return NewDbDestroyStep(cfg)
 	})
+
+	for _, name := range []string{config.StepPromptInput, config.StepPromptSelect, config.StepPromptConfirm} {
+		r.Register(name, func(cfg config.StepConfig) types.ScaffoldStep {
+			return NewPromptStep(name, cfg)
+		})
+	}
 }
 
 // Global registry for backward compatibility during migration.
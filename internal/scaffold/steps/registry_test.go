@@ -1,6 +1,9 @@
 package steps
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,11 +95,13 @@ func TestRegistry_StepRegistration(t *testing.T) {
 			cfg      config.StepConfig
 		}{
 			{"file.copy", config.StepConfig{From: "a.txt", To: "b.txt"}},
+			{"file.template", config.StepConfig{From: "a.tmpl", To: "a"}},
 			{"bash.run", config.StepConfig{Command: "echo test"}},
 			{"command.run", config.StepConfig{Command: "echo test"}},
 			{"env.read", config.StepConfig{Key: "TEST_KEY"}},
 			{"env.write", config.StepConfig{Key: "TEST_KEY"}},
 			{"db.create", config.StepConfig{}},
+			{"db.clone", config.StepConfig{}},
 			{"db.destroy", config.StepConfig{}},
 		}
 
@@ -246,6 +251,78 @@ func TestExplicitRegistry_Create(t *testing.T) {
 		assert.Nil(t, step)
 		assert.Contains(t, err.Error(), "unknown step")
 	})
+
+	t.Run("rejects invalid conditions", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.RegisterDefaults()
+
+		step, err := registry.Create("node.npm", config.StepConfig{
+			Args:            []string{"ci"},
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exist": "package-lock.json"}},
+		})
+
+		require.Error(t, err)
+		assert.Nil(t, step)
+		assert.Contains(t, err.Error(), `invalid condition: file_exist: unknown condition "file_exist" (did you mean "file_exists"?)`)
+	})
+}
+
+func TestRegistry_Conditions(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterDefaults()
+	tmpDir := t.TempDir()
+	ctx := &types.ScaffoldContext{WorktreePath: tmpDir}
+
+	t.Run("non-binary steps honor the configured condition", func(t *testing.T) {
+		step, err := registry.Create(config.StepEnvWrite, config.StepConfig{
+			Key:             "APP_KEY",
+			Value:           "secret",
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exists": "artisan"}},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, step.Condition(ctx))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "artisan"), nil, 0644))
+		assert.True(t, step.Condition(ctx))
+	})
+
+	t.Run("the step's own condition still applies", func(t *testing.T) {
+		step, err := registry.Create(config.StepFileCopy, config.StepConfig{
+			From:            "missing.example",
+			To:              "missing",
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"os": runtime.GOOS}},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, step.Condition(ctx))
+	})
+
+	t.Run("the configured condition replaces the binary check", func(t *testing.T) {
+		step, err := registry.Create("node.bun", config.StepConfig{
+			Args:            []string{"install"},
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"os": runtime.GOOS}},
+		})
+		require.NoError(t, err)
+
+		assert.True(t, step.Condition(ctx))
+	})
+
+	t.Run("evaluation errors are reported", func(t *testing.T) {
+		step, err := registry.Create("node.npm", config.StepConfig{
+			Args:            []string{"ci"},
+			ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_changed_since": "package-lock.json"}},
+		})
+		require.NoError(t, err)
+
+		checked, ok := step.(interface {
+			CheckCondition(*types.ScaffoldContext) (bool, error)
+		})
+		require.True(t, ok)
+		_, err = checked.CheckCondition(&types.ScaffoldContext{WorktreePath: tmpDir, DefaultBranch: "main"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file_changed_since")
+		assert.False(t, step.Condition(ctx))
+	})
 }
 
 func TestExplicitRegistry_RegisterDefaults(t *testing.T) {
@@ -254,18 +331,20 @@ func TestExplicitRegistry_RegisterDefaults(t *testing.T) {
 		registry.RegisterDefaults()
 
 		registered := registry.ListRegistered()
-		assert.Len(t, registered, 16) // 8 binary steps + 8 other steps
+		assert.Len(t, registered, 21) // 8 binary steps + 13 other steps
 
 		// Verify all expected steps are present
 		expectedSteps := []string{
 			"bash.run",
 			"command.run",
+			"db.clone",
 			"db.create",
 			"db.destroy",
 			"env.copy",
 			"env.read",
 			"env.write",
 			"file.copy",
+			"file.template",
 			"herd",
 			"node.bun",
 			"node.npm",
@@ -274,6 +353,9 @@ func TestExplicitRegistry_RegisterDefaults(t *testing.T) {
 			"php",
 			"php.composer",
 			"php.laravel",
+			"prompt.confirm",
+			"prompt.input",
+			"prompt.select",
 		}
 
 		for _, stepName := range expectedSteps {
@@ -0,0 +1,141 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/scaffold/types"
+	"github.com/naoray/anvil/internal/utils"
+)
+
+// Data sources that are only loaded when a template references them.
+const (
+	sourceEnv  = "Env"  // The worktree .env file
+	sourceGit  = "Git"  // Git information about the worktree
+	sourcePort = "Port" // Ports allocated to the worktree, by name
+)
+
+// templateData returns the data a template is executed with: the scaffold
+// variables, and the data sources the template references.
+func templateData(tmpl *template.Template, ctx *types.ScaffoldContext) (map[string]any, error) {
+	snapshot := ctx.SnapshotForTemplate()
+	data := make(map[string]any, len(snapshot)+3)
+	for key, value := range snapshot {
+		data[key] = value
+	}
+
+	refs := references(tmpl)
+
+	env := map[string]string{}
+	if _, ok := refs[sourceEnv]; ok {
+		for key, value := range utils.ReadEnvFile(ctx.WorktreePath, ".env") {
+			env[key] = unquote(value)
+		}
+	}
+	data[sourceEnv] = env
+
+	gitInfo := map[string]string{}
+	if _, ok := refs[sourceGit]; ok {
+		sha, err := git.ShortSHA(ctx.WorktreePath)
+		if err != nil {
+			return nil, fmt.Errorf("resolving .Git: %w", err)
+		}
+		gitInfo["ShortSHA"] = sha
+	}
+	data[sourceGit] = gitInfo
+
+	for name := range refs[sourcePort] {
+		if _, err := ctx.Port(name); err != nil {
+			return nil, fmt.Errorf("resolving .Port.%s: %w", name, err)
+		}
+	}
+	data[sourcePort] = ctx.SnapshotPorts()
+
+	return data, nil
+}
+
+// references returns the top-level fields used by the templates, with the
+// fields accessed on each of them (e.g. "Port" -> {"app"} for .Port.app).
+func references(tmpl *template.Template) map[string]map[string]bool {
+	refs := make(map[string]map[string]bool)
+	add := func(idents []string) {
+		if len(idents) == 0 {
+			return
+		}
+		if refs[idents[0]] == nil {
+			refs[idents[0]] = make(map[string]bool)
+		}
+		if len(idents) > 1 {
+			refs[idents[0]][idents[1]] = true
+		}
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			add(n.Ident)
+		case *parse.VariableNode:
+			// $.Port.app refers to the root data like .Port.app
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				add(n.Ident[1:])
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	return refs
+}
+
+// unquote removes the quotes around a .env value.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if quote := value[0]; (quote == '"' || quote == '\'') && value[len(value)-1] == quote {
+			return value[1 : len(value)-1]
+		}
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,91 @@
+package template
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// funcs are the functions available in scaffold templates. Functions that
+// transform a value take it as their last argument so they can be used in
+// pipelines, e.g. {{ .Branch | slug | truncate 20 }}.
+var funcs = template.FuncMap{
+	"lower":        strings.ToLower,
+	"upper":        strings.ToUpper,
+	"slug":         slug,
+	"truncate":     truncate,
+	"default":      defaultValue,
+	"sha1":         sha1Hex,
+	"randAlphaNum": randAlphaNum,
+	"shellquote":   shellQuote,
+	"env":          os.Getenv,
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug lowercases s and replaces everything but letters and digits with
+// single hyphens, e.g. "Feature/Auth_Flow" becomes "feature-auth-flow".
+func slug(s string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// truncate shortens s to at most n characters.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// defaultValue returns fallback if value is empty.
+func defaultValue(fallback, value any) any {
+	if value == nil {
+		return fallback
+	}
+	if v := reflect.ValueOf(value); v.IsZero() || (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.Len() == 0 {
+		return fallback
+	}
+	return value
+}
+
+// sha1Hex returns the hex encoded SHA-1 hash of s.
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+const alphaNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// randAlphaNum returns a random string of n letters and digits.
+func randAlphaNum(n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("randAlphaNum: negative length %d", n)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphaNum))))
+		if err != nil {
+			return "", fmt.Errorf("randAlphaNum: %w", err)
+		}
+		b[i] = alphaNum[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// shellQuote quotes each argument for a POSIX shell and joins them with
+// spaces.
+func shellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -3,22 +3,77 @@ package template
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"text/template"
 
 	"github.com/naoray/anvil/internal/scaffold/types"
 )
 
+// ReplaceTemplateVars renders str with the scaffold template variables,
+// data sources and functions.
 func ReplaceTemplateVars(str string, ctx *types.ScaffoldContext) (string, error) {
-	tmpl, err := template.New("").Option("missingkey=error").Parse(str)
+	tmpl, err := newTemplate("").Parse(str)
 	if err != nil {
 		return "", fmt.Errorf("invalid template: %w", err)
 	}
 
-	data := ctx.SnapshotForTemplate()
+	data, err := templateData(tmpl, ctx)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// CheckSyntax parses str without rendering it, which reports syntax errors
+// and unknown functions before anything runs.
+func CheckSyntax(str string) error {
+	if _, err := newTemplate("").Parse(str); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+// missingKeyPattern extracts the location and the key from the error of a
+// template that references an unknown variable.
+var missingKeyPattern = regexp.MustCompile(`^template: (.*?): executing .* map has no entry for key "([^"]*)"`)
+
+// RenderFile renders the contents of a template file. Errors name the file
+// and, for unknown variables, the line and the variables that are available.
+func RenderFile(name, content string, ctx *types.ScaffoldContext) (string, error) {
+	tmpl, err := newTemplate(name).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	data, err := templateData(tmpl, ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
+		if m := missingKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+			keys := make([]string, 0, len(data))
+			for key := range data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return "", fmt.Errorf("%s: missing key %q (available: %s)", m[1], m[2], strings.Join(keys, ", "))
+		}
 		return "", fmt.Errorf("template execution failed: %w", err)
 	}
 
 	return buf.String(), nil
 }
+
+// newTemplate creates a template that fails on unknown variables and knows
+// the scaffold template functions.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=error").Funcs(funcs)
+}
@@ -1,6 +1,10 @@
 package template
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/naoray/anvil/internal/scaffold/types"
@@ -193,3 +197,187 @@ func TestReplaceTemplateVars_SnapshotForTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderFile(t *testing.T) {
+	ctx := &types.ScaffoldContext{SiteName: "mysite"}
+
+	result, err := RenderFile("app.yml", "name: {{ .SiteName }}\n", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "name: mysite\n" {
+		t.Errorf("expected %q, got %q", "name: mysite\n", result)
+	}
+
+	_, err = RenderFile("app.yml", "name: {{ .SiteName }}\nport: {{ .AppPort }}\n", ctx)
+	if err == nil {
+		t.Fatal("expected error for missing key")
+	}
+	want := `app.yml:2:9: missing key "AppPort" (available: Branch, DatabaseName, DbSuffix, Env, Git, MainWorktree, Path, Port, ProjectName, RepoName, RepoPath, SanitizedSiteName, SiteName)`
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+
+	_, err = RenderFile("app.yml", "{{ .SiteName", ctx)
+	if err == nil || !strings.Contains(err.Error(), "invalid template: template: app.yml:1:") {
+		t.Errorf("expected parse error naming the file, got %v", err)
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "plain text", input: "migrate --seed"},
+		{name: "unknown variables are not checked", input: "{{ .Unknown | slug }}"},
+		{name: "unclosed action", input: "{{ .SiteName", wantErr: "invalid template:"},
+		{name: "unknown function", input: "{{ shout .SiteName }}", wantErr: `function "shout" not defined`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSyntax(tt.input)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplaceTemplateVars_Funcs(t *testing.T) {
+	t.Setenv("ANVIL_TEMPLATE_TEST", "from-os")
+	ctx := &types.ScaffoldContext{
+		Branch:   "Feature/Auth_Flow",
+		SiteName: "myapp",
+		Vars:     map[string]string{"Empty": "", "Name": "it's"},
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{{ .SiteName | upper }}`, "MYAPP"},
+		{`{{ "MyApp" | lower }}`, "myapp"},
+		{`{{ .Branch | slug }}`, "feature-auth-flow"},
+		{`{{ .Branch | slug | truncate 7 }}`, "feature"},
+		{`{{ truncate 20 .SiteName }}`, "myapp"},
+		{`{{ .Empty | default "fallback" }}`, "fallback"},
+		{`{{ .SiteName | default "fallback" }}`, "myapp"},
+		{`{{ sha1 "anvil" }}`, "4ae8839ea32bfdc33bbad0b1974dbd63d98ac27e"},
+		{`{{ .Name | shellquote }}`, `'it'\''s'`},
+		{`{{ shellquote "a b" "c" }}`, `'a b' 'c'`},
+		{`{{ env "ANVIL_TEMPLATE_TEST" }}`, "from-os"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ReplaceTemplateVars(tt.input, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("randAlphaNum", func(t *testing.T) {
+		result, err := ReplaceTemplateVars(`{{ randAlphaNum 16 }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 16 || strings.Trim(result, alphaNum) != "" {
+			t.Errorf("expected 16 letters and digits, got %q", result)
+		}
+	})
+}
+
+func TestReplaceTemplateVars_DataSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("APP_NAME=\"My App\"\nDB_PORT=3306\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &types.ScaffoldContext{
+		WorktreePath: tmpDir,
+		ProjectName:  "myapp",
+		MainWorktree: "/code/myapp/main",
+	}
+
+	t.Run("project and main worktree", func(t *testing.T) {
+		result, err := ReplaceTemplateVars(`{{ .ProjectName }}@{{ .MainWorktree }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != "myapp@/code/myapp/main" {
+			t.Errorf("unexpected result %q", result)
+		}
+	})
+
+	t.Run("worktree .env values", func(t *testing.T) {
+		result, err := ReplaceTemplateVars(`{{ .Env.APP_NAME }}:{{ .Env.DB_PORT }}:{{ index .Env "MISSING" | default "none" }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != "My App:3306:none" {
+			t.Errorf("unexpected result %q", result)
+		}
+	})
+
+	t.Run("ports are allocated once per name", func(t *testing.T) {
+		first, err := ReplaceTemplateVars(`{{ .Port.app }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := ReplaceTemplateVars(`{{ $.Port.app }} {{ .Port.vite }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fields := strings.Fields(second)
+		if fields[0] != first {
+			t.Errorf("expected port %s to be reused, got %s", first, fields[0])
+		}
+		if fields[1] == first {
+			t.Errorf("expected a different port for vite, got %s", fields[1])
+		}
+		if ports := ctx.SnapshotPorts(); len(ports) != 2 {
+			t.Errorf("expected 2 allocated ports, got %v", ports)
+		}
+	})
+
+	t.Run("git short sha", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"init", "-b", "main"},
+			{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "init"},
+		} {
+			if output, err := exec.Command("git", append([]string{"-C", tmpDir}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, output)
+			}
+		}
+		want, err := exec.Command("git", "-C", tmpDir, "rev-parse", "--short", "HEAD").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ReplaceTemplateVars(`{{ .Git.ShortSHA }}`, ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != strings.TrimSpace(string(want)) {
+			t.Errorf("expected %q, got %q", strings.TrimSpace(string(want)), result)
+		}
+	})
+
+	t.Run("git outside a repository fails", func(t *testing.T) {
+		_, err := ReplaceTemplateVars(`{{ .Git.ShortSHA }}`, &types.ScaffoldContext{WorktreePath: t.TempDir()})
+		if err == nil || !strings.Contains(err.Error(), "resolving .Git") {
+			t.Errorf("expected an error resolving .Git, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,759 @@
+package types
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/git"
+	"github.com/naoray/anvil/internal/utils"
+)
+
+// Condition is a compiled step or pre-flight condition. Compiling checks
+// every key and value once, so evaluation only fails on runtime problems
+// such as a git error.
+type Condition struct {
+	eval conditionFunc
+}
+
+type conditionFunc func(ctx *ScaffoldContext) (bool, error)
+
+// ConditionError reports an invalid condition. Path locates the offending
+// entry inside the condition, e.g. "any[1].file_exist". Callers that know
+// where the condition was configured extend Path and set File and Line.
+type ConditionError struct {
+	Path string
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ConditionError) Error() string {
+	msg := e.Err.Error()
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	switch {
+	case e.File != "" && e.Line > 0:
+		msg = fmt.Sprintf("%s:%d: %s", e.File, e.Line, msg)
+	case e.File != "":
+		msg = e.File + ": " + msg
+	}
+	return msg
+}
+
+func (e *ConditionError) Unwrap() error {
+	return e.Err
+}
+
+// conditionKeys lists the supported condition keys.
+var conditionKeys = []string{
+	"file_exists", "file_contains", "file_has_script", "file_changed_since",
+	"command_exists", "os",
+	"env_exists", "env_not_exists", "env_file_contains", "env_file_missing",
+	"branch_matches", "is_default_branch", "var_equals", "var_set", "preset_is",
+	"any", "all", "not",
+}
+
+// CompileCondition validates a condition map and compiles it. An empty map
+// compiles to a condition that is always true. Errors are *ConditionError.
+func CompileCondition(conditions map[string]any) (*Condition, error) {
+	if len(conditions) == 0 {
+		return &Condition{eval: func(*ScaffoldContext) (bool, error) { return true, nil }}, nil
+	}
+	eval, err := compileMap(conditions, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{eval: eval}, nil
+}
+
+// Evaluate reports whether the condition holds. A nil condition is true.
+func (c *Condition) Evaluate(ctx *ScaffoldContext) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	return c.eval(ctx)
+}
+
+// EvaluateCondition compiles and evaluates a condition map.
+func (ctx *ScaffoldContext) EvaluateCondition(conditions map[string]any) (bool, error) {
+	condition, err := CompileCondition(conditions)
+	if err != nil {
+		return false, err
+	}
+	return condition.Evaluate(ctx)
+}
+
+func conditionErrorf(path, format string, args ...any) error {
+	return &ConditionError{Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// compileMap compiles a map of conditions that must all be true.
+func compileMap(conditions map[string]any, parent string) (conditionFunc, error) {
+	keys := make([]string, 0, len(conditions))
+	for key := range conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	evals := make([]conditionFunc, 0, len(keys))
+	for _, key := range keys {
+		eval, err := compileKey(key, conditions[key], joinPath(parent, key))
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, eval)
+	}
+	return allOf(evals), nil
+}
+
+// compileGroup compiles the value of any, all and not: a map of conditions,
+// or a list of condition maps.
+func compileGroup(value any, path string) ([]conditionFunc, error) {
+	if m, ok := value.(map[string]any); ok {
+		if len(m) == 0 {
+			return nil, conditionErrorf(path, "must not be empty")
+		}
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		evals := make([]conditionFunc, 0, len(keys))
+		for _, key := range keys {
+			eval, err := compileKey(key, m[key], joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			evals = append(evals, eval)
+		}
+		return evals, nil
+	}
+
+	items, ok := toList(value)
+	if !ok || len(items) == 0 {
+		return nil, conditionErrorf(path, "expected a map or a list of conditions")
+	}
+	evals := make([]conditionFunc, 0, len(items))
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		m, ok := item.(map[string]any)
+		if !ok || len(m) == 0 {
+			return nil, conditionErrorf(itemPath, "expected a map of conditions")
+		}
+		eval, err := compileMap(m, itemPath)
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, eval)
+	}
+	return evals, nil
+}
+
+func allOf(evals []conditionFunc) conditionFunc {
+	return func(ctx *ScaffoldContext) (bool, error) {
+		for _, eval := range evals {
+			ok, err := eval(ctx)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+func anyOf(evals []conditionFunc) conditionFunc {
+	return func(ctx *ScaffoldContext) (bool, error) {
+		for _, eval := range evals {
+			ok, err := eval(ctx)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// compileKey compiles a single condition.
+func compileKey(key string, value any, path string) (conditionFunc, error) {
+	switch key {
+	case "any", "all", "not":
+		evals, err := compileGroup(value, path)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "any":
+			return anyOf(evals), nil
+		case "all":
+			return allOf(evals), nil
+		}
+		all := allOf(evals)
+		return func(ctx *ScaffoldContext) (bool, error) {
+			ok, err := all(ctx)
+			return !ok && err == nil, err
+		}, nil
+
+	case "file_exists":
+		files, err := stringsOrField(value, "file", path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) { return ctx.fileExists(files), nil }, nil
+
+	case "file_contains":
+		fields, err := fieldMap(value, path, []string{"file", "pattern"}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			return ctx.fileContains(fields["file"], fields["pattern"]), nil
+		}, nil
+
+	case "file_has_script":
+		name, err := stringOrField(value, "name", path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) { return ctx.fileHasScript(name), nil }, nil
+
+	case "file_changed_since":
+		files, base, err := compileChangedFiles(value, path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			ok, err := ctx.fileChangedSince(files, base)
+			if err != nil {
+				return false, fmt.Errorf("file_changed_since: %w", err)
+			}
+			return ok, nil
+		}, nil
+
+	case "command_exists":
+		commands, err := stringsOrField(value, "command", path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) { return commandsExist(commands), nil }, nil
+
+	case "os":
+		names, err := stringList(value, path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) { return osMatches(names), nil }, nil
+
+	case "env_exists", "env_not_exists":
+		names, err := stringsOrField(value, "env", path)
+		if err != nil {
+			return nil, err
+		}
+		want := key == "env_exists"
+		return func(ctx *ScaffoldContext) (bool, error) { return envsExist(names) == want, nil }, nil
+
+	case "env_file_contains", "env_file_missing":
+		file, envKey, err := compileEnvFileKey(value, path)
+		if err != nil {
+			return nil, err
+		}
+		want := key == "env_file_contains"
+		return func(ctx *ScaffoldContext) (bool, error) {
+			return ctx.envFileContains(file, envKey) == want, nil
+		}, nil
+
+	case "branch_matches":
+		patterns, err := stringList(value, path)
+		if err != nil {
+			return nil, err
+		}
+		matchers := make([]func(string) bool, 0, len(patterns))
+		for _, pattern := range patterns {
+			match, err := compilePattern(pattern)
+			if err != nil {
+				return nil, &ConditionError{Path: path, Err: err}
+			}
+			matchers = append(matchers, match)
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			for _, match := range matchers {
+				if match(ctx.Branch) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+
+	case "is_default_branch":
+		expected, ok := value.(bool)
+		if !ok {
+			return nil, conditionErrorf(path, "expected true or false, got %s", describe(value))
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			return (ctx.Branch == ctx.defaultBranch()) == expected, nil
+		}, nil
+
+	case "var_equals":
+		checks, err := compileVarChecks(value, path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			for name, want := range checks {
+				if ctx.GetVar(name) != want {
+					return false, nil
+				}
+			}
+			return true, nil
+		}, nil
+
+	case "var_set":
+		names, err := stringList(value, path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			for _, name := range names {
+				if ctx.GetVar(name) == "" {
+					return false, nil
+				}
+			}
+			return true, nil
+		}, nil
+
+	case "preset_is":
+		presets, err := stringList(value, path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *ScaffoldContext) (bool, error) {
+			for _, preset := range presets {
+				if preset == ctx.Preset {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+	}
+
+	if suggestion := closestKey(key); suggestion != "" {
+		return nil, conditionErrorf(path, "unknown condition %q (did you mean %q?)", key, suggestion)
+	}
+	return nil, conditionErrorf(path, "unknown condition %q", key)
+}
+
+// closestKey returns the supported condition key closest to key, if it is
+// close enough to be a likely typo.
+func closestKey(key string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range conditionKeys {
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// toList returns the items of a list value.
+func toList(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, true
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, m := range v {
+			items[i] = m
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// describe names the type of a configuration value for error messages.
+func describe(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64:
+		return "a number"
+	case map[string]any:
+		return "a map"
+	}
+	if _, ok := toList(value); ok {
+		return "a list"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// stringList accepts a string or a non-empty list of strings.
+func stringList(value any, path string) ([]string, error) {
+	if s, ok := value.(string); ok {
+		if s == "" {
+			return nil, conditionErrorf(path, "must not be empty")
+		}
+		return []string{s}, nil
+	}
+	items, ok := toList(value)
+	if !ok {
+		return nil, conditionErrorf(path, "expected a string or a list of strings, got %s", describe(value))
+	}
+	if len(items) == 0 {
+		return nil, conditionErrorf(path, "must not be empty")
+	}
+	values := make([]string, 0, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, conditionErrorf(fmt.Sprintf("%s[%d]", path, i), "expected a string, got %s", describe(item))
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// stringsOrField accepts a string, a list of strings, or a map with the
+// single given field. An empty list is allowed, as all of its entries are
+// trivially present.
+func stringsOrField(value any, field, path string) ([]string, error) {
+	if items, ok := toList(value); ok && len(items) == 0 {
+		return nil, nil
+	}
+	if _, ok := value.(map[string]any); ok {
+		fields, err := fieldMap(value, path, []string{field}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fields[field]}, nil
+	}
+	return stringList(value, path)
+}
+
+// stringOrField accepts a string or a map with the single given field.
+func stringOrField(value any, field, path string) (string, error) {
+	if s, ok := value.(string); ok {
+		if s == "" {
+			return "", conditionErrorf(path, "must not be empty")
+		}
+		return s, nil
+	}
+	if _, ok := value.(map[string]any); !ok {
+		return "", conditionErrorf(path, "expected a string or a map with %q, got %s", field, describe(value))
+	}
+	fields, err := fieldMap(value, path, []string{field}, nil)
+	if err != nil {
+		return "", err
+	}
+	return fields[field], nil
+}
+
+// fieldMap accepts a map with the required and optional string fields and
+// no others.
+func fieldMap(value any, path string, required, optional []string) (map[string]string, error) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, conditionErrorf(path, "expected a map with %s, got %s", quoteList(required), describe(value))
+	}
+
+	fields := make(map[string]string, len(m))
+	for key, v := range m {
+		known := false
+		for _, name := range append(append([]string{}, required...), optional...) {
+			known = known || name == key
+		}
+		if !known {
+			return nil, conditionErrorf(joinPath(path, key), "unknown field (expected %s)", quoteList(append(append([]string{}, required...), optional...)))
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, conditionErrorf(joinPath(path, key), "expected a string, got %s", describe(v))
+		}
+		fields[key] = s
+	}
+	for _, name := range required {
+		if fields[name] == "" {
+			return nil, conditionErrorf(path, "%q is required", name)
+		}
+	}
+	return fields, nil
+}
+
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// compileEnvFileKey accepts a key, or a map with "key" and an optional
+// "file" (default .env).
+func compileEnvFileKey(value any, path string) (file, key string, err error) {
+	if s, ok := value.(string); ok && s != "" {
+		return ".env", s, nil
+	}
+	fields, err := fieldMap(value, path, []string{"key"}, []string{"file"})
+	if err != nil {
+		return "", "", err
+	}
+	file = fields["file"]
+	if file == "" {
+		file = ".env"
+	}
+	return file, fields["key"], nil
+}
+
+// compileVarChecks accepts a {name, value} map or a list of them.
+func compileVarChecks(value any, path string) (map[string]string, error) {
+	var items []any
+	_, single := value.(map[string]any)
+	if single {
+		items = []any{value}
+	} else if list, ok := toList(value); ok && len(list) > 0 {
+		items = list
+	} else {
+		return nil, conditionErrorf(path, "expected a map with \"name\" and \"value\" or a list of them, got %s", describe(value))
+	}
+
+	checks := make(map[string]string, len(items))
+	for i, item := range items {
+		itemPath := path
+		if !single {
+			itemPath = fmt.Sprintf("%s[%d]", path, i)
+		}
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, conditionErrorf(itemPath, "expected a map with \"name\" and \"value\", got %s", describe(item))
+		}
+		for key := range m {
+			if key != "name" && key != "value" {
+				return nil, conditionErrorf(joinPath(itemPath, key), "unknown field (expected \"name\", \"value\")")
+			}
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" {
+			return nil, conditionErrorf(itemPath, "\"name\" is required")
+		}
+		want, ok := m["value"]
+		if !ok {
+			return nil, conditionErrorf(itemPath, "\"value\" is required")
+		}
+		switch want.(type) {
+		case string, bool, int, int64, uint64, float64:
+		default:
+			return nil, conditionErrorf(joinPath(itemPath, "value"), "expected a string, number or boolean, got %s", describe(want))
+		}
+		checks[name] = fmt.Sprint(want)
+	}
+	return checks, nil
+}
+
+// compileChangedFiles accepts paths as a string or list, or a map with
+// "file" or "files" and an optional "base".
+func compileChangedFiles(value any, at string) ([]string, string, error) {
+	var files []string
+	var base string
+
+	if m, ok := value.(map[string]any); ok {
+		for key := range m {
+			if key != "file" && key != "files" && key != "base" {
+				return nil, "", conditionErrorf(joinPath(at, key), "unknown field (expected \"file\", \"files\", \"base\")")
+			}
+		}
+		if v, ok := m["file"]; ok {
+			file, err := stringList(v, joinPath(at, "file"))
+			if err != nil {
+				return nil, "", err
+			}
+			files = append(files, file...)
+		}
+		if v, ok := m["files"]; ok {
+			list, err := stringList(v, joinPath(at, "files"))
+			if err != nil {
+				return nil, "", err
+			}
+			files = append(files, list...)
+		}
+		if v, ok := m["base"]; ok {
+			if base, ok = v.(string); !ok || base == "" {
+				return nil, "", conditionErrorf(joinPath(at, "base"), "expected a git ref, got %s", describe(v))
+			}
+		}
+		if len(files) == 0 {
+			return nil, "", conditionErrorf(at, "\"file\" or \"files\" is required")
+		}
+	} else {
+		var err error
+		if files, err = stringList(value, at); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for i, file := range files {
+		file = strings.TrimSuffix(filepath.ToSlash(file), "/")
+		if _, err := path.Match(file, ""); err != nil {
+			return nil, "", conditionErrorf(at, "invalid pattern %q: %v", file, err)
+		}
+		files[i] = file
+	}
+	return files, base, nil
+}
+
+// compilePattern compiles a glob, or a regular expression when enclosed in
+// slashes (e.g. "/^release-[0-9]+$/").
+func compilePattern(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return func(value string) bool {
+		matched, _ := path.Match(pattern, value)
+		return matched
+	}, nil
+}
+
+func (ctx *ScaffoldContext) fileExists(files []string) bool {
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(ctx.WorktreePath, file)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (ctx *ScaffoldContext) fileContains(file, pattern string) bool {
+	data, err := os.ReadFile(filepath.Join(ctx.WorktreePath, file))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), pattern)
+}
+
+func (ctx *ScaffoldContext) fileHasScript(name string) bool {
+	data, err := os.ReadFile(filepath.Join(ctx.WorktreePath, "package.json"))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), `"`+name+`"`)
+}
+
+func commandsExist(commands []string) bool {
+	for _, command := range commands {
+		if _, err := exec.LookPath(command); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func osMatches(names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, runtime.GOOS) {
+			return true
+		}
+	}
+	return false
+}
+
+func envsExist(names []string) bool {
+	for _, name := range names {
+		if _, exists := os.LookupEnv(name); !exists {
+			return false
+		}
+	}
+	return true
+}
+
+func (ctx *ScaffoldContext) envFileContains(file, key string) bool {
+	env := utils.ReadEnvFile(ctx.WorktreePath, file)
+	val, exists := env[key]
+	return exists && val != ""
+}
+
+// defaultBranch returns the configured default branch or detects it.
+func (ctx *ScaffoldContext) defaultBranch() string {
+	if ctx.DefaultBranch != "" {
+		return ctx.DefaultBranch
+	}
+	if branch, err := git.GetDefaultBranch(ctx.WorktreePath); err == nil && branch != "" {
+		return branch
+	}
+	return config.DefaultBranch
+}
+
+// fileChangedSince reports whether a file matching one of the paths or
+// globs differs from the merge base with base (the default branch if
+// empty). Directories match every file below them.
+func (ctx *ScaffoldContext) fileChangedSince(patterns []string, base string) (bool, error) {
+	if base == "" {
+		base = ctx.defaultBranch()
+	}
+	changed, err := git.ChangedFiles(ctx.WorktreePath, base)
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range patterns {
+		for _, file := range changed {
+			if file == pattern || strings.HasPrefix(file, pattern+"/") {
+				return true, nil
+			}
+			if matched, _ := path.Match(pattern, file); matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,120 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileCondition_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition map[string]any
+		want      string
+	}{
+		{
+			name:      "unknown key with suggestion",
+			condition: map[string]any{"file_exist": "composer.json"},
+			want:      `file_exist: unknown condition "file_exist" (did you mean "file_exists"?)`,
+		},
+		{
+			name:      "unknown key without suggestion",
+			condition: map[string]any{"weather": "sunny"},
+			want:      `weather: unknown condition "weather"`,
+		},
+		{
+			name:      "unknown key inside any",
+			condition: map[string]any{"any": []any{map[string]any{"file_exists": "a"}, map[string]any{"comand_exists": "php"}}},
+			want:      `any[1].comand_exists: unknown condition "comand_exists" (did you mean "command_exists"?)`,
+		},
+		{
+			name:      "wrong value type",
+			condition: map[string]any{"file_exists": 42},
+			want:      "file_exists: expected a string or a list of strings, got a number",
+		},
+		{
+			name:      "wrong list item type",
+			condition: map[string]any{"command_exists": []any{"php", true}},
+			want:      "command_exists[1]: expected a string, got a boolean",
+		},
+		{
+			name:      "missing field",
+			condition: map[string]any{"file_contains": map[string]any{"file": ".env"}},
+			want:      `file_contains: "pattern" is required`,
+		},
+		{
+			name:      "unknown field",
+			condition: map[string]any{"env_file_contains": map[string]any{"key": "APP_KEY", "path": ".env"}},
+			want:      `env_file_contains.path: unknown field (expected "key", "file")`,
+		},
+		{
+			name:      "non-boolean is_default_branch",
+			condition: map[string]any{"is_default_branch": "yes"},
+			want:      "is_default_branch: expected true or false, got a string",
+		},
+		{
+			name:      "invalid regular expression",
+			condition: map[string]any{"branch_matches": "/feature-(/"},
+			want:      `branch_matches: invalid regular expression "/feature-(/"`,
+		},
+		{
+			name:      "var_equals without value",
+			condition: map[string]any{"var_equals": []any{map[string]any{"name": "HasDocker"}}},
+			want:      `var_equals[0]: "value" is required`,
+		},
+		{
+			name:      "not with a scalar",
+			condition: map[string]any{"not": "composer.lock"},
+			want:      "not: expected a map or a list of conditions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileCondition(tt.condition)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var condErr *ConditionError
+			if !errors.As(err, &condErr) {
+				t.Fatalf("expected a *ConditionError, got %T", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want prefix %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileCondition_AcceptsValidShapes(t *testing.T) {
+	conditions := []map[string]any{
+		{"file_exists": []string{"composer.json", "artisan"}},
+		{"file_exists": map[string]any{"file": "composer.json"}},
+		{"file_has_script": map[string]any{"name": "build"}},
+		{"env_file_missing": "APP_KEY"},
+		{"os": []any{"darwin", "linux"}},
+		{"var_equals": map[string]any{"name": "HasDocker", "value": true}},
+		{"file_changed_since": map[string]any{"files": []any{"composer.lock"}, "base": "origin/main"}},
+		{"all": map[string]any{"command_exists": "php", "not": []any{map[string]any{"env_exists": "CI"}}}},
+	}
+
+	for _, condition := range conditions {
+		if _, err := CompileCondition(condition); err != nil {
+			t.Errorf("CompileCondition(%v) returned error: %v", condition, err)
+		}
+	}
+}
+
+func TestConditionError_Error(t *testing.T) {
+	err := &ConditionError{
+		Path: "scaffold.steps[0].condition.file_exist",
+		File: "anvil.yaml",
+		Line: 7,
+		Err:  errors.New("unknown condition"),
+	}
+
+	want := "anvil.yaml:7: scaffold.steps[0].condition.file_exist: unknown condition"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+)
+
+// Ports are allocated from this range, away from the defaults of common
+// development servers and below the ephemeral range.
+const (
+	portRangeStart = 20000
+	portRangeSize  = 10000
+)
+
+// Port returns the port allocated to name, allocating one on first use.
+// The first port tried is derived from the worktree path and the name, so a
+// worktree gets the same ports again even without saved state; ports in use
+// are skipped.
+func (ctx *ScaffoldContext) Port(name string) (int, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if port, ok := ctx.Ports[name]; ok {
+		return port, nil
+	}
+
+	taken := make(map[int]bool, len(ctx.Ports))
+	for _, port := range ctx.Ports {
+		taken[port] = true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ctx.WorktreePath + "\x00" + name))
+	offset := int(h.Sum32() % portRangeSize)
+	for i := 0; i < portRangeSize; i++ {
+		port := portRangeStart + (offset+i)%portRangeSize
+		if taken[port] || !portFree(port) {
+			continue
+		}
+		if ctx.Ports == nil {
+			ctx.Ports = make(map[string]int)
+		}
+		ctx.Ports[name] = port
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port for %q", name)
+}
+
+// SnapshotPorts returns a copy of the allocated ports.
+func (ctx *ScaffoldContext) SnapshotPorts() map[string]int {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	ports := make(map[string]int, len(ctx.Ports))
+	for name, port := range ctx.Ports {
+		ports[name] = port
+	}
+	return ports
+}
+
+// portFree reports whether nothing listens on the port on localhost.
+func portFree(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestScaffoldContext_Port(t *testing.T) {
+	t.Run("returns saved ports", func(t *testing.T) {
+		ctx := &ScaffoldContext{WorktreePath: "/code/app", Ports: map[string]int{"app": 20123}}
+
+		port, err := ctx.Port("app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if port != 20123 {
+			t.Errorf("expected saved port 20123, got %d", port)
+		}
+	})
+
+	t.Run("skips ports in use", func(t *testing.T) {
+		ctx := &ScaffoldContext{WorktreePath: t.TempDir()}
+		probe := &ScaffoldContext{WorktreePath: ctx.WorktreePath}
+		busy, err := probe.Port("app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(busy)))
+		if err != nil {
+			t.Skipf("cannot listen on port %d: %v", busy, err)
+		}
+		defer ln.Close()
+
+		port, err := ctx.Port("app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if port == busy {
+			t.Errorf("expected a port other than %d, which is in use", busy)
+		}
+		if port < portRangeStart || port >= portRangeStart+portRangeSize {
+			t.Errorf("port %d outside the allocation range", port)
+		}
+	})
+
+}
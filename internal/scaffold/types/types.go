@@ -1,31 +1,40 @@
 package types
 
 import (
-	"os"
-	"os/exec"
-	"path/filepath"
+	"context"
 	"regexp"
-	"runtime"
 	"strings"
 	"sync"
-
-	"github.com/go-viper/mapstructure/v2"
-
-	"github.com/naoray/anvil/internal/utils"
 )
 
 type ScaffoldContext struct {
 	WorktreePath string
 	Branch       string
-	RepoName     string
-	SiteName     string
-	Preset       string
-	Env          map[string]string
-	Path         string
-	RepoPath     string
-	DbSuffix     string
-	Vars         map[string]string
-	mu           sync.RWMutex
+	// DefaultBranch is the project's default branch, used by the
+	// is_default_branch and file_changed_since conditions. It is detected
+	// from git when empty.
+	DefaultBranch string
+	RepoName      string
+	SiteName      string
+	Preset        string
+	Env           map[string]string
+	Path          string
+	RepoPath      string
+	DbSuffix      string
+	Vars          map[string]string
+	// ProjectName and MainWorktree identify the project and the worktree
+	// of its default branch for templates.
+	ProjectName  string
+	MainWorktree string
+	// MainSiteName is the site name of the default branch worktree, the
+	// project's site_name. It is empty if the project sets none.
+	MainSiteName string
+	// Ports holds the ports allocated with Port, by name.
+	Ports map[string]int
+	// Inputs holds the values passed with --var, by name. Prompt steps
+	// use them instead of asking.
+	Inputs map[string]string
+	mu     sync.RWMutex
 }
 
 type StepOptions struct {
@@ -33,6 +42,19 @@ type StepOptions struct {
 	DryRun  bool
 	Verbose bool
 	Quiet   bool
+	// Interactive is set when steps may ask the user for input.
+	Interactive bool
+	// Ctx is cancelled when the step times out or the run is interrupted.
+	// Use Context() to read it; it may be nil when steps are run directly.
+	Ctx context.Context
+}
+
+// Context returns the context a step should pass to the commands it runs.
+func (o StepOptions) Context() context.Context {
+	if o.Ctx == nil {
+		return context.Background()
+	}
+	return o.Ctx
 }
 
 type ScaffoldStep interface {
@@ -41,289 +63,11 @@ type ScaffoldStep interface {
 	Condition(ctx *ScaffoldContext) bool
 }
 
-func (ctx *ScaffoldContext) EvaluateCondition(conditions map[string]any) (bool, error) {
-	if len(conditions) == 0 {
-		return true, nil
-	}
-
-	if not, ok := conditions["not"]; ok {
-		result, err := ctx.evaluateCondition(not)
-		if err != nil {
-			return false, err
-		}
-		return !result, nil
-	}
-
-	return ctx.evaluateCondition(conditions)
-}
-
-func (ctx *ScaffoldContext) evaluateCondition(cond any) (bool, error) {
-	switch c := cond.(type) {
-	case map[string]any:
-		return ctx.evaluateMapCondition(c)
-	case []any:
-		return ctx.evaluateArrayCondition(c)
-	default:
-		return true, nil
-	}
-}
-
-func (ctx *ScaffoldContext) evaluateMapCondition(conditions map[string]any) (bool, error) {
-	for key, value := range conditions {
-		result, err := ctx.evaluateSingle(key, value)
-		if err != nil {
-			return false, err
-		}
-		if !result {
-			return false, nil
-		}
-	}
-	return true, nil
-}
-
-func (ctx *ScaffoldContext) evaluateArrayCondition(conditions []any) (bool, error) {
-	for _, item := range conditions {
-		result, err := ctx.evaluateCondition(item.(map[string]any))
-		if err != nil {
-			return false, err
-		}
-		if !result {
-			return false, nil
-		}
-	}
-	return true, nil
-}
-
-func (ctx *ScaffoldContext) evaluateSingle(key string, value any) (bool, error) {
-	switch key {
-	case "file_exists":
-		return ctx.fileExists(value)
-	case "file_contains":
-		return ctx.fileContains(value)
-	case "file_has_script":
-		return ctx.fileHasScript(value)
-	case "command_exists":
-		return ctx.commandExists(value)
-	case "os":
-		return ctx.osMatches(value)
-	case "env_exists":
-		return ctx.envExists(value)
-	case "env_not_exists":
-		return ctx.envNotExists(value)
-	case "env_file_contains":
-		return ctx.envFileContains(value)
-	case "env_file_missing":
-		return ctx.envFileMissing(value)
-	case "not":
-		result, err := ctx.evaluateCondition(value)
-		if err != nil {
-			return false, err
-		}
-		return !result, nil
-	default:
-		return true, nil
-	}
-}
-
-func (ctx *ScaffoldContext) fileExists(value any) (bool, error) {
-	switch v := value.(type) {
-	case string:
-		// Single file
-		fullPath := filepath.Join(ctx.WorktreePath, v)
-		_, err := os.Stat(fullPath)
-		return err == nil, nil
-	case []any:
-		// Array of files - all must exist
-		for _, item := range v {
-			if path, ok := item.(string); ok {
-				fullPath := filepath.Join(ctx.WorktreePath, path)
-				_, err := os.Stat(fullPath)
-				if err != nil {
-					return false, nil
-				}
-			}
-		}
-		return true, nil
-	case map[string]any:
-		// Map format with "file" key
-		if p, ok := v["file"].(string); ok {
-			fullPath := filepath.Join(ctx.WorktreePath, p)
-			_, err := os.Stat(fullPath)
-			return err == nil, nil
-		}
-	}
-
-	return false, nil
-}
-
-func (ctx *ScaffoldContext) fileContains(value any) (bool, error) {
-	var config struct {
-		File    string `mapstructure:"file"`
-		Pattern string `mapstructure:"pattern"`
-	}
-
-	switch v := value.(type) {
-	case map[string]any:
-		if err := mapstructure.Decode(v, &config); err != nil {
-			return false, nil
-		}
-	case string:
-		return false, nil
-	}
-
-	if config.File == "" || config.Pattern == "" {
-		return false, nil
-	}
-
-	fullPath := filepath.Join(ctx.WorktreePath, config.File)
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return false, nil
-	}
-
-	return strings.Contains(string(data), config.Pattern), nil
-}
-
-func (ctx *ScaffoldContext) fileHasScript(value any) (bool, error) {
-	var scriptName string
-	switch v := value.(type) {
-	case string:
-		scriptName = v
-	case map[string]any:
-		if s, ok := v["name"].(string); ok {
-			scriptName = s
-		}
-	}
-
-	if scriptName == "" {
-		return false, nil
-	}
-
-	fullPath := filepath.Join(ctx.WorktreePath, "package.json")
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return false, nil
-	}
-
-	return strings.Contains(string(data), `"`+scriptName+`"`), nil
-}
-
-func (ctx *ScaffoldContext) commandExists(value any) (bool, error) {
-	switch v := value.(type) {
-	case string:
-		// Single command
-		_, err := exec.LookPath(v)
-		return err == nil, nil
-	case []any:
-		// Array of commands - all must exist
-		for _, item := range v {
-			if cmdName, ok := item.(string); ok {
-				_, err := exec.LookPath(cmdName)
-				if err != nil {
-					return false, nil
-				}
-			}
-		}
-		return true, nil
-	case map[string]any:
-		// Map format with "command" key
-		if c, ok := v["command"].(string); ok {
-			_, err := exec.LookPath(c)
-			return err == nil, nil
-		}
-	}
-
-	return false, nil
-}
-
-func (ctx *ScaffoldContext) osMatches(value any) (bool, error) {
-	var osList []string
-	switch v := value.(type) {
-	case string:
-		osList = []string{v}
-	case []any:
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				osList = append(osList, s)
-			}
-		}
-	}
-
-	for _, os := range osList {
-		if strings.EqualFold(os, runtime.GOOS) {
-			return true, nil
-		}
-	}
-	return false, nil
-}
-
-func (ctx *ScaffoldContext) envExists(value any) (bool, error) {
-	switch v := value.(type) {
-	case string:
-		// Single environment variable
-		_, exists := os.LookupEnv(v)
-		return exists, nil
-	case []any:
-		// Array of environment variables - all must exist
-		for _, item := range v {
-			if envName, ok := item.(string); ok {
-				_, exists := os.LookupEnv(envName)
-				if !exists {
-					return false, nil
-				}
-			}
-		}
-		return true, nil
-	case map[string]any:
-		// Map format with "env" key
-		if e, ok := v["env"].(string); ok {
-			_, exists := os.LookupEnv(e)
-			return exists, nil
-		}
-	}
-
-	return false, nil
-}
-
-func (ctx *ScaffoldContext) envNotExists(value any) (bool, error) {
-	exists, err := ctx.envExists(value)
-	if err != nil {
-		return false, err
-	}
-	return !exists, nil
-}
-
-func (ctx *ScaffoldContext) envFileContains(value any) (bool, error) {
-	var config struct {
-		File string `mapstructure:"file"`
-		Key  string `mapstructure:"key"`
-	}
-
-	switch v := value.(type) {
-	case map[string]any:
-		if err := mapstructure.Decode(v, &config); err != nil {
-			return false, nil
-		}
-	case string:
-		config.Key = v
-		config.File = ".env"
-	}
-
-	if config.File == "" || config.Key == "" {
-		return false, nil
-	}
-
-	env := utils.ReadEnvFile(ctx.WorktreePath, config.File)
-	val, exists := env[config.Key]
-	return exists && val != "", nil
-}
-
-func (ctx *ScaffoldContext) envFileMissing(value any) (bool, error) {
-	contains, err := ctx.envFileContains(value)
-	if err != nil {
-		return false, err
-	}
-	return !contains, nil
+// StepPlan describes what a step would do without running it. Steps
+// report it by implementing Plan(ctx *ScaffoldContext) (StepPlan, error).
+type StepPlan struct {
+	Command string   // Command line the step would run
+	Files   []string // Files the step would write, relative to the worktree
 }
 
 func (ctx *ScaffoldContext) SetVar(key, value string) {
@@ -341,6 +85,17 @@ func (ctx *ScaffoldContext) GetVar(key string) string {
 	return ctx.Vars[key]
 }
 
+// SnapshotVars returns a copy of the variables stored by steps.
+func (ctx *ScaffoldContext) SnapshotVars() map[string]string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	vars := make(map[string]string, len(ctx.Vars))
+	for k, v := range ctx.Vars {
+		vars[k] = v
+	}
+	return vars
+}
+
 func (ctx *ScaffoldContext) SetDbSuffix(suffix string) {
 	ctx.mu.Lock()
 	defer ctx.mu.Unlock()
@@ -374,6 +129,8 @@ func (ctx *ScaffoldContext) SnapshotForTemplate() map[string]string {
 		"Branch":            ctx.Branch,
 		"DbSuffix":          ctx.DbSuffix,
 		"DatabaseName":      dbName,
+		"ProjectName":       ctx.ProjectName,
+		"MainWorktree":      ctx.MainWorktree,
 	}
 	for k, v := range ctx.Vars {
 		snapshot[k] = v
@@ -381,6 +138,19 @@ func (ctx *ScaffoldContext) SnapshotForTemplate() map[string]string {
 	return snapshot
 }
 
+// StandardEnv returns the ANVIL_* environment variables every command run
+// by a step gets, so scripts can identify the worktree without templates.
+func (ctx *ScaffoldContext) StandardEnv() []string {
+	snapshot := ctx.SnapshotForTemplate()
+	return []string{
+		"ANVIL_WORKTREE=" + ctx.WorktreePath,
+		"ANVIL_BRANCH=" + ctx.Branch,
+		"ANVIL_DB_NAME=" + snapshot["DatabaseName"],
+		"ANVIL_SITE_NAME=" + ctx.SiteName,
+		"ANVIL_PROJECT=" + ctx.ProjectName,
+	}
+}
+
 const maxDbNameLength = 63
 
 func buildDatabaseName(sanitized string, suffix string, maxLength int) string {
@@ -0,0 +1,91 @@
+package scaffold
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/template"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// ValidatePreset checks the steps of a preset without running anything. It
+// creates every scaffold and cleanup step, which rejects unknown steps,
+// invalid step configs and unknown condition keys, parses the templates of
+// the steps and checks their dependencies. It returns every problem found,
+// each prefixed with the step it was found in.
+func (m *ScaffoldManager) ValidatePreset(preset Preset) []error {
+	var problems []error
+
+	stepConfigs := preset.DefaultSteps()
+	created := make([]types.ScaffoldStep, 0, len(stepConfigs))
+	for i, cfg := range stepConfigs {
+		key := configKey(cfg.ID, cfg.Name, i)
+		step, err := m.createStep(cfg)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("step %s: %w", key, err))
+		} else {
+			created = append(created, step)
+		}
+		for _, err := range checkTemplates(cfg) {
+			problems = append(problems, fmt.Errorf("step %s: %w", key, err))
+		}
+	}
+	// The graph can only be checked once every step could be created
+	if len(created) == len(stepConfigs) {
+		if _, err := buildStepGraph(created); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for i, cleanup := range preset.CleanupSteps() {
+		key := configKey(cleanup.ID, cleanup.Name, i)
+		cfg := m.cleanupConfigToStepConfig(cleanup)
+		if _, err := m.registry.Create(cleanup.Name, cfg); err != nil {
+			problems = append(problems, fmt.Errorf("cleanup step %s: %w", key, err))
+		}
+		for _, err := range checkTemplates(cfg) {
+			problems = append(problems, fmt.Errorf("cleanup step %s: %w", key, err))
+		}
+	}
+
+	return problems
+}
+
+// configKey returns the key of a configured step like stepKey does for a
+// created step: its id, or its name and position.
+func configKey(id, name string, index int) string {
+	if id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s#%d", name, index+1)
+}
+
+// checkTemplates parses the options of a step that are rendered as
+// templates and returns an error per invalid template.
+func checkTemplates(cfg config.StepConfig) []error {
+	type field struct{ name, value string }
+	fields := []field{{"command", cfg.Command}, {"value", cfg.Value}, {"message", cfg.Message}}
+	if cfg.Default != nil {
+		fields = append(fields, field{"default", *cfg.Default})
+	}
+	for i, arg := range cfg.Args {
+		fields = append(fields, field{fmt.Sprintf("args[%d]", i), arg})
+	}
+	envNames := make([]string, 0, len(cfg.Env))
+	for name := range cfg.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		fields = append(fields, field{"env." + name, cfg.Env[name]})
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if err := template.CheckSyntax(f.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,60 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+)
+
+func TestScaffoldManager_ValidatePreset(t *testing.T) {
+	manager := NewScaffoldManager()
+
+	t.Run("valid preset", func(t *testing.T) {
+		problems := manager.ValidatePreset(&testPreset{
+			defaultSteps: []config.StepConfig{
+				{Name: "php.composer", ID: "composer", DependsOn: []string{}, Args: []string{"install"}},
+				{Name: "bash.run", DependsOn: []string{"composer"}, Command: "echo {{ .SiteName | upper }}"},
+			},
+			cleanupSteps: []config.CleanupStep{{Name: "herd", ID: "herd-unlink"}},
+		})
+		assert.Empty(t, problems)
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		problems := manager.ValidatePreset(&testPreset{
+			defaultSteps: []config.StepConfig{
+				{Name: "php.laravel.artisan", ID: "key-generate", Args: []string{"key:generate"}},
+				{Name: "bash.run", Command: "echo {{ .SiteName"},
+				{Name: "env.write", ID: "app-key", Key: "APP_KEY", ConditionHolder: config.ConditionHolder{Condition: map[string]any{"file_exist": ".env"}}},
+				{Name: "php.laravel", Args: []string{"{{ nope }}"}},
+			},
+			cleanupSteps: []config.CleanupStep{{Name: "herd.unlink"}},
+		})
+
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.Error()
+		}
+		require.Len(t, messages, 5, "%v", messages)
+		assert.Contains(t, messages[0], `step key-generate: creating step "php.laravel.artisan": unknown step "php.laravel.artisan"`)
+		assert.Contains(t, messages[1], "step bash.run#2: command: invalid template:")
+		assert.Contains(t, messages[2], "step app-key:")
+		assert.Contains(t, messages[2], "file_exist")
+		assert.Contains(t, messages[3], `step php.laravel#4: args[0]: invalid template:`)
+		assert.Contains(t, messages[3], `function "nope" not defined`)
+		assert.Contains(t, messages[4], `cleanup step herd.unlink#1: unknown step "herd.unlink"`)
+	})
+
+	t.Run("unknown dependencies", func(t *testing.T) {
+		problems := manager.ValidatePreset(&testPreset{
+			defaultSteps: []config.StepConfig{
+				{Name: "bash.run", ID: "build", DependsOn: []string{"install"}, Command: "make"},
+			},
+		})
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0].Error(), `depends on unknown step id "install"`)
+	})
+}
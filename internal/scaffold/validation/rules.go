@@ -154,6 +154,21 @@ func NewFileCopyValidator() *Validator {
 		})
 }
 
+// NewFileTemplateValidator creates a validator for file.template step.
+func NewFileTemplateValidator() *Validator {
+	return NewValidator("file.template").
+		AddRule(RequiredField{
+			Field:     "from",
+			GetValue:  func(c config.StepConfig) string { return c.From },
+			FieldName: "from",
+		}).
+		AddRule(RequiredField{
+			Field:     "to",
+			GetValue:  func(c config.StepConfig) string { return c.To },
+			FieldName: "to",
+		})
+}
+
 // NewBashRunValidator creates a validator for bash.run step.
 func NewBashRunValidator() *Validator {
 	return NewValidator("bash.run").
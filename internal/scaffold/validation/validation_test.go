@@ -392,6 +392,18 @@ func TestStepValidators(t *testing.T) {
 			cfg:       config.StepConfig{To: "b"},
 			wantErr:   true,
 		},
+		{
+			name:      "FileTemplateValidator passes with all fields",
+			validator: NewFileTemplateValidator(),
+			cfg:       config.StepConfig{From: "a.tmpl", To: "a"},
+			wantErr:   false,
+		},
+		{
+			name:      "FileTemplateValidator fails without to",
+			validator: NewFileTemplateValidator(),
+			cfg:       config.StepConfig{From: "a.tmpl"},
+			wantErr:   true,
+		},
 		{
 			name:      "BashRunValidator passes with command",
 			validator: NewBashRunValidator(),
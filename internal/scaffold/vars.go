@@ -0,0 +1,139 @@
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/template"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+// varNamePattern matches the names templates can refer to a variable by,
+// e.g. {{ .seed_dataset }}.
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// resolveVars returns the values of the project variables. A variable takes
+// the value passed with --var, else the value the worktree was scaffolded
+// with before, else its default rendered against ctx.
+func resolveVars(defs map[string]config.VarConfig, overrides, saved map[string]string, ctx *types.ScaffoldContext) (map[string]string, error) {
+	if err := checkVars(defs, overrides, saved); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(defs))
+	for _, name := range sortedVarNames(defs) {
+		if value, ok := overrides[name]; ok {
+			values[name] = value
+			continue
+		}
+		if value, ok := saved[name]; ok {
+			values[name] = value
+			continue
+		}
+		value, err := template.ReplaceTemplateVars(defs[name].Default, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// CheckVars reports the errors resolving the project variables of a new
+// worktree would fail with, so commands can check them before creating it.
+func CheckVars(defs map[string]config.VarConfig, overrides map[string]string) error {
+	return checkVars(defs, overrides, nil)
+}
+
+// checkVars checks the variable definitions and that every required
+// variable has a value.
+func checkVars(defs map[string]config.VarConfig, overrides, saved map[string]string) error {
+	var missing []string
+	for _, name := range sortedVarNames(defs) {
+		def := defs[name]
+		if !varNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q: use letters, digits and underscores", name)
+		}
+		if def.Required && def.Default != "" {
+			return fmt.Errorf("variable %s: 'required' and 'default' are mutually exclusive", name)
+		}
+		_, passed := overrides[name]
+		_, wasSaved := saved[name]
+		if def.Required && !passed && !wasSaved {
+			missing = append(missing, describeVar(name, def))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required variables, pass them with --var KEY=VALUE:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+func sortedVarNames(defs map[string]config.VarConfig) []string {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeVar returns the name of a variable and its description, if any.
+func describeVar(name string, def config.VarConfig) string {
+	if def.Description == "" {
+		return name
+	}
+	return fmt.Sprintf("%s: %s", name, def.Description)
+}
+
+// checkVarOverrides rejects values passed with --var that no project
+// variable or prompt step uses, which are most likely typos.
+func checkVarOverrides(overrides map[string]string, defs map[string]config.VarConfig, steps []types.ScaffoldStep) error {
+	known := make(map[string]bool, len(defs))
+	for name := range defs {
+		known[name] = true
+	}
+	for _, step := range steps {
+		if name := promptVar(step); name != "" {
+			known[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		available := make([]string, 0, len(known))
+		for name := range known {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		if len(available) == 0 {
+			return fmt.Errorf("unknown variable %q passed with --var (no variables or prompt steps defined)", name)
+		}
+		return fmt.Errorf("unknown variable %q passed with --var (available: %s)", name, strings.Join(available, ", "))
+	}
+	return nil
+}
+
+// promptVar returns the variable a prompt step stores its answer in, or ""
+// for other steps.
+func promptVar(step types.ScaffoldStep) string {
+	configured, ok := step.(*configuredStep)
+	if !ok {
+		return ""
+	}
+	switch configured.cfg.Name {
+	case config.StepPromptInput, config.StepPromptSelect, config.StepPromptConfirm:
+		return configured.cfg.StoreAs
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/types"
+)
+
+func TestResolveVars(t *testing.T) {
+	defs := map[string]config.VarConfig{
+		"seed":      {Default: "demo"},
+		"mail_from": {Default: "dev@{{ .SiteName }}.test"},
+		"tenant":    {Required: true, Description: "Tenant to create"},
+	}
+	ctx := &types.ScaffoldContext{SiteName: "myapp"}
+
+	t.Run("defaults, saved values and overrides", func(t *testing.T) {
+		vars, err := resolveVars(defs, map[string]string{"seed": "full"}, map[string]string{"tenant": "acme", "seed": "none"}, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"seed": "full", "mail_from": "dev@myapp.test", "tenant": "acme"}, vars)
+	})
+
+	t.Run("missing required variables", func(t *testing.T) {
+		_, err := resolveVars(defs, nil, nil, ctx)
+		require.Error(t, err)
+		assert.Equal(t, "missing required variables, pass them with --var KEY=VALUE:\n  tenant: Tenant to create", err.Error())
+	})
+
+	t.Run("invalid definitions", func(t *testing.T) {
+		_, err := resolveVars(map[string]config.VarConfig{"seed-data": {}}, nil, nil, ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid variable name "seed-data"`)
+
+		_, err = resolveVars(map[string]config.VarConfig{"seed": {Required: true, Default: "demo"}}, nil, nil, ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mutually exclusive")
+	})
+
+	t.Run("unknown variable in default", func(t *testing.T) {
+		_, err := resolveVars(map[string]config.VarConfig{"seed": {Default: "{{ .missing }}"}}, nil, nil, ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "variable seed")
+	})
+}
+
+func TestCheckVarOverrides(t *testing.T) {
+	defs := map[string]config.VarConfig{"seed": {Default: "demo"}}
+	steps := []types.ScaffoldStep{
+		newConfiguredStep(&mockStep{name: "prompt.input"}, config.StepConfig{Name: "prompt.input", StoreAs: "api_token"}),
+		newConfiguredStep(&mockStep{name: "bash.run"}, config.StepConfig{Name: "bash.run", StoreAs: "commit"}),
+	}
+
+	require.NoError(t, checkVarOverrides(map[string]string{"seed": "full", "api_token": "abc"}, defs, steps))
+
+	err := checkVarOverrides(map[string]string{"commit": "abc"}, defs, steps)
+	require.Error(t, err)
+	assert.Equal(t, `unknown variable "commit" passed with --var (available: api_token, seed)`, err.Error())
+
+	err = checkVarOverrides(map[string]string{"seed": "full"}, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no variables or prompt steps defined")
+}
@@ -0,0 +1,372 @@
+// Package snapshot saves and restores snapshots of worktree databases.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/steps"
+	"github.com/naoray/anvil/internal/utils"
+)
+
+// Dir is the directory of a project that holds the database snapshots of
+// its worktrees, relative to the project root.
+const Dir = ".anvil/snapshots"
+
+// maxDatabaseName is the longest database name PostgreSQL accepts; MySQL
+// accepts 64 characters.
+const maxDatabaseName = 63
+
+// validName matches snapshot names.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Snapshot describes a saved copy of a worktree database.
+type Snapshot struct {
+	Name   string                `yaml:"name"`
+	Engine config.DatabaseEngine `yaml:"engine"`
+	// Database is the database or SQLite file the snapshot was taken of
+	Database string `yaml:"database"`
+	// Copy is the database holding the snapshot on the server, or the
+	// file of a SQLite snapshot in the snapshot directory
+	Copy string `yaml:"copy"`
+	// Commit is the commit checked out in the worktree when the snapshot
+	// was taken
+	Commit  string    `yaml:"commit,omitempty"`
+	Created time.Time `yaml:"created"`
+}
+
+// Database identifies the database of a worktree.
+type Database struct {
+	Engine config.DatabaseEngine
+	Name   string // Database name, or path of the SQLite file
+}
+
+// Store keeps the snapshots of the database of one worktree in
+// <project>/.anvil/snapshots/<worktree>/. The snapshots of MySQL and
+// PostgreSQL databases are copies on the database server, which the store
+// keeps track of; SQLite snapshots are copies of the database file.
+type Store struct {
+	project       string
+	root          string
+	dir           string
+	worktreePath  string
+	clientFactory steps.DatabaseClientFactory
+}
+
+// NewStore returns the snapshot store of the worktree at worktreePath of
+// the project called project at projectPath.
+func NewStore(project, projectPath, worktreePath string) *Store {
+	return NewStoreWithFactory(project, projectPath, worktreePath, steps.DefaultDatabaseClientFactory)
+}
+
+// NewStoreWithFactory returns a snapshot store that connects to database
+// servers with factory.
+func NewStoreWithFactory(project, projectPath, worktreePath string, factory steps.DatabaseClientFactory) *Store {
+	root := filepath.Join(projectPath, filepath.FromSlash(Dir))
+	return &Store{
+		project:       project,
+		root:          root,
+		dir:           filepath.Join(root, filepath.Base(worktreePath)),
+		worktreePath:  worktreePath,
+		clientFactory: factory,
+	}
+}
+
+// Dir returns the directory the snapshots are kept in.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// List returns the snapshots, oldest first.
+func (s *Store) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshots: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		snapshot, err := s.read(strings.TrimSuffix(entry.Name(), ".yaml"))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// Get returns the snapshot called name.
+func (s *Store) Get(name string) (*Snapshot, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("snapshot %q does not exist", name)
+	}
+	snapshot, err := s.read(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %q does not exist", name)
+	}
+	return snapshot, err
+}
+
+// Take saves a snapshot of db called name. commit is recorded with it.
+// Cancelling ctx stops copying a MySQL or PostgreSQL database.
+func (s *Store) Take(ctx context.Context, db Database, name, commit string) (*Snapshot, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name %q: use lowercase letters, digits, - and _", name)
+	}
+	if _, err := os.Stat(s.metadataPath(name)); err == nil {
+		return nil, fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := s.ensureDir(); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Name:     name,
+		Engine:   db.Engine,
+		Database: db.Name,
+		Commit:   commit,
+		Created:  time.Now(),
+	}
+	if db.Engine == config.DBEngineSQLite {
+		snapshot.Copy = name + ".sqlite"
+		if err := utils.CopyFile(db.Name, filepath.Join(s.dir, snapshot.Copy)); err != nil {
+			return nil, fmt.Errorf("copying SQLite database: %w", err)
+		}
+	} else {
+		snapshot.Copy = copyDatabaseName(name, db.Name)
+		if len(snapshot.Copy) > maxDatabaseName {
+			return nil, fmt.Errorf("snapshot name %q is too long for database %s", name, db.Name)
+		}
+		err := s.withClient(db.Engine, func(client steps.DatabaseClient, host string) error {
+			if err := client.CloneDatabase(ctx, db.Name, snapshot.Copy); err != nil {
+				return err
+			}
+			// Registered, the copy is dropped along with the worktree
+			err := config.RegisterDatabase(config.DatabaseRecord{
+				Engine:   db.Engine,
+				Host:     host,
+				Name:     snapshot.Copy,
+				Project:  s.project,
+				Worktree: s.worktreePath,
+			})
+			if err != nil {
+				_ = client.DropDatabase(snapshot.Copy) // best-effort cleanup
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.write(snapshot); err != nil {
+		_ = s.dropCopy(snapshot) // best-effort cleanup
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Restore replaces db with the snapshot called name. The snapshot is kept.
+// Cancelling ctx stops copying a MySQL or PostgreSQL database until db is
+// dropped; the copy that recreates db is not cancelled.
+func (s *Store) Restore(ctx context.Context, db Database, name string) (*Snapshot, error) {
+	snapshot, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Engine != db.Engine {
+		return nil, fmt.Errorf("snapshot %q is a %s snapshot, the worktree uses %s", name, snapshot.Engine, db.Engine)
+	}
+
+	if db.Engine == config.DBEngineSQLite {
+		if err := utils.CopyFile(filepath.Join(s.dir, snapshot.Copy), db.Name); err != nil {
+			return nil, fmt.Errorf("restoring SQLite database: %w", err)
+		}
+		return snapshot, nil
+	}
+
+	err = s.withClient(db.Engine, func(client steps.DatabaseClient, host string) error {
+		// Check the copy before the database is dropped
+		databases, err := client.ListDatabases(snapshot.Copy)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(databases, snapshot.Copy) {
+			return fmt.Errorf("snapshot database %s no longer exists", snapshot.Copy)
+		}
+
+		// Restore into a temporary database first, so a failed copy leaves
+		// the database of the worktree untouched
+		restored := restoreDatabaseName(db.Name)
+		record := config.DatabaseRecord{Engine: db.Engine, Host: host, Name: restored, Project: s.project, Worktree: s.worktreePath}
+		if databases, err := client.ListDatabases(restored); err == nil && slices.Contains(databases, restored) {
+			if err := client.DropDatabase(restored); err != nil {
+				return fmt.Errorf("dropping %s left by an earlier restore: %w", restored, err)
+			}
+		}
+		if err := client.CloneDatabase(ctx, snapshot.Copy, restored); err != nil {
+			_ = client.DropDatabase(restored) // best-effort cleanup
+			return err
+		}
+		// Registered, a copy left behind by a failure is dropped along with
+		// the worktree
+		if err := config.RegisterDatabase(record); err != nil {
+			_ = client.DropDatabase(restored) // best-effort cleanup
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			_ = client.DropDatabase(restored) // best-effort cleanup
+			_ = config.UnregisterDatabases(record)
+			return err
+		}
+		if err := client.DropDatabase(db.Name); err != nil {
+			_ = client.DropDatabase(restored) // best-effort cleanup
+			_ = config.UnregisterDatabases(record)
+			return err
+		}
+		// Stopping now would leave the worktree without a database
+		if err := client.CloneDatabase(context.WithoutCancel(ctx), restored, db.Name); err != nil {
+			return fmt.Errorf("database %s was dropped and could not be recreated, the restored data is kept in %s: %w", db.Name, restored, err)
+		}
+		// Left registered if it cannot be dropped, so db.destroy drops it later
+		if err := client.DropDatabase(restored); err == nil {
+			return config.UnregisterDatabases(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Drop deletes the snapshot called name.
+func (s *Store) Drop(name string) (*Snapshot, error) {
+	snapshot, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dropCopy(snapshot); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(s.metadataPath(name)); err != nil {
+		return nil, fmt.Errorf("removing snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// dropCopy deletes the database or file holding a snapshot.
+func (s *Store) dropCopy(snapshot *Snapshot) error {
+	if snapshot.Engine == config.DBEngineSQLite {
+		err := os.Remove(filepath.Join(s.dir, snapshot.Copy))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing snapshot: %w", err)
+		}
+		return nil
+	}
+	return s.withClient(snapshot.Engine, func(client steps.DatabaseClient, host string) error {
+		if err := client.DropDatabase(snapshot.Copy); err != nil {
+			return err
+		}
+		return config.UnregisterDatabases(config.DatabaseRecord{Engine: snapshot.Engine, Host: host, Name: snapshot.Copy})
+	})
+}
+
+// withClient runs fn with a client connected to the server of engine, with
+// the connection options of the worktree, and the address of the server.
+func (s *Store) withClient(engine config.DatabaseEngine, fn func(client steps.DatabaseClient, host string) error) error {
+	opts, err := steps.ResolveConnectionOptions(engine, nil, s.worktreePath)
+	if err != nil {
+		return fmt.Errorf("database connection: %w", err)
+	}
+	client, err := s.clientFactory(string(engine), opts)
+	if err != nil {
+		return fmt.Errorf("creating database client: %w", err)
+	}
+	defer func() { _ = client.Close() }() // best-effort cleanup
+
+	if err := client.Ping(); err != nil {
+		return fmt.Errorf("connecting to %s database at %s: %w", engine, opts, err)
+	}
+	return fn(client, opts.Address())
+}
+
+// restoreDatabaseName returns the name of the temporary database a
+// snapshot of database is restored into.
+func restoreDatabaseName(database string) string {
+	name := "restore_" + database
+	if len(name) > maxDatabaseName {
+		name = name[:maxDatabaseName]
+	}
+	return name
+}
+
+// copyDatabaseName returns the name of the database holding the snapshot
+// name of database. It ends in the name of database, and so in its
+// suffix, so db.destroy drops the snapshots along with the database.
+func copyDatabaseName(name, database string) string {
+	return "snap_" + strings.ReplaceAll(name, "-", "_") + "_" + database
+}
+
+func (s *Store) metadataPath(name string) string {
+	return filepath.Join(s.dir, name+".yaml")
+}
+
+func (s *Store) read(name string) (*Snapshot, error) {
+	content, err := os.ReadFile(s.metadataPath(name))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := yaml.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", name, err)
+	}
+	return &snapshot, nil
+}
+
+func (s *Store) write(snapshot *Snapshot) error {
+	content, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	if err := os.WriteFile(s.metadataPath(snapshot.Name), content, 0644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// ensureDir creates the snapshot directory, keeping snapshots out of git
+// like the scaffold logs.
+func (s *Store) ensureDir() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	ignoreFile := filepath.Join(s.root, ".gitignore")
+	if _, err := os.Stat(ignoreFile); os.IsNotExist(err) {
+		if err := os.WriteFile(ignoreFile, []byte("*\n"), 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", ignoreFile, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/naoray/anvil/internal/config"
+	"github.com/naoray/anvil/internal/scaffold/steps"
+)
+
+func TestStore_Server(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := t.TempDir()
+	worktree := filepath.Join(project, "feature-auth")
+	db := Database{Engine: config.DBEngineMySQL, Name: "feature_auth_swift_runner"}
+
+	client := steps.NewMockDatabaseClient()
+	client.AddDatabase(db.Name)
+	store := NewStoreWithFactory("app", project, worktree, steps.MockClientFactory(client))
+
+	t.Run("take", func(t *testing.T) {
+		snapshot, err := store.Take(context.Background(), db, "before-migration", "abc1234")
+		require.NoError(t, err)
+		assert.Equal(t, "snap_before_migration_feature_auth_swift_runner", snapshot.Copy)
+		assert.Equal(t, [][2]string{{db.Name, snapshot.Copy}}, client.GetCloneCalls())
+		assert.FileExists(t, filepath.Join(project, ".anvil", "snapshots", ".gitignore"))
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		require.Len(t, records, 1, "db.destroy drops the copy with the worktree")
+		assert.Equal(t, snapshot.Copy, records[0].Name)
+		assert.Equal(t, "app", records[0].Project)
+		assert.True(t, records[0].OwnedBy(worktree))
+
+		_, err = store.Take(context.Background(), db, "before-migration", "abc1234")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already exists")
+	})
+
+	t.Run("list and get", func(t *testing.T) {
+		_, err := store.Take(context.Background(), db, "seeded", "def5678")
+		require.NoError(t, err)
+
+		snapshots, err := store.List()
+		require.NoError(t, err)
+		require.Len(t, snapshots, 2)
+		assert.Equal(t, "before-migration", snapshots[0].Name)
+		assert.Equal(t, "abc1234", snapshots[0].Commit)
+		assert.Equal(t, config.DBEngineMySQL, snapshots[0].Engine)
+		assert.Equal(t, db.Name, snapshots[0].Database)
+		assert.Equal(t, "seeded", snapshots[1].Name)
+
+		snapshot, err := store.Get("seeded")
+		require.NoError(t, err)
+		assert.Equal(t, "def5678", snapshot.Commit)
+
+		_, err = store.Get("missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `snapshot "missing" does not exist`)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		_, err := store.Restore(context.Background(), db, "before-migration")
+		require.NoError(t, err)
+		assert.Contains(t, client.GetDropCalls(), db.Name)
+		clones := client.GetCloneCalls()
+		assert.Equal(t, [][2]string{
+			{"snap_before_migration_feature_auth_swift_runner", "restore_feature_auth_swift_runner"},
+			{"restore_feature_auth_swift_runner", db.Name},
+		}, clones[len(clones)-2:], "the snapshot is copied before the database is dropped")
+		assert.True(t, client.HasDatabase(db.Name))
+		assert.False(t, client.HasDatabase("restore_feature_auth_swift_runner"))
+		assert.True(t, client.HasDatabase("snap_before_migration_feature_auth_swift_runner"), "the snapshot is kept")
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		for _, record := range records {
+			assert.NotEqual(t, "restore_feature_auth_swift_runner", record.Name)
+		}
+	})
+
+	t.Run("restore keeps the database if the copy fails", func(t *testing.T) {
+		failing := &failingCloneClient{MockDatabaseClient: client, target: "restore_feature_auth_swift_runner"}
+		store := NewStoreWithFactory("app", project, worktree, clientFactory(failing))
+		drops := len(client.GetDropCalls())
+
+		_, err := store.Restore(context.Background(), db, "before-migration")
+		require.Error(t, err)
+		assert.NotContains(t, client.GetDropCalls()[drops:], db.Name)
+		assert.True(t, client.HasDatabase(db.Name))
+	})
+
+	t.Run("restore keeps the restored copy if the database cannot be recreated", func(t *testing.T) {
+		failing := &failingCloneClient{MockDatabaseClient: client, target: db.Name}
+		store := NewStoreWithFactory("app", project, worktree, clientFactory(failing))
+
+		_, err := store.Restore(context.Background(), db, "before-migration")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "was dropped")
+		assert.Contains(t, err.Error(), "restore_feature_auth_swift_runner")
+		assert.True(t, client.HasDatabase("restore_feature_auth_swift_runner"))
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(records, func(r config.DatabaseRecord) bool {
+			return r.Name == "restore_feature_auth_swift_runner" && r.OwnedBy(worktree)
+		}), "the copy is dropped along with the worktree")
+
+		// The next restore replaces the copy
+		_, err = store.Restore(context.Background(), db, "before-migration")
+		require.Error(t, err)
+		_, err = NewStoreWithFactory("app", project, worktree, steps.MockClientFactory(client)).Restore(context.Background(), db, "before-migration")
+		require.NoError(t, err)
+		assert.True(t, client.HasDatabase(db.Name))
+		assert.False(t, client.HasDatabase("restore_feature_auth_swift_runner"))
+	})
+
+	t.Run("restore keeps the database when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		drops := len(client.GetDropCalls())
+
+		_, err := store.Restore(ctx, db, "before-migration")
+		require.ErrorIs(t, err, context.Canceled)
+		assert.NotContains(t, client.GetDropCalls()[drops:], db.Name)
+		assert.True(t, client.HasDatabase(db.Name))
+		assert.False(t, client.HasDatabase("restore_feature_auth_swift_runner"))
+	})
+
+	t.Run("restore keeps the database if the copy is gone", func(t *testing.T) {
+		require.NoError(t, client.DropDatabase("snap_seeded_feature_auth_swift_runner"))
+		drops := len(client.GetDropCalls())
+
+		_, err := store.Restore(context.Background(), db, "seeded")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no longer exists")
+		assert.Len(t, client.GetDropCalls(), drops)
+		assert.True(t, client.HasDatabase(db.Name))
+	})
+
+	t.Run("restore checks the engine", func(t *testing.T) {
+		_, err := store.Restore(context.Background(), Database{Engine: config.DBEnginePgSQL, Name: db.Name}, "before-migration")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is a mysql snapshot")
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		_, err := store.Drop("before-migration")
+		require.NoError(t, err)
+		assert.False(t, client.HasDatabase("snap_before_migration_feature_auth_swift_runner"))
+
+		_, err = store.Get("before-migration")
+		assert.Error(t, err)
+
+		records, err := config.ReadDatabaseRegistry()
+		require.NoError(t, err)
+		for _, record := range records {
+			assert.NotEqual(t, "snap_before_migration_feature_auth_swift_runner", record.Name)
+		}
+	})
+}
+
+func TestStore_Take_Errors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := t.TempDir()
+	worktree := filepath.Join(project, "main")
+	db := Database{Engine: config.DBEnginePgSQL, Name: "app_swift_runner"}
+
+	client := steps.NewMockDatabaseClient()
+	store := NewStoreWithFactory("app", project, worktree, steps.MockClientFactory(client))
+
+	for _, name := range []string{"", "Before", "-x", "a b", "../x"} {
+		_, err := store.Take(context.Background(), db, name, "")
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "invalid snapshot name", name)
+	}
+
+	_, err := store.Take(context.Background(), db, "this-snapshot-name-is-far-too-long-for-the-server", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too long")
+
+	_, err = store.Take(context.Background(), db, "missing", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "source database app_swift_runner does not exist")
+
+	client.SetPingError(errors.New("connection refused"))
+	_, err = store.Take(context.Background(), db, "offline", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connecting to pgsql database")
+
+	snapshots, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestStore_Sqlite(t *testing.T) {
+	project := t.TempDir()
+	worktree := filepath.Join(project, "feature-auth")
+	file := filepath.Join(worktree, "database", "database.sqlite")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	require.NoError(t, os.WriteFile(file, []byte("seeded"), 0644))
+
+	db := Database{Engine: config.DBEngineSQLite, Name: file}
+	store := NewStore("app", project, worktree)
+
+	snapshot, err := store.Take(context.Background(), db, "seeded", "")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(store.Dir(), snapshot.Copy))
+
+	require.NoError(t, os.WriteFile(file, []byte("migrated"), 0644))
+	_, err = store.Restore(context.Background(), db, "seeded")
+	require.NoError(t, err)
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "seeded", string(content))
+
+	_, err = store.Drop("seeded")
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(store.Dir(), snapshot.Copy))
+	snapshots, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+// failingCloneClient fails to clone into target.
+type failingCloneClient struct {
+	*steps.MockDatabaseClient
+	target string
+}
+
+func (c *failingCloneClient) CloneDatabase(ctx context.Context, source, target string) error {
+	if target == c.target {
+		return errors.New("clone failed")
+	}
+	return c.MockDatabaseClient.CloneDatabase(ctx, source, target)
+}
+
+// clientFactory returns a factory that always returns client.
+func clientFactory(client steps.DatabaseClient) steps.DatabaseClientFactory {
+	return func(engine string, opts steps.DatabaseOptions) (steps.DatabaseClient, error) {
+		return client, nil
+	}
+}
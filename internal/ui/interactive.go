@@ -119,6 +119,36 @@ func SelectWorktreesToPrune(removable []git.Worktree) ([]git.Worktree, error) {
 	return result, nil
 }
 
+// SelectDatabasesToDrop asks which of the labeled databases to drop and
+// returns the indexes of the selected ones.
+func SelectDatabasesToDrop(labels []string) ([]int, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	options := make([]huh.Option[int], len(labels))
+	for i, label := range labels {
+		options[i] = huh.NewOption(label, i)
+	}
+
+	var selected []int
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[int]().
+				Title("Select databases to drop").
+				Description("Space to toggle, Enter to confirm").
+				Options(options...).
+				Value(&selected),
+		),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		return nil, NormalizeAbort(err)
+	}
+
+	return selected, nil
+}
+
 func ConfirmRemoval(count int) (bool, error) {
 	var confirmed bool
 
@@ -392,3 +422,78 @@ func ConfirmSaveSyncConfig() (bool, error) {
 
 	return confirmed, nil
 }
+
+// PromptInput asks for a free-form value, prefilled with defaultValue.
+func PromptInput(title, defaultValue string) (string, error) {
+	value := defaultValue
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(title).
+				Value(&value),
+		),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		return "", NormalizeAbort(err)
+	}
+
+	return value, nil
+}
+
+// PromptPassword asks for a value like PromptInput, but masks what is typed.
+func PromptPassword(title, defaultValue string) (string, error) {
+	value := defaultValue
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(title).
+				EchoMode(huh.EchoModePassword).
+				Value(&value),
+		),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		return "", NormalizeAbort(err)
+	}
+
+	return value, nil
+}
+
+// PromptSelect asks to choose one of options. The cursor starts on
+// defaultValue if it is one of them.
+func PromptSelect(title string, options []string, defaultValue string) (string, error) {
+	selected := defaultValue
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(title).
+				Options(huh.NewOptions(options...)...).
+				Value(&selected),
+		),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		return "", NormalizeAbort(err)
+	}
+
+	return selected, nil
+}
+
+// PromptConfirm asks a yes/no question, preselecting defaultValue.
+func PromptConfirm(title string, defaultValue bool) (bool, error) {
+	confirmed := defaultValue
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Value(&confirmed),
+		),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		return false, NormalizeAbort(err)
+	}
+
+	return confirmed, nil
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// defaultTailWidth is used until the terminal reports its size.
+const defaultTailWidth = 80
+
+// lineWriter splits written output into lines and hands every complete
+// line to emit. Carriage returns end a line as well, so progress bars that
+// redraw themselves show up as separate updates. Escape sequences are
+// removed. It is safe for concurrent use.
+type lineWriter struct {
+	emit    func(line string)
+	mu      sync.Mutex
+	partial []byte
+}
+
+func newLineWriter(emit func(line string)) *lineWriter {
+	return &lineWriter{emit: emit}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(ansi.Strip(string(w.partial[:i])))
+		w.partial = w.partial[i+1:]
+		if line != "" {
+			w.emit(line)
+		}
+	}
+	return len(p), nil
+}
+
+type tailLineMsg struct {
+	line string
+}
+
+type tailDoneMsg struct{}
+
+type tailModel struct {
+	spinner spinner.Model
+	title   string
+	lines   []string
+	max     int
+	width   int
+}
+
+// RunWithOutputTail runs action with a spinner showing title. Output
+// written to the writer passed to action is shown below the title, limited
+// to the last lines lines. It always waits for action to return, even if
+// rendering stops early because of Ctrl-C. The returned error is the error
+// returned by action.
+func RunWithOutputTail(title string, lines int, action func(out io.Writer) error) error {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#F780E2"))
+
+	model := &tailModel{spinner: s, title: title, max: lines, width: defaultTailWidth}
+	program := tea.NewProgram(model, tea.WithInput(nil))
+	out := newLineWriter(func(line string) {
+		program.Send(tailLineMsg{line: line})
+	})
+
+	actionDone := make(chan error, 1)
+	go func() {
+		err := action(out)
+		actionDone <- err
+		program.Send(tailDoneMsg{})
+	}()
+
+	_, runErr := program.Run()
+	if err := <-actionDone; err != nil {
+		return err
+	}
+	return runErr
+}
+
+func (m *tailModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m *tailModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tailLineMsg:
+		m.lines = append(m.lines, msg.line)
+		if len(m.lines) > m.max {
+			m.lines = m.lines[len(m.lines)-m.max:]
+		}
+		return m, nil
+	case tailDoneMsg:
+		// Clear the spinner and the tail before quitting
+		m.title = ""
+		m.lines = nil
+		return m, tea.Quit
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Interrupt
+		}
+	}
+
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+func (m *tailModel) View() string {
+	if m.title == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(m.spinner.View() + m.title)
+	for _, line := range m.lines {
+		b.WriteString("\n")
+		b.WriteString(MutedStyle.Render("  " + ansi.Truncate(line, m.width-4, "…")))
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// TaskList shows a spinner line for every task that is currently running.
+// It is used when several scaffold steps execute concurrently and a single
+// spinner cannot represent progress. All methods are safe for concurrent use.
+type TaskList struct {
+	program *tea.Program
+}
+
+type taskStartMsg struct {
+	id    int
+	title string
+}
+
+type taskFinishMsg struct {
+	id   int
+	line string
+}
+
+type taskOutputMsg struct {
+	id   int
+	line string
+}
+
+type taskPrintMsg struct {
+	line string
+}
+
+type taskListDoneMsg struct{}
+
+type runningTask struct {
+	id     int
+	title  string
+	output string // Last line of output
+}
+
+type taskListModel struct {
+	spinner spinner.Model
+	running []runningTask
+	width   int
+}
+
+// RunWithTaskList runs action while rendering the tasks it starts.
+// It always waits for action to return, even if rendering stops early
+// because of Ctrl-C. The returned error is the error returned by action.
+func RunWithTaskList(action func(tl *TaskList) error) error {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#F780E2"))
+
+	tl := &TaskList{}
+	model := &taskListModel{spinner: s, width: defaultTailWidth}
+	tl.program = tea.NewProgram(model, tea.WithInput(nil))
+
+	actionDone := make(chan error, 1)
+	go func() {
+		err := action(tl)
+		actionDone <- err
+		tl.program.Send(taskListDoneMsg{})
+	}()
+
+	_, runErr := tl.program.Run()
+	if err := <-actionDone; err != nil {
+		return err
+	}
+	return runErr
+}
+
+// Start adds a running task with the given title.
+func (tl *TaskList) Start(id int, title string) {
+	tl.program.Send(taskStartMsg{id: id, title: title})
+}
+
+// Writer returns a writer whose last line of output is shown below the
+// running task with the given id.
+func (tl *TaskList) Writer(id int) io.Writer {
+	return newLineWriter(func(line string) {
+		tl.program.Send(taskOutputMsg{id: id, line: line})
+	})
+}
+
+// Print prints line above the running tasks.
+func (tl *TaskList) Print(line string) {
+	tl.program.Send(taskPrintMsg{line: line})
+}
+
+// Finish removes a running task and prints line in its place.
+func (tl *TaskList) Finish(id int, line string) {
+	tl.program.Send(taskFinishMsg{id: id, line: line})
+}
+
+func (m *taskListModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m *taskListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case taskStartMsg:
+		m.running = append(m.running, runningTask{id: msg.id, title: msg.title})
+		return m, nil
+	case taskFinishMsg:
+		for i, task := range m.running {
+			if task.id == msg.id {
+				m.running = append(m.running[:i], m.running[i+1:]...)
+				break
+			}
+		}
+		if msg.line == "" {
+			return m, nil
+		}
+		return m, tea.Println(msg.line)
+	case taskOutputMsg:
+		for i := range m.running {
+			if m.running[i].id == msg.id {
+				m.running[i].output = msg.line
+			}
+		}
+		return m, nil
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case taskPrintMsg:
+		return m, tea.Println(msg.line)
+	case taskListDoneMsg:
+		m.running = nil
+		return m, tea.Quit
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Interrupt
+		}
+	}
+
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+func (m *taskListModel) View() string {
+	if len(m.running) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(m.running))
+	for _, task := range m.running {
+		lines = append(lines, m.spinner.View()+task.title)
+		if task.output != "" {
+			lines = append(lines, MutedStyle.Render("  "+ansi.Truncate(task.output, m.width-4, "…")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
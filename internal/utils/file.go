@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyFile copies the file at from to to, creating the directory of to if
+// needed. The copy is written to a temporary file first and renamed, so to
+// is either replaced completely or left as it was.
+func CopyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", from, err)
+	}
+	defer func() { _ = in.Close() }() // best-effort cleanup
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(to), filepath.Base(to)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpFileName := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpFileName) }() // no-op once renamed
+
+	if _, err := io.Copy(tmpFile, in); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("copying %s: %w", from, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err := os.Rename(tmpFileName, to); err != nil {
+		return fmt.Errorf("replacing %s: %w", to, err)
+	}
+	return nil
+}
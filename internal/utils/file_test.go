@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "app.sqlite")
+	require.NoError(t, os.WriteFile(from, []byte("SQLite format 3"), 0644))
+
+	to := filepath.Join(dir, "snapshots", "main", "app.sqlite")
+	require.NoError(t, CopyFile(from, to))
+	content, err := os.ReadFile(to)
+	require.NoError(t, err)
+	assert.Equal(t, "SQLite format 3", string(content))
+
+	require.NoError(t, os.WriteFile(from, []byte("new"), 0644))
+	require.NoError(t, CopyFile(from, to))
+	content, err = os.ReadFile(to)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content), "replaces an existing file")
+
+	entries, err := os.ReadDir(filepath.Dir(to))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files are left behind")
+
+	assert.Error(t, CopyFile(filepath.Join(dir, "missing"), to))
+}